@@ -18,7 +18,8 @@ func (ams *AuthInMemoryStorage) Authorize(name string, token string) (bool, erro
 	ams.RLock()
 	defer ams.RUnlock()
 
-	return ams.users[name].Token == token, nil
+	user := ams.users[name]
+	return user != nil && user.Token == token, nil
 }
 
 func (ams *AuthInMemoryStorage) AddUser(name string, admin bool) (string, error) {
@@ -45,8 +46,6 @@ func (ams *AuthInMemoryStorage) IsAdmin(name string) (bool, error) {
 	ams.RLock()
 	defer ams.RUnlock()
 
-	if ams.users[name] == nil {
-		return false, fmt.Errorf("User %s does not exist", name)
-	}
-	return ams.users[name].Admin, nil
+	user := ams.users[name]
+	return user != nil && user.Admin, nil
 }
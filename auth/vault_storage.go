@@ -32,9 +32,13 @@ func NewAuthVaultStorage(url string) *AuthVaultStorage {
 
 func (avs *AuthVaultStorage) Authorize(name string, token string) (bool, error) {
 	secret, err := avs.vault.Read(pathForUser(name))
-	if err != nil || secret == nil {
+	if err != nil {
 		return false, fmt.Errorf("Can't get the secret for user %s: %s", name, err)
 	}
+	if secret == nil {
+		// unknown user
+		return false, nil
+	}
 	log.Infof("[AuthVaultStorage] Secret: %v", secret)
 	log.Infof("[AuthVaultStorage] Data: %v", secret.Data)
 	return secret.Data["token"] == token, nil
@@ -71,7 +75,11 @@ func (avs *AuthVaultStorage) IsAdmin(name string) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	return secret.Data["admin"].(bool), nil
+	if secret == nil {
+		return false, nil
+	}
+	admin, _ := secret.Data["admin"].(bool)
+	return admin, nil
 }
 
 func pathForUser(username string) string {
@@ -1,10 +1,14 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/goavro/wednesday/schema"
+	"github.com/goavro/wednesday/schema/storage"
 	"github.com/yanzay/log"
 )
 
@@ -15,6 +19,12 @@ var (
 	cassandra    = flag.String("cassandra", "", "Cassandra nodes")
 	protoVersion = flag.Int("proto-version", 3, "Cassandra protocol version")
 	cqlVersion   = flag.String("cql-version", "3.0.0", "Cassandra CQL version")
+
+	admissionWebhooks = flag.String("admission-webhooks", "", "Webhook URLs asked to admit new schemas")
+	admissionTimeout  = flag.Duration("admission-timeout", 5*time.Second, "Admission webhook timeout")
+	admissionFailOpen = flag.Bool("admission-fail-open", false, "Admit new schemas when an admission webhook fails")
+
+	importSkipRecords = flag.Bool("import-skip-records", false, "Let imports pad the Kafka log with skip records, every node must apply them")
 )
 
 func main() {
@@ -29,9 +39,142 @@ func main() {
 	registryConfig.Cassandra = *cassandra
 	registryConfig.Port = *port
 	registryConfig.Topic = *topic
+	registryConfig.ProtoVersion = *protoVersion
+	registryConfig.CQLVersion = *cqlVersion
+	if len(*admissionWebhooks) > 0 {
+		registryConfig.AdmissionWebhooks = strings.Split(*admissionWebhooks, ",")
+	}
+	registryConfig.AdmissionTimeout = *admissionTimeout
+	registryConfig.AdmissionFailOpen = *admissionFailOpen
+	registryConfig.ImportSkipRecords = *importSkipRecords
+
+	switch flag.Arg(0) {
+	case "":
+	case "fsck":
+		fsck(registryConfig, flag.Args()[1:])
+		return
+	case "rebuild":
+		rebuild(registryConfig, flag.Args()[1:])
+		return
+	case "export":
+		export(registryConfig, flag.Args()[1:])
+		return
+	case "import":
+		importArchive(registryConfig, flag.Args()[1:])
+		return
+	default:
+		log.Fatalf("Unknown command %s", flag.Arg(0))
+	}
+
 	app := schema.NewApp(registryConfig)
 	err := app.Start()
 	if err != nil {
 		log.Fatal(err)
 	}
 }
+
+// fsck compares the Kafka log with Cassandra. A running node compares its in-memory state too at /admin/fsck.
+func fsck(config schema.SchemaRegistryConfig, args []string) {
+	flags := flag.NewFlagSet("fsck", flag.ExitOnError)
+	repair := flags.Bool("repair", false, "Rewrite Cassandra from the Kafka log, every registry node must be stopped")
+	flags.Parse(args)
+
+	var cassandraStorage *storage.CassandraStorage
+	if config.Cassandra != "" {
+		cassandraStorage = storage.NewCassandraStorage(config.Cassandra, config.ProtoVersion, config.CQLVersion)
+	}
+	report, err := schema.NewConsistencyChecker(config, nil, cassandraStorage, nil, nil).Check(*repair)
+	if err != nil {
+		log.Fatal(err)
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	err = encoder.Encode(report)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !report.Consistent() && !report.Repaired {
+		os.Exit(1)
+	}
+}
+
+// rebuild repopulates Cassandra from the Kafka log. The registry should be stopped while it runs.
+func rebuild(config schema.SchemaRegistryConfig, args []string) {
+	flags := flag.NewFlagSet("rebuild", flag.ExitOnError)
+	reset := flags.Bool("reset", false, "Remove all data from Cassandra and replay the whole Kafka log")
+	flags.Parse(args)
+
+	if config.Cassandra == "" {
+		log.Fatal("Rebuild requires --cassandra")
+	}
+	cassandraStorage := storage.NewCassandraStorage(config.Cassandra, config.ProtoVersion, config.CQLVersion)
+	_, err := schema.NewRebuilder(config, cassandraStorage).Rebuild(*reset)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// export writes the registry state, read from Cassandra or replayed from the Kafka log, to stdout or a file.
+func export(config schema.SchemaRegistryConfig, args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	format := flags.String("format", storage.ArchiveNDJSON, "Archive format, ndjson or tar")
+	output := flags.String("output", "", "Archive file, stdout if empty")
+	flags.Parse(args)
+
+	out := os.Stdout
+	if *output != "" {
+		var err error
+		out, err = os.Create(*output)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer out.Close()
+	}
+	err := archiver(config, nil).Export(out, *format)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// importArchive restores an archive file through the Kafka log and prints the import plan as JSON.
+func importArchive(config schema.SchemaRegistryConfig, args []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	format := flags.String("format", storage.ArchiveNDJSON, "Archive format, ndjson or tar")
+	dryRun := flags.Bool("dry-run", false, "Only list what would be imported and the conflicts")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		log.Fatal("Usage: wednesday import [--dry-run] [--format ndjson|tar] <archive>")
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+	archive, err := storage.ReadArchive(file, *format)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(config.Brokers) == 0 {
+		log.Fatal(storage.ErrNoLog)
+	}
+	plan, err := archiver(config, schema.NewProducer(config.Brokers)).Import(archive, *dryRun)
+	if err != nil {
+		log.Fatal(err)
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	err = encoder.Encode(plan)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(plan.Conflicts) > 0 {
+		os.Exit(1)
+	}
+}
+
+func archiver(config schema.SchemaRegistryConfig, producer storage.Sender) *schema.Archiver {
+	if config.Cassandra != "" {
+		cassandraStorage := storage.NewCassandraStorage(config.Cassandra, config.ProtoVersion, config.CQLVersion)
+		return schema.NewArchiver(config, cassandraStorage, producer)
+	}
+	return schema.NewArchiver(config, nil, producer)
+}
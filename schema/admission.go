@@ -0,0 +1,117 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/goavro/wednesday/schema/api"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/yanzay/log"
+)
+
+// AdmissionReview is the body POSTed to admission webhooks. Diff is a unified diff of the latest version
+// and the candidate schema, both indented, and is empty for a new subject.
+type AdmissionReview struct {
+	Client        string `json:"client"`
+	Subject       string `json:"subject"`
+	Schema        string `json:"schema"`
+	SchemaType    string `json:"schemaType,omitempty"`
+	LatestVersion int    `json:"latest_version,omitempty"`
+	Diff          string `json:"diff,omitempty"`
+}
+
+// WebhookAdmission asks every configured webhook in turn and admits a schema only if all of them allow it.
+// A webhook has to respond with 200 and an api.AdmissionResponse. A webhook that can't be reached, times out
+// or responds otherwise allows the schema if failOpen is set and denies it if not.
+type WebhookAdmission struct {
+	webhooks []string
+	failOpen bool
+	client   *http.Client
+}
+
+func NewWebhookAdmission(webhooks []string, timeout time.Duration, failOpen bool) *WebhookAdmission {
+	return &WebhookAdmission{
+		webhooks: webhooks,
+		failOpen: failOpen,
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+func (wa *WebhookAdmission) Admit(request *api.AdmissionRequest) *api.AdmissionResponse {
+	review := &AdmissionReview{
+		Client:        request.Client,
+		Subject:       request.Subject,
+		Schema:        request.Schema,
+		SchemaType:    request.SchemaType,
+		LatestVersion: request.LatestVersion,
+	}
+	if request.LatestVersion > 0 {
+		review.Diff = schemaDiff(request.LatestSchema, request.Schema, fmt.Sprintf("%s version %d", request.Subject, request.LatestVersion))
+	}
+	body, err := json.Marshal(review)
+	if err != nil {
+		return wa.failed("", err)
+	}
+	for _, webhook := range wa.webhooks {
+		response, err := wa.call(webhook, body)
+		if err != nil {
+			response = wa.failed(webhook, err)
+		}
+		if !response.Allowed {
+			return response
+		}
+	}
+	return &api.AdmissionResponse{Allowed: true}
+}
+
+func (wa *WebhookAdmission) call(webhook string, body []byte) (*api.AdmissionResponse, error) {
+	resp, err := wa.client.Post(webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("responded with status %d", resp.StatusCode)
+	}
+	response := new(api.AdmissionResponse)
+	err = json.NewDecoder(resp.Body).Decode(response)
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (wa *WebhookAdmission) failed(webhook string, err error) *api.AdmissionResponse {
+	if wa.failOpen {
+		log.Warningf("[WebhookAdmission] Admission webhook %s failed, allowing: %s", webhook, err)
+		return &api.AdmissionResponse{Allowed: true}
+	}
+	log.Warningf("[WebhookAdmission] Admission webhook %s failed, denying: %s", webhook, err)
+	return &api.AdmissionResponse{Allowed: false, Reason: fmt.Sprintf("Admission webhook %s failed: %s", webhook, err)}
+}
+
+// schemaDiff returns a unified diff of two schemas, indented so that every field gets its own lines.
+func schemaDiff(from string, to string, fromName string) string {
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(indent(from)),
+		B:        difflib.SplitLines(indent(to)),
+		FromFile: fromName,
+		ToFile:   "candidate",
+		Context:  3,
+	})
+	if err != nil {
+		log.Warningf("[WebhookAdmission] Can't diff schemas: %s", err)
+	}
+	return diff
+}
+
+func indent(schema string) string {
+	indented := &bytes.Buffer{}
+	if err := json.Indent(indented, []byte(schema), "", "  "); err != nil {
+		return schema + "\n"
+	}
+	return indented.String() + "\n"
+}
@@ -0,0 +1,96 @@
+package schema
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goavro/wednesday/schema/api"
+)
+
+func admissionWebhook(t *testing.T, reviews chan<- *AdmissionReview, status int, response string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		review := new(AdmissionReview)
+		if err := json.NewDecoder(r.Body).Decode(review); err != nil {
+			t.Errorf("Can't decode admission review: %s", err)
+		}
+		if reviews != nil {
+			reviews <- review
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(response))
+	}))
+}
+
+func TestWebhookAdmissionReview(t *testing.T) {
+	reviews := make(chan *AdmissionReview, 1)
+	webhook := admissionWebhook(t, reviews, http.StatusOK, `{"allowed": true}`)
+	defer webhook.Close()
+
+	admission := NewWebhookAdmission([]string{webhook.URL}, time.Second, false)
+	response := admission.Admit(&api.AdmissionRequest{
+		Client:        "tenant",
+		Subject:       "orders",
+		Schema:        `{"type": "record", "name": "Order", "fields": [{"name": "id", "type": "long"}, {"name": "total", "type": "double"}]}`,
+		LatestVersion: 2,
+		LatestSchema:  `{"type": "record", "name": "Order", "fields": [{"name": "id", "type": "long"}]}`,
+	})
+	if !response.Allowed {
+		t.Fatalf("Schema was denied: %s", response.Reason)
+	}
+	review := <-reviews
+	if review.Client != "tenant" || review.Subject != "orders" || review.LatestVersion != 2 {
+		t.Errorf("Unexpected review %+v", review)
+	}
+	if !strings.Contains(review.Diff, "--- orders version 2\n+++ candidate\n") {
+		t.Errorf("Diff has no header:\n%s", review.Diff)
+	}
+	if !strings.Contains(review.Diff, `+      "name": "total",`) {
+		t.Errorf("Diff doesn't add the new field:\n%s", review.Diff)
+	}
+
+	admission.Admit(&api.AdmissionRequest{Client: "tenant", Subject: "new", Schema: `"string"`})
+	if review := <-reviews; review.Diff != "" || review.LatestVersion != 0 {
+		t.Errorf("New subject has a diff:\n%s", review.Diff)
+	}
+}
+
+func TestWebhookAdmissionDenied(t *testing.T) {
+	allowing := admissionWebhook(t, nil, http.StatusOK, `{"allowed": true}`)
+	defer allowing.Close()
+	denying := admissionWebhook(t, nil, http.StatusOK, `{"allowed": false, "reason": "Change freeze"}`)
+	defer denying.Close()
+
+	admission := NewWebhookAdmission([]string{allowing.URL, denying.URL}, time.Second, true)
+	response := admission.Admit(&api.AdmissionRequest{Client: "tenant", Subject: "orders", Schema: `"string"`})
+	if response.Allowed || response.Reason != "Change freeze" {
+		t.Errorf("Expected denial for change freeze, got %+v", response)
+	}
+}
+
+func TestWebhookAdmissionFailure(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte(`{"allowed": true}`))
+	}))
+	defer slow.Close()
+	broken := admissionWebhook(t, nil, http.StatusInternalServerError, `{"allowed": true}`)
+	defer broken.Close()
+	garbled := admissionWebhook(t, nil, http.StatusOK, `allowed`)
+	defer garbled.Close()
+
+	request := &api.AdmissionRequest{Client: "tenant", Subject: "orders", Schema: `"string"`}
+	for _, webhook := range []string{slow.URL, broken.URL, garbled.URL} {
+		response := NewWebhookAdmission([]string{webhook}, 50*time.Millisecond, false).Admit(request)
+		if response.Allowed || !strings.HasPrefix(response.Reason, "Admission webhook "+webhook+" failed") {
+			t.Errorf("Failing webhook %s should deny when failing closed, got %+v", webhook, response)
+		}
+		response = NewWebhookAdmission([]string{webhook}, 50*time.Millisecond, true).Admit(request)
+		if !response.Allowed {
+			t.Errorf("Failing webhook %s should allow when failing open, got %+v", webhook, response)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/goavro/wednesday/schema/storage"
+	"github.com/julienschmidt/httprouter"
+	"github.com/yanzay/log"
+)
+
+type ConsistencyChecker interface {
+	Check(repair bool) (*storage.ConsistencyReport, error)
+}
+
+type Archiver interface {
+	Export(w io.Writer, format string) error
+	Import(archive *storage.Snapshot, dryRun bool) (*storage.ImportPlan, error)
+}
+
+// Fsck compares the schema log with the in-memory state and Cassandra.
+// POST with repair=true rewrites the stores that differ from the log. Cassandra can't be repaired
+// while the cluster runs, so with Cassandra storage the repair is refused with a 409.
+func (as *ApiServer) Fsck(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if as.checker == nil {
+		registryError(w, ErrNotSupported, http.StatusNotImplemented, nil)
+		return
+	}
+	repair := r.Method == "POST" && r.URL.Query().Get("repair") == "true"
+	report, err := as.checker.Check(repair)
+	if err == storage.ErrRepairWhileRunning {
+		registryError(w, ErrRepairWhileRunning, http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		registryError(w, ErrInBackendStore, http.StatusInternalServerError, err)
+		return
+	}
+	encoder := json.NewEncoder(w)
+	err = encoder.Encode(report)
+	if err != nil {
+		registryError(w, ErrEncoding, http.StatusInternalServerError, err)
+	}
+}
+
+// Export streams every client's schemas and configs as NDJSON, or as a gzipped tarball with format=tar.
+func (as *ApiServer) Export(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	format, contentType, ok := archiveFormat(r)
+	if !ok {
+		registryError(w, ErrInvalidArchiveFormat, 422, nil)
+		return
+	}
+	// the archive is streamed, an error is only responded with if nothing was written yet
+	out := &trackingWriter{ResponseWriter: w}
+	out.Header().Set("Content-Type", contentType)
+	err := as.archiver.Export(out, format)
+	if err != nil && !out.written {
+		out.Header().Del("Content-Type")
+		registryError(w, ErrInBackendStore, http.StatusInternalServerError, err)
+		return
+	}
+	if err != nil {
+		log.Errorf("Can't write archive: %s", err)
+	}
+}
+
+// Import restores an archive written by Export. With dry_run=true it only reports what would be imported
+// and the conflicts. An import with conflicts writes nothing and responds with 409.
+func (as *ApiServer) Import(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	defer r.Body.Close()
+	format, _, ok := archiveFormat(r)
+	if !ok {
+		registryError(w, ErrInvalidArchiveFormat, 422, nil)
+		return
+	}
+	archive, err := storage.ReadArchive(r.Body, format)
+	if err != nil {
+		registryError(w, ErrDecoding, http.StatusBadRequest, err)
+		return
+	}
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+	plan, err := as.archiver.Import(archive, dryRun)
+	if err == storage.ErrNoLog {
+		registryError(w, ErrNotSupported, http.StatusNotImplemented, err)
+		return
+	}
+	if err != nil {
+		registryError(w, ErrInBackendStore, http.StatusInternalServerError, err)
+		return
+	}
+	if plan.Imported && as.multiuser {
+		for client := range archive.Clients {
+			as.watcher.Watch(client)
+		}
+	}
+	if len(plan.Conflicts) > 0 && !dryRun {
+		w.WriteHeader(http.StatusConflict)
+	}
+	encoder := json.NewEncoder(w)
+	err = encoder.Encode(plan)
+	if err != nil {
+		registryError(w, ErrEncoding, http.StatusInternalServerError, err)
+	}
+}
+
+// trackingWriter tells whether anything was written to a response.
+type trackingWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (tw *trackingWriter) Write(p []byte) (int, error) {
+	tw.written = true
+	return tw.ResponseWriter.Write(p)
+}
+
+func archiveFormat(r *http.Request) (string, string, bool) {
+	switch r.URL.Query().Get("format") {
+	case "", storage.ArchiveNDJSON:
+		return storage.ArchiveNDJSON, "application/x-ndjson", true
+	case storage.ArchiveTar:
+		return storage.ArchiveTar, "application/gzip", true
+	}
+	return "", "", false
+}
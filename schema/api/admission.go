@@ -0,0 +1,23 @@
+package api
+
+// Admission decides whether a new schema may be registered, after it passed validation and the compatibility check.
+type Admission interface {
+	Admit(request *AdmissionRequest) *AdmissionResponse
+}
+
+// AdmissionRequest is a schema about to be registered. LatestVersion is 0 for a new subject,
+// SchemaType is empty for Avro schemas.
+type AdmissionRequest struct {
+	Client        string
+	Subject       string
+	Schema        string
+	SchemaType    string
+	LatestVersion int
+	LatestSchema  string
+}
+
+// AdmissionResponse allows or denies a registration, Reason tells the caller why it was denied.
+type AdmissionResponse struct {
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+}
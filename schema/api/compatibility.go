@@ -5,11 +5,27 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/goavro/wednesday/schema/validation"
 	"github.com/julienschmidt/httprouter"
+	"github.com/yanzay/log"
 )
 
+// maxPatchRounds limits how many times a schema is patched, a fix can uncover incompatibilities further down.
+const maxPatchRounds = 5
+
+// CompatibilityMessage is the result of a compatibility check. Incompatibilities are listed only with verbose=true.
 type CompatibilityMessage struct {
-	IsCompatible bool `json:"is_compatible"`
+	IsCompatible      bool                         `json:"is_compatible"`
+	Incompatibilities validation.Incompatibilities `json:"incompatibilities,omitempty"`
+}
+
+// PatchMessage is a schema patched to pass the compatibility check of a subject, with the fixes applied to it
+// and the incompatibilities left if no fix was found for them.
+type PatchMessage struct {
+	Schema            string                       `json:"schema"`
+	IsCompatible      bool                         `json:"is_compatible"`
+	Fixes             []*validation.Fix            `json:"fixes"`
+	Incompatibilities validation.Incompatibilities `json:"incompatibilities,omitempty"`
 }
 
 func (as *ApiServer) CheckCompatibility(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
@@ -23,11 +39,16 @@ func (as *ApiServer) CheckCompatibility(w http.ResponseWriter, r *http.Request,
 	}
 
 	defer r.Body.Close()
-	var schema SchemaMessage
+	var req SchemaMessage
 	decoder := json.NewDecoder(r.Body)
-	err = decoder.Decode(&schema)
-	if err != nil || !schemaValid(schema.Schema) {
+	err = decoder.Decode(&req)
+	if err != nil {
 		registryError(w, ErrInvalidSchema, 422, err)
+		return
+	}
+	schemaType, schema, ok := requestSchema(w, &req)
+	if !ok || !parseSchema(w, schemaType, schema) {
+		return
 	}
 
 	oldSchema, found, err := as.storage.GetSchema(client, subject, version)
@@ -41,12 +62,82 @@ func (as *ApiServer) CheckCompatibility(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
-	compatibility, found, _ := as.storage.GetSubjectConfig(client, subject)
+	compatible, incompatibilities := typeCompatible(schemaType, schema, oldSchema, as.compatibilityLevel(client, subject))
+	resp := CompatibilityMessage{
+		IsCompatible: compatible,
+	}
+	if r.URL.Query().Get("verbose") == "true" {
+		resp.Incompatibilities = incompatibilities
+	}
+	encoder := json.NewEncoder(w)
+	err = encoder.Encode(resp)
+	if err != nil {
+		registryError(w, ErrEncoding, http.StatusInternalServerError, err)
+	}
+}
+
+// PatchSchema applies the first suggested fix of every incompatibility with the latest version of the subject
+// until the schema passes the subject's compatibility level or no fix is left. Fixes are suggested for Avro schemas only.
+func (as *ApiServer) PatchSchema(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	client := ps.ByName("client")
+	subject := ps.ByName("subject")
+	defer r.Body.Close()
+	var req SchemaMessage
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&req)
+	if err != nil {
+		registryError(w, ErrInvalidSchema, 422, err)
+		return
+	}
+	schemaType, schema, ok := requestSchema(w, &req)
+	if !ok {
+		return
+	}
+	if schemaType != SchemaTypeAvro {
+		registryError(w, ErrSchemaTypeNotSupported+" "+schemaType, 422, nil)
+		return
+	}
+	if !parseSchema(w, schemaType, schema) {
+		return
+	}
+	latest, found, err := as.storage.GetLatestSchema(client, subject)
+	if err != nil {
+		registryError(w, ErrInBackendStore, http.StatusInternalServerError, err)
+		return
+	}
 	if !found {
-		compatibility, _ = as.storage.GetGlobalConfig(client)
+		registryError(w, ErrSubjectNotFound, http.StatusNotFound, nil)
+		return
 	}
-	resp := CompatibilityMessage{
-		IsCompatible: schemaCompatible(schema.Schema, oldSchema, compatibility), //TODO compatibility
+	latestSchema, ok := avroOnly(w, latest.Schema)
+	if !ok {
+		return
+	}
+
+	compatibility := as.compatibilityLevel(client, subject)
+	resp := PatchMessage{Schema: schema, Fixes: make([]*validation.Fix, 0)}
+	for round := 0; round <= maxPatchRounds; round++ {
+		compatible, incompatibilities := schemaCompatible(resp.Schema, latestSchema, compatibility)
+		resp.IsCompatible, resp.Incompatibilities = compatible, incompatibilities
+		if compatible || len(incompatibilities) == 0 || round == maxPatchRounds {
+			break
+		}
+		fixes := make([]*validation.Fix, 0)
+		for _, incompatibility := range incompatibilities {
+			if len(incompatibility.Fixes) > 0 {
+				fixes = append(fixes, incompatibility.Fixes[0])
+			}
+		}
+		if len(fixes) == 0 {
+			break
+		}
+		patched, err := validation.Patch(resp.Schema, fixes)
+		if err != nil {
+			log.Warningf("Can't patch schema of subject %s: %s", subject, err)
+			break
+		}
+		resp.Schema = patched
+		resp.Fixes = append(resp.Fixes, fixes...)
 	}
 	encoder := json.NewEncoder(w)
 	err = encoder.Encode(resp)
@@ -10,10 +10,20 @@ import (
 )
 
 var compatibilityCheckers = map[string]validation.CompatibilityChecker{
-	storage.CompatibilityNone:     new(validation.NoneCompatibility),
-	storage.CompatibilityBackward: validation.NewBackwardCompatibility(),
-	storage.CompatibilityForward:  validation.NewForwardCompatibility(),
-	storage.CompatibilityFull:     validation.NewFullCompatibility(),
+	storage.CompatibilityNone:      new(validation.NoneCompatibility),
+	storage.CompatibilityBackward:  validation.NewBackwardCompatibility(),
+	storage.CompatibilityForward:   validation.NewForwardCompatibility(),
+	storage.CompatibilityFull:      validation.NewFullCompatibility(),
+	storage.CompatibilityKeyStable: new(validation.KeyStableCompatibility),
+}
+
+// jsonCompatibilityCheckers rejects every schema at KEY_STABLE, JSON Schema has no notion of key fields.
+var jsonCompatibilityCheckers = map[string]validation.JSONCompatibilityChecker{
+	storage.CompatibilityNone:      new(validation.JSONCompatibility),
+	storage.CompatibilityBackward:  &validation.JSONCompatibility{Backward: true},
+	storage.CompatibilityForward:   &validation.JSONCompatibility{Forward: true},
+	storage.CompatibilityFull:      &validation.JSONCompatibility{Backward: true, Forward: true},
+	storage.CompatibilityKeyStable: &validation.JSONLevelNotApplicable{Level: storage.CompatibilityKeyStable},
 }
 
 func (as *ApiServer) UpdateGlobalConfig(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
@@ -114,9 +124,52 @@ func (as *ApiServer) GetSubjectConfig(w http.ResponseWriter, r *http.Request, ps
 	}
 }
 
+// UpdateValidation sets how strictly new schemas of the client are validated, STRICT unless set to LENIENT.
+func (as *ApiServer) UpdateValidation(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	client := ps.ByName("client")
+	defer r.Body.Close()
+	var config storage.ValidationConfig
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&config)
+	if err != nil {
+		registryError(w, ErrDecoding, http.StatusBadRequest, err)
+		return
+	}
+	if config.Validation != storage.ValidationStrict && config.Validation != storage.ValidationLenient {
+		registryError(w, ErrInvalidValidation, 422, nil)
+		return
+	}
+	err = as.storage.UpdateSetting(client, "", storage.SettingValidation, config.Validation)
+	if err != nil {
+		registryError(w, ErrInBackendStore, http.StatusInternalServerError, err)
+		return
+	}
+	encoder := json.NewEncoder(w)
+	err = encoder.Encode(config)
+	if err != nil {
+		registryError(w, ErrEncoding, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+func (as *ApiServer) GetValidation(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	client := ps.ByName("client")
+	config := storage.ValidationConfig{Validation: storage.ValidationStrict}
+	if !as.strictValidation(client) {
+		config.Validation = storage.ValidationLenient
+	}
+	encoder := json.NewEncoder(w)
+	err := encoder.Encode(config)
+	if err != nil {
+		registryError(w, ErrEncoding, http.StatusInternalServerError, err)
+		return
+	}
+}
+
 func validCompatibilityLevel(level string) bool {
 	return level == storage.CompatibilityNone ||
 		level == storage.CompatibilityFull ||
 		level == storage.CompatibilityForward ||
-		level == storage.CompatibilityBackward
+		level == storage.CompatibilityBackward ||
+		level == storage.CompatibilityKeyStable
 }
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/goavro/wednesday/schema/validation"
+	"github.com/julienschmidt/httprouter"
+)
+
+// ContainerMessage is the writer schema of an uploaded container file, checked against a subject or registered
+// with it, and how many of its records were read with the subject's latest schema.
+type ContainerMessage struct {
+	Schema            string                       `json:"schema"`
+	Codec             string                       `json:"codec"`
+	Records           int64                        `json:"records"`
+	ID                int64                        `json:"id,omitempty"`
+	Warnings          validation.Violations        `json:"warnings,omitempty"`
+	IsCompatible      bool                         `json:"is_compatible"`
+	Incompatibilities validation.Incompatibilities `json:"incompatibilities,omitempty"`
+	Sampled           int                          `json:"sampled"`
+	SampleError       string                       `json:"sample_error,omitempty"`
+}
+
+// UploadContainer reads the writer schema of an Avro object container file and checks it against the latest
+// version of a subject, or registers it with register=true. With sample=N the first N records are read with the
+// latest version, or with the file schema if the subject has none. A registration is refused if they can't be.
+func (as *ApiServer) UploadContainer(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	client := ps.ByName("client")
+	subject := ps.ByName("subject")
+	query := r.URL.Query()
+	register := query.Get("register") == "true"
+	sample := 0
+	if query.Get("sample") != "" {
+		var err error
+		sample, err = strconv.Atoi(query.Get("sample"))
+		if err != nil || sample < 0 {
+			registryError(w, ErrDecoding, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	defer r.Body.Close()
+	file, err := ioutil.TempFile("", "container")
+	if err != nil {
+		registryError(w, ErrInBackendStore, http.StatusInternalServerError, err)
+		return
+	}
+	defer os.Remove(file.Name())
+	_, err = io.Copy(file, http.MaxBytesReader(w, r.Body, maxContainerBody))
+	file.Close()
+	if err != nil {
+		registryError(w, ErrDecoding, http.StatusBadRequest, err)
+		return
+	}
+	container, err := validation.OpenContainerFile(file.Name())
+	if err != nil {
+		registryError(w, ErrInvalidContainerFile, 422, err)
+		return
+	}
+	resp := ContainerMessage{Schema: container.Schema, Codec: container.Codec, Records: container.Records}
+
+	latest, exists, _ := as.storage.GetLatestSchema(client, subject)
+	if exists {
+		var ok bool
+		if latest.Schema, ok = avroOnly(w, latest.Schema); !ok {
+			return
+		}
+	}
+	if sample > 0 {
+		readerSchema := container.Schema
+		if exists {
+			readerSchema = latest.Schema
+		}
+		reader, err := validation.ParseSchema(readerSchema)
+		if err != nil {
+			registryError(w, ErrInvalidSchema, 422, err)
+			return
+		}
+		resp.Sampled, err = container.Sample(reader, sample)
+		if err != nil {
+			if register {
+				invalidDatumError(w, ErrInvalidDatum, err)
+				return
+			}
+			resp.SampleError = err.Error()
+		}
+	}
+
+	if register {
+		resp.ID, resp.Warnings, resp.IsCompatible = as.registerSchema(w, client, subject, SchemaTypeAvro, container.Schema)
+		if !resp.IsCompatible {
+			return
+		}
+	} else {
+		if as.strictValidation(client) {
+			if errors := validation.CheckSchema(container.Schema); len(errors) > 0 {
+				invalidSchemaError(w, errors)
+				return
+			}
+		}
+		resp.IsCompatible = true
+		if exists {
+			resp.IsCompatible, resp.Incompatibilities = schemaCompatible(container.Schema, latest.Schema, as.compatibilityLevel(client, subject))
+		}
+	}
+	encoder := json.NewEncoder(w)
+	err = encoder.Encode(resp)
+	if err != nil {
+		registryError(w, ErrEncoding, http.StatusInternalServerError, err)
+	}
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/goavro/wednesday/schema/validation"
+	"github.com/julienschmidt/httprouter"
+)
+
+// DatumMessage is a datum read with a version of a subject, in the Avro JSON encoding.
+type DatumMessage struct {
+	Writer int64       `json:"writer"`
+	Reader int         `json:"reader"`
+	Datum  interface{} `json:"datum"`
+}
+
+// ReadDatum decodes the request body with a version of a subject, resolving it with the schema it was written
+// with. The body is Avro binary data written with the schema id given as writer, or without writer
+// a message in the Confluent wire format, which starts with the schema id.
+func (as *ApiServer) ReadDatum(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	client := ps.ByName("client")
+	subject := ps.ByName("subject")
+	defer r.Body.Close()
+	data, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxDatumBody))
+	if err != nil {
+		registryError(w, ErrDecoding, http.StatusBadRequest, err)
+		return
+	}
+
+	var writerID int64
+	if writer := r.URL.Query().Get("writer"); writer != "" {
+		writerID, err = strconv.ParseInt(writer, 10, 64)
+		if err != nil {
+			registryError(w, ErrDecoding, http.StatusBadRequest, err)
+			return
+		}
+	} else {
+		writerID, data, err = validation.SplitWireFormat(data)
+		if err != nil {
+			invalidDatumError(w, ErrInvalidDatum, err)
+			return
+		}
+	}
+	writerSchema, found, err := as.storage.GetSchemaByID(client, writerID)
+	if err != nil {
+		registryError(w, ErrInBackendStore, http.StatusInternalServerError, err)
+		return
+	}
+	if !found {
+		registryError(w, ErrSchemaNotFound, http.StatusNotFound, nil)
+		return
+	}
+	readerVersion, readerSchema, ok := as.versionSchema(w, client, subject, ps.ByName("version"))
+	if !ok {
+		return
+	}
+	writerSchema, ok = avroOnly(w, writerSchema)
+	if !ok {
+		return
+	}
+	writer, err := validation.ParseSchema(writerSchema)
+	if err != nil {
+		registryError(w, ErrInvalidSchema, 422, err)
+		return
+	}
+	reader, err := validation.ParseSchema(readerSchema)
+	if err != nil {
+		registryError(w, ErrInvalidSchema, 422, err)
+		return
+	}
+
+	datum, err := validation.ReadDatum(writer, reader, data)
+	if err != nil {
+		invalidDatumError(w, ErrInvalidDatum, err)
+		return
+	}
+	encoder := json.NewEncoder(w)
+	err = encoder.Encode(DatumMessage{Writer: writerID, Reader: readerVersion, Datum: datum})
+	if err != nil {
+		registryError(w, ErrEncoding, http.StatusInternalServerError, err)
+	}
+}
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/elodina/go-avro"
+	"github.com/goavro/wednesday/schema/storage"
+	"github.com/goavro/wednesday/schema/validation"
+	"github.com/julienschmidt/httprouter"
+	"github.com/yanzay/log"
+)
+
+// maxMatrixCacheSize bounds the cached version pairs, the cache is dropped once it grows past it.
+const maxMatrixCacheSize = 100000
+
+// MatrixMessage tells which versions of a subject can read which. Row i of Readable is the reader version
+// Versions[i] and column j the writer version Versions[j]. Every pair that can't be read is listed in Failures.
+type MatrixMessage struct {
+	Subject  string           `json:"subject"`
+	Versions []int            `json:"versions"`
+	Readable [][]bool         `json:"readable"`
+	Failures []*MatrixFailure `json:"failures"`
+}
+
+// MatrixFailure is why data written with one version can't be read with another.
+type MatrixFailure struct {
+	Reader            int                          `json:"reader"`
+	Writer            int                          `json:"writer"`
+	Incompatibilities validation.Incompatibilities `json:"incompatibilities"`
+}
+
+type matrixKey struct {
+	client string
+	writer int64
+	reader int64
+}
+
+// matrixCache keeps the incompatibilities of schema pairs by id. Ids never change their schema, so entries
+// never go stale.
+type matrixCache struct {
+	lock    sync.Mutex
+	results map[matrixKey]validation.Incompatibilities
+}
+
+func newMatrixCache() *matrixCache {
+	return &matrixCache{results: make(map[matrixKey]validation.Incompatibilities)}
+}
+
+func (mc *matrixCache) get(key matrixKey) (validation.Incompatibilities, bool) {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+	incompatibilities, ok := mc.results[key]
+	return incompatibilities, ok
+}
+
+func (mc *matrixCache) put(key matrixKey, incompatibilities validation.Incompatibilities) {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+	if len(mc.results) >= maxMatrixCacheSize {
+		mc.results = make(map[matrixKey]validation.Incompatibilities)
+	}
+	mc.results[key] = incompatibilities
+}
+
+// GetCompatibilityMatrix checks every version of a subject against every other with the BACKWARD check,
+// which is whether the reader version can read data of the writer version. With format=csv the matrix is
+// written as CSV, a cell is empty if the reader can read the writer and lists the reasons otherwise.
+func (as *ApiServer) GetCompatibilityMatrix(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	client := ps.ByName("client")
+	subject := ps.ByName("subject")
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		registryError(w, ErrInvalidMatrixFormat, 422, nil)
+		return
+	}
+
+	versions, found, err := as.storage.GetVersions(client, subject)
+	if err != nil {
+		registryError(w, ErrInBackendStore, http.StatusInternalServerError, err)
+		return
+	}
+	if !found {
+		registryError(w, ErrSubjectNotFound, http.StatusNotFound, nil)
+		return
+	}
+	ids := make([]int64, len(versions))
+	schemas := make([]avro.Schema, len(versions))
+	for i, version := range versions {
+		raw, found, err := as.storage.GetSchema(client, subject, version)
+		if err != nil {
+			registryError(w, ErrInBackendStore, http.StatusInternalServerError, err)
+			return
+		}
+		if !found {
+			registryError(w, ErrSchemaNotFound, http.StatusNotFound, nil)
+			return
+		}
+		ids[i] = as.storage.GetID(client, raw)
+		schema, ok := avroOnly(w, raw)
+		if !ok {
+			return
+		}
+		schemas[i], err = validation.ParseSchema(schema)
+		if err != nil {
+			registryError(w, ErrInvalidSchema, 422, err)
+			return
+		}
+	}
+
+	resp := MatrixMessage{
+		Subject:  subject,
+		Versions: versions,
+		Readable: make([][]bool, len(versions)),
+		Failures: make([]*MatrixFailure, 0),
+	}
+	checker := compatibilityCheckers[storage.CompatibilityBackward]
+	for i := range versions {
+		resp.Readable[i] = make([]bool, len(versions))
+		for j := range versions {
+			key := matrixKey{client, ids[j], ids[i]}
+			incompatibilities, ok := as.matrix.get(key)
+			if !ok {
+				err := checker.Validate(schemas[i], schemas[j])
+				incompatibilities, _ = err.(validation.Incompatibilities)
+				as.matrix.put(key, incompatibilities)
+			}
+			resp.Readable[i][j] = len(incompatibilities) == 0
+			if len(incompatibilities) > 0 {
+				resp.Failures = append(resp.Failures, &MatrixFailure{Reader: versions[i], Writer: versions[j], Incompatibilities: incompatibilities})
+			}
+		}
+	}
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		err = writeMatrixCSV(w, &resp)
+		if err != nil {
+			log.Errorf("Can't write compatibility matrix: %s", err)
+		}
+		return
+	}
+	encoder := json.NewEncoder(w)
+	err = encoder.Encode(resp)
+	if err != nil {
+		registryError(w, ErrEncoding, http.StatusInternalServerError, err)
+	}
+}
+
+// writeMatrixCSV writes a row per reader version and a column per writer version.
+func writeMatrixCSV(w http.ResponseWriter, matrix *MatrixMessage) error {
+	reasons := make(map[[2]int]string)
+	for _, failure := range matrix.Failures {
+		messages := make([]string, len(failure.Incompatibilities))
+		for i, incompatibility := range failure.Incompatibilities {
+			messages[i] = incompatibility.Path + ": " + incompatibility.Message
+		}
+		reasons[[2]int{failure.Reader, failure.Writer}] = strings.Join(messages, "; ")
+	}
+
+	out := csv.NewWriter(w)
+	header := []string{"reader\\writer"}
+	for _, version := range matrix.Versions {
+		header = append(header, strconv.Itoa(version))
+	}
+	err := out.Write(header)
+	if err != nil {
+		return err
+	}
+	for _, reader := range matrix.Versions {
+		row := []string{strconv.Itoa(reader)}
+		for _, writer := range matrix.Versions {
+			row = append(row, reasons[[2]int{reader, writer}])
+		}
+		err = out.Write(row)
+		if err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
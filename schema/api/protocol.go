@@ -0,0 +1,49 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/goavro/wednesday/schema/storage"
+	"github.com/goavro/wednesday/schema/validation"
+)
+
+// Schema types a schema can be given as. IDL and protocol documents are registered as the Avro schema they define.
+const (
+	SchemaTypeAvro         = storage.SchemaTypeAvro
+	SchemaTypeAvroIDL      = "AVRO_IDL"
+	SchemaTypeAvroProtocol = "AVRO_PROTOCOL"
+	SchemaTypeJSON         = storage.SchemaTypeJSON
+)
+
+// requestSchema returns the type and the schema of a request, the record it selects if it is an IDL or a protocol
+// document, or writes the error response. Parse errors give the line and column they were found at.
+func requestSchema(w http.ResponseWriter, req *SchemaMessage) (string, string, bool) {
+	var protocol *validation.Protocol
+	var err error
+	switch req.SchemaType {
+	case "", SchemaTypeAvro:
+		return SchemaTypeAvro, req.Schema, true
+	case SchemaTypeJSON:
+		return SchemaTypeJSON, req.Schema, true
+	case SchemaTypeAvroIDL:
+		protocol, err = validation.ParseIDL(req.Schema)
+	case SchemaTypeAvroProtocol:
+		protocol, err = validation.ParseProtocol(req.Schema)
+	default:
+		registryError(w, ErrInvalidSchemaType, 422, nil)
+		return "", "", false
+	}
+	schema := ""
+	if err == nil {
+		schema, err = protocol.Schema(req.Record)
+	}
+	if schemaError, ok := err.(*validation.SchemaError); ok {
+		invalidSchemaError(w, validation.SchemaErrors{schemaError})
+		return "", "", false
+	}
+	if err != nil {
+		registryError(w, ErrInvalidSchema, 422, err)
+		return "", "", false
+	}
+	return SchemaTypeAvro, schema, true
+}
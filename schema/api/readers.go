@@ -0,0 +1,160 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/goavro/wednesday/schema/storage"
+	"github.com/goavro/wednesday/schema/validation"
+	"github.com/julienschmidt/httprouter"
+)
+
+// DefaultReaderTTL is how long a reader schema stays active if the consumer registering it doesn't say.
+const DefaultReaderTTL = 5 * time.Minute
+
+// ReaderMessage registers the reader schema of a consumer, TTL is in seconds.
+type ReaderMessage struct {
+	Schema string `json:"schema"`
+	TTL    int    `json:"ttl,omitempty"`
+}
+
+// ReaderStatus is an active reader schema of a subject.
+type ReaderStatus struct {
+	Consumer string    `json:"consumer"`
+	Schema   string    `json:"schema"`
+	Expires  time.Time `json:"expires"`
+}
+
+// BrokenReader is a consumer whose reader schema can't read a new schema.
+type BrokenReader struct {
+	Consumer          string                       `json:"consumer"`
+	Incompatibilities validation.Incompatibilities `json:"incompatibilities"`
+}
+
+// RegisterReader registers or renews the reader schema a consumer reads a subject with. Every consumer is written
+// to the log as a setting of its own, a renewal only once less than half of the TTL is left, so consumers can renew
+// as often as they like without growing the log.
+func (as *ApiServer) RegisterReader(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	client := ps.ByName("client")
+	subject := ps.ByName("subject")
+	consumer := ps.ByName("consumer")
+	defer r.Body.Close()
+	var req ReaderMessage
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&req)
+	if err != nil || !schemaValid(req.Schema) {
+		registryError(w, ErrInvalidSchema, 422, err)
+		return
+	}
+	if req.TTL < 0 {
+		registryError(w, ErrInvalidTTL, 422, nil)
+		return
+	}
+	ttl := DefaultReaderTTL
+	if req.TTL > 0 {
+		ttl = time.Duration(req.TTL) * time.Second
+	}
+	if latest, exists, _ := as.storage.GetLatestSchema(client, subject); exists {
+		if _, ok := avroOnly(w, latest.Schema); !ok {
+			return
+		}
+	}
+
+	reader, err := as.reader(client, subject, consumer)
+	if err != nil {
+		registryError(w, ErrInBackendStore, http.StatusInternalServerError, err)
+		return
+	}
+	now := time.Now()
+	if reader == nil || reader.Schema != req.Schema || reader.Expires.Before(now.Add(ttl/2)) || reader.Expires.After(now.Add(ttl)) {
+		reader = &storage.Reader{Schema: req.Schema, Expires: now.Add(ttl).UTC()}
+		err = as.storage.UpdateSetting(client, subject, storage.ReaderSetting(consumer), reader.String())
+		if err != nil {
+			registryError(w, ErrInBackendStore, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	encoder := json.NewEncoder(w)
+	err = encoder.Encode(&ReaderStatus{Consumer: consumer, Schema: reader.Schema, Expires: reader.Expires})
+	if err != nil {
+		registryError(w, ErrEncoding, http.StatusInternalServerError, err)
+	}
+}
+
+// GetReaders lists the active reader schemas of a subject.
+func (as *ApiServer) GetReaders(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	client := ps.ByName("client")
+	subject := ps.ByName("subject")
+	readers, err := as.readers(client, subject)
+	if err != nil {
+		registryError(w, ErrInBackendStore, http.StatusInternalServerError, err)
+		return
+	}
+	statuses := make([]*ReaderStatus, 0, len(readers))
+	for _, consumer := range readers.SortedConsumers() {
+		statuses = append(statuses, &ReaderStatus{Consumer: consumer, Schema: readers[consumer].Schema, Expires: readers[consumer].Expires})
+	}
+	encoder := json.NewEncoder(w)
+	err = encoder.Encode(statuses)
+	if err != nil {
+		registryError(w, ErrEncoding, http.StatusInternalServerError, err)
+	}
+}
+
+// DeleteReader removes the reader schema of a consumer that stopped reading the subject.
+func (as *ApiServer) DeleteReader(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	client := ps.ByName("client")
+	subject := ps.ByName("subject")
+	consumer := ps.ByName("consumer")
+	reader, err := as.reader(client, subject, consumer)
+	if err != nil {
+		registryError(w, ErrInBackendStore, http.StatusInternalServerError, err)
+		return
+	}
+	if reader == nil || !reader.Expires.After(time.Now()) {
+		registryError(w, ErrReaderNotFound, http.StatusNotFound, nil)
+		return
+	}
+	err = as.storage.UpdateSetting(client, subject, storage.ReaderSetting(consumer), "")
+	if err != nil {
+		registryError(w, ErrInBackendStore, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// reader returns the reader schema of a consumer of a subject, nil if it has none or it was deleted.
+func (as *ApiServer) reader(client string, subject string, consumer string) (*storage.Reader, error) {
+	value, _, err := as.storage.GetSetting(client, subject, storage.ReaderSetting(consumer))
+	if err != nil {
+		return nil, err
+	}
+	return storage.ParseReader(value)
+}
+
+// readers returns the active reader schemas of a subject.
+func (as *ApiServer) readers(client string, subject string) (storage.Readers, error) {
+	settings, err := as.storage.GetSettings(client, subject)
+	if err != nil {
+		return nil, err
+	}
+	return storage.ParseReaders(settings).Active(time.Now()), nil
+}
+
+// brokenReaders returns every active consumer of a subject that can't read data written with the schema.
+// An error means the readers couldn't be read, and the schema must not be registered unchecked.
+func (as *ApiServer) brokenReaders(client string, subject string, schema string) ([]*BrokenReader, error) {
+	readers, err := as.readers(client, subject)
+	if err != nil {
+		return nil, err
+	}
+	broken := make([]*BrokenReader, 0)
+	for _, consumer := range readers.SortedConsumers() {
+		compatible, incompatibilities := schemaCompatible(schema, readers[consumer].Schema, storage.CompatibilityForward)
+		if !compatible {
+			broken = append(broken, &BrokenReader{Consumer: consumer, Incompatibilities: incompatibilities})
+		}
+	}
+	return broken, nil
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goavro/wednesday/schema/storage"
+	"github.com/julienschmidt/httprouter"
+)
+
+// settingsOutage fails to read the settings of a subject as a whole.
+type settingsOutage struct {
+	*storage.InMemoryStorage
+}
+
+func (settingsOutage) GetSettings(string, string) (map[string]string, error) {
+	return nil, errors.New("backend unavailable")
+}
+
+func registerString(as *ApiServer) *httptest.ResponseRecorder {
+	r, _ := http.NewRequest("POST", "/subjects/s/versions", strings.NewReader(`{"schema": "\"string\""}`))
+	w := httptest.NewRecorder()
+	as.NewSchema(w, r, httprouter.Params{{Key: "client", Value: "snow"}, {Key: "subject", Value: "s"}})
+	return w
+}
+
+func TestRegistrationChecksReaders(t *testing.T) {
+	state := storage.NewInMemoryStorage()
+	reader := &storage.Reader{Schema: `"int"`, Expires: time.Now().Add(time.Hour)}
+	state.SetSetting("snow", "s", storage.ReaderSetting("billing"), reader.String())
+	state.SetSetting("snow", "s", storage.ReaderSetting("broken"), "{")
+
+	// the setting that doesn't decode doesn't keep the billing reader from being checked
+	as := NewApiServer(":0", &storage.CombinedStorage{StorageWriter: new(storage.MockStorageWriter),
+		StorageStateReader: state, StorageStateWriter: state}, nil, nil, nil, nil, false, "schemas")
+	if w := registerString(as); w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "billing") {
+		t.Errorf("Expected the billing reader to break the registration, got %d %s", w.Code, w.Body.String())
+	}
+
+	as = NewApiServer(":0", &storage.CombinedStorage{StorageWriter: new(storage.MockStorageWriter),
+		StorageStateReader: settingsOutage{state}, StorageStateWriter: state}, nil, nil, nil, nil, false, "schemas")
+	if w := registerString(as); w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected readers that can't be read to fail the registration, got %d %s", w.Code, w.Body.String())
+	}
+}
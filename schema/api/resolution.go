@@ -0,0 +1,86 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/goavro/wednesday/schema/validation"
+	"github.com/julienschmidt/httprouter"
+)
+
+// ResolutionMessage is the resolution plan for reading data written with one version of a subject with another.
+type ResolutionMessage struct {
+	Subject string `json:"subject"`
+	Writer  int    `json:"writer"`
+	Reader  int    `json:"reader"`
+	*validation.ResolutionPlan
+}
+
+// GetResolution explains step by step how data written with the writer version of a subject is read with the
+// reader version. Both versions can be "latest", which is also the default.
+func (as *ApiServer) GetResolution(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	client := ps.ByName("client")
+	subject := ps.ByName("subject")
+	query := r.URL.Query()
+	writerVersion, writerSchema, ok := as.versionSchema(w, client, subject, query.Get("writer"))
+	if !ok {
+		return
+	}
+	readerVersion, readerSchema, ok := as.versionSchema(w, client, subject, query.Get("reader"))
+	if !ok {
+		return
+	}
+	writer, err := validation.ParseSchema(writerSchema)
+	if err != nil {
+		registryError(w, ErrInvalidSchema, 422, err)
+		return
+	}
+	reader, err := validation.ParseSchema(readerSchema)
+	if err != nil {
+		registryError(w, ErrInvalidSchema, 422, err)
+		return
+	}
+
+	resp := ResolutionMessage{
+		Subject:        subject,
+		Writer:         writerVersion,
+		Reader:         readerVersion,
+		ResolutionPlan: validation.Explain(writer, reader),
+	}
+	encoder := json.NewEncoder(w)
+	err = encoder.Encode(resp)
+	if err != nil {
+		registryError(w, ErrEncoding, http.StatusInternalServerError, err)
+	}
+}
+
+// versionSchema returns a version of a subject and its Avro schema, writing the error response if there is none
+// or the subject holds schemas of another type. Every endpoint it is used by works with Avro only.
+func (as *ApiServer) versionSchema(w http.ResponseWriter, client string, subject string, versionStr string) (int, string, bool) {
+	if versionStr == "" || versionStr == "latest" {
+		latest, found, _ := as.storage.GetLatestSchema(client, subject)
+		if !found {
+			registryError(w, ErrSchemaNotFound, http.StatusNotFound, nil)
+			return 0, "", false
+		}
+		schema, ok := avroOnly(w, latest.Schema)
+		return latest.Version, schema, ok
+	}
+	version, err := strconv.Atoi(versionStr)
+	if err != nil {
+		registryError(w, ErrDecoding, http.StatusBadRequest, err)
+		return 0, "", false
+	}
+	schema, found, err := as.storage.GetSchema(client, subject, version)
+	if err != nil {
+		registryError(w, ErrInBackendStore, http.StatusInternalServerError, err)
+		return 0, "", false
+	}
+	if !found {
+		registryError(w, ErrSchemaNotFound, http.StatusNotFound, nil)
+		return 0, "", false
+	}
+	schema, ok := avroOnly(w, schema)
+	return version, schema, ok
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/goavro/wednesday/schema/storage"
+	"github.com/goavro/wednesday/schema/validation"
+	"github.com/julienschmidt/httprouter"
+	"github.com/yanzay/log"
+)
+
+// IDMessage is the response to a registered schema, with the violations of WARN level lint rules if there are any.
+type IDMessage struct {
+	ID       int64                 `json:"id"`
+	Warnings validation.Violations `json:"warnings,omitempty"`
+}
+
+// UpdateRules sets the lint rules of the client at /rules, or of a single subject at /rules/:subject.
+// A subject's rule set replaces the client's one for that subject.
+func (as *ApiServer) UpdateRules(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	client := ps.ByName("client")
+	subject := ps.ByName("subject")
+	defer r.Body.Close()
+	var ruleSet validation.RuleSet
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&ruleSet)
+	if err != nil {
+		registryError(w, ErrDecoding, http.StatusBadRequest, err)
+		return
+	}
+	if ruleSet.Rules == nil {
+		ruleSet.Rules = make([]*validation.RuleConfig, 0)
+	}
+	err = ruleSet.Validate()
+	if err != nil {
+		registryError(w, ErrInvalidRules, 422, err)
+		return
+	}
+	value, err := json.Marshal(ruleSet)
+	if err != nil {
+		registryError(w, ErrEncoding, http.StatusInternalServerError, err)
+		return
+	}
+	err = as.storage.UpdateSetting(client, subject, storage.SettingRules, string(value))
+	if err != nil {
+		registryError(w, ErrInBackendStore, http.StatusInternalServerError, err)
+		return
+	}
+	encoder := json.NewEncoder(w)
+	err = encoder.Encode(ruleSet)
+	if err != nil {
+		registryError(w, ErrEncoding, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// GetRules returns the rule set of the client or of a subject. A client without rules has an empty rule set,
+// a subject without its own rule set is not found.
+func (as *ApiServer) GetRules(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	client := ps.ByName("client")
+	subject := ps.ByName("subject")
+	value, found, err := as.storage.GetSetting(client, subject, storage.SettingRules)
+	if err != nil {
+		registryError(w, ErrInBackendStore, http.StatusInternalServerError, err)
+		return
+	}
+	if !found && subject != "" {
+		registryError(w, ErrRulesNotFound, http.StatusNotFound, nil)
+		return
+	}
+	ruleSet := &validation.RuleSet{Rules: make([]*validation.RuleConfig, 0)}
+	if found {
+		ruleSet, err = validation.ParseRuleSet(value)
+		if err != nil {
+			registryError(w, ErrInBackendStore, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	encoder := json.NewEncoder(w)
+	err = encoder.Encode(ruleSet)
+	if err != nil {
+		registryError(w, ErrEncoding, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// rules returns the rule set new schemas of a subject are linted with, nil if there is none.
+func (as *ApiServer) rules(client string, subject string) *validation.RuleSet {
+	for _, scope := range []string{subject, ""} {
+		value, found, err := as.storage.GetSetting(client, scope, storage.SettingRules)
+		if err != nil {
+			log.Warningf("Can't get rules of client %s, not linting: %s", client, err)
+			return nil
+		}
+		if !found {
+			continue
+		}
+		ruleSet, err := validation.ParseRuleSet(value)
+		if err != nil {
+			log.Warningf("Invalid rule set of client %s, not linting: %s", client, err)
+			return nil
+		}
+		return ruleSet
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/goavro/wednesday/schema/storage"
+	"github.com/goavro/wednesday/schema/validation"
+	"github.com/julienschmidt/httprouter"
+	"github.com/yanzay/log"
+)
+
+// SchemaType parses and checks the schemas of one type. Registration, lookups and compatibility levels work
+// the same way for every type, lint rules, readers and the data endpoints only work with Avro schemas.
+type SchemaType interface {
+	// Parse returns why a schema can't be parsed, a *validation.SchemaError if it can tell where
+	Parse(schema string) error
+	// Check returns every problem strict validation finds in a schema
+	Check(schema string) validation.SchemaErrors
+	// Compatible checks a schema against an existing one of the same type at the given compatibility level
+	Compatible(toValidate string, existing string, compatibilityLevel string) (bool, validation.Incompatibilities)
+}
+
+var schemaTypes = map[string]SchemaType{
+	storage.SchemaTypeAvro: new(AvroSchemaType),
+	storage.SchemaTypeJSON: new(JSONSchemaType),
+}
+
+type AvroSchemaType struct{}
+
+func (*AvroSchemaType) Parse(schema string) error {
+	_, err := validation.ParseSchema(schema)
+	return err
+}
+
+func (*AvroSchemaType) Check(schema string) validation.SchemaErrors {
+	return validation.CheckSchema(schema)
+}
+
+func (*AvroSchemaType) Compatible(toValidate string, existing string, compatibilityLevel string) (bool, validation.Incompatibilities) {
+	return schemaCompatible(toValidate, existing, compatibilityLevel)
+}
+
+type JSONSchemaType struct{}
+
+func (*JSONSchemaType) Parse(schema string) error {
+	_, err := validation.ParseJSONSchema(schema)
+	return err
+}
+
+func (*JSONSchemaType) Check(schema string) validation.SchemaErrors {
+	return validation.CheckJSONSchema(schema)
+}
+
+func (*JSONSchemaType) Compatible(toValidate string, existing string, compatibilityLevel string) (bool, validation.Incompatibilities) {
+	return jsonCompatibility.compatible(toValidate, existing, compatibilityLevel)
+}
+
+// parseSchema parses a schema of a type or writes the error response.
+func parseSchema(w http.ResponseWriter, schemaType string, schema string) bool {
+	log.Infof("Validating %s schema %s", schemaType, schema)
+	err := schemaTypes[schemaType].Parse(schema)
+	if schemaError, ok := err.(*validation.SchemaError); ok {
+		invalidSchemaError(w, validation.SchemaErrors{schemaError})
+		return false
+	}
+	if err != nil {
+		registryError(w, ErrInvalidSchema, 422, err)
+		return false
+	}
+	return true
+}
+
+// typeCompatible checks a schema against a stored one. Schemas of different types are never compatible.
+func typeCompatible(schemaType string, toValidate string, stored string, compatibilityLevel string) (bool, validation.Incompatibilities) {
+	existingType, existing := storage.DecodeSchema(stored)
+	if existingType != schemaType {
+		log.Infof("Schema type %s does not match registered schema type %s", schemaType, existingType)
+		return false, nil
+	}
+	return schemaTypes[schemaType].Compatible(toValidate, existing, compatibilityLevel)
+}
+
+// avroOnly returns a stored schema for an endpoint that only works with Avro schemas,
+// or writes the error response if the schema is of another type.
+func avroOnly(w http.ResponseWriter, stored string) (string, bool) {
+	schemaType, schema := storage.DecodeSchema(stored)
+	if schemaType != SchemaTypeAvro {
+		registryError(w, ErrSchemaTypeNotSupported+" "+schemaType, 422, nil)
+		return "", false
+	}
+	return schema, true
+}
+
+// responseType is the schemaType a stored schema is responded with, empty for Avro as Confluent clients expect.
+func responseType(schemaType string) string {
+	if schemaType == storage.SchemaTypeAvro {
+		return ""
+	}
+	return schemaType
+}
+
+// GetSchemaTypes lists the schema types schemas can be registered as.
+func (as *ApiServer) GetSchemaTypes(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	types := make([]string, 0, len(schemaTypes))
+	for schemaType := range schemaTypes {
+		types = append(types, schemaType)
+	}
+	sort.Strings(types)
+	encoder := json.NewEncoder(w)
+	err := encoder.Encode(types)
+	if err != nil {
+		registryError(w, ErrEncoding, http.StatusInternalServerError, err)
+	}
+}
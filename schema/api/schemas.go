@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/goavro/wednesday/schema/storage"
 	"github.com/julienschmidt/httprouter"
 )
 
@@ -25,7 +26,8 @@ func (as *ApiServer) GetSchema(w http.ResponseWriter, r *http.Request, ps httpro
 		return
 	}
 	w.Header().Add("Content-Type", "application/vnd.schemaregistry.v1+json")
-	message := SchemaMessage{schema}
+	schemaType, schema := storage.DecodeSchema(schema)
+	message := SchemaMessage{Schema: schema, SchemaType: responseType(schemaType)}
 	encoder := json.NewEncoder(w)
 	err = encoder.Encode(message)
 	if err != nil {
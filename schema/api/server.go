@@ -4,9 +4,10 @@ import (
 	"fmt"
 	"net/http"
 
-	avro "github.com/elodina/go-avro"
+	"github.com/elodina/go-avro"
 	"github.com/goavro/wednesday/auth"
 	"github.com/goavro/wednesday/schema/storage"
+	"github.com/goavro/wednesday/schema/validation"
 	"github.com/julienschmidt/httprouter"
 	"github.com/yanzay/log"
 )
@@ -17,22 +18,40 @@ type Watcher interface {
 
 type SchemaMessage struct {
 	Schema string `json:"schema"`
+	// SchemaType is AVRO if empty, JSON for a JSON Schema, or AVRO_IDL or AVRO_PROTOCOL for a protocol defining the schema
+	SchemaType string `json:"schemaType,omitempty"`
+	// Record selects the named type of a protocol to use as the schema
+	Record string `json:"record,omitempty"`
 }
 
 type ApiServer struct {
-	storage storage.Storage
-	address string
-	watcher Watcher
+	storage  storage.Storage
+	address  string
+	watcher  Watcher
+	checker  ConsistencyChecker
+	archiver Archiver
+	// admission is nil if new schemas are not sent for admission
+	admission Admission
+	// matrix caches compatibility matrix results by schema ids
+	matrix *matrixCache
+	// types caches the named types of every subject by version
+	types *typeIndexCache
 
 	multiuser bool
 	topic     string
 }
 
-func NewApiServer(addr string, stor storage.Storage, watcher Watcher, multiuser bool, topic string) *ApiServer {
+func NewApiServer(addr string, stor storage.Storage, watcher Watcher, checker ConsistencyChecker, archiver Archiver, admission Admission,
+	multiuser bool, topic string) *ApiServer {
 	server := &ApiServer{
 		storage:   stor,
 		address:   addr,
 		watcher:   watcher,
+		checker:   checker,
+		archiver:  archiver,
+		admission: admission,
+		matrix:    newMatrixCache(),
+		types:     newTypeIndexCache(),
 		multiuser: multiuser,
 		topic:     topic,
 	}
@@ -41,17 +60,42 @@ func NewApiServer(addr string, stor storage.Storage, watcher Watcher, multiuser
 
 func (as *ApiServer) Start() error {
 	router := httprouter.New()
+	router.GET("/schemas/types", as.auth(as.GetSchemaTypes))
 	router.GET("/schemas/ids/:id", as.auth(as.GetSchema))
+	router.POST("/schemas/ids/:id/validate", as.auth(as.ValidateIDDatum))
 	router.GET("/subjects", as.auth(as.GetSubjects))
 	router.GET("/subjects/:subject/versions", as.auth(as.GetVersionList))
 	router.GET("/subjects/:subject/versions/:version", as.auth(as.GetVersion))
 	router.POST("/subjects/:subject/versions", as.auth(as.NewSchema))
+	router.POST("/subjects/:subject/versions/:version/read", as.auth(as.ReadDatum))
+	router.POST("/subjects/:subject/versions/:version/validate", as.auth(as.ValidateVersionDatum))
+	router.POST("/subjects/:subject/versions/:version/encode", as.auth(as.EncodeWireFormat))
+	router.POST("/wire/decode", as.auth(as.DecodeWireFormat))
 	router.POST("/subjects/:subject", as.auth(as.CheckRegistered))
+	router.POST("/subjects/:subject/container", as.auth(as.UploadContainer))
+	router.GET("/subjects/:subject/resolution", as.auth(as.GetResolution))
+	router.GET("/subjects/:subject/compatibility-matrix", as.auth(as.GetCompatibilityMatrix))
+	router.GET("/subjects/:subject/readers", as.auth(as.GetReaders))
+	router.PUT("/subjects/:subject/readers/:consumer", as.auth(as.RegisterReader))
+	router.DELETE("/subjects/:subject/readers/:consumer", as.auth(as.DeleteReader))
 	router.POST("/compatibility/subjects/:subject/versions/:version", as.auth(as.CheckCompatibility))
+	router.POST("/compatibility/subjects/:subject/patch", as.auth(as.PatchSchema))
 	router.PUT("/config", as.auth(as.UpdateGlobalConfig))
 	router.GET("/config", as.auth(as.GetGlobalConfig))
 	router.PUT("/config/:subject", as.auth(as.UpdateSubjectConfig))
 	router.GET("/config/:subject", as.auth(as.GetSubjectConfig))
+	router.PUT("/validation", as.auth(as.UpdateValidation))
+	router.GET("/validation", as.auth(as.GetValidation))
+	router.GET("/types/:name", as.auth(as.GetNamedType))
+	router.POST("/types/:name/impact", as.auth(as.CheckNamedTypeImpact))
+	router.PUT("/rules", as.auth(as.UpdateRules))
+	router.GET("/rules", as.auth(as.GetRules))
+	router.PUT("/rules/:subject", as.auth(as.UpdateRules))
+	router.GET("/rules/:subject", as.auth(as.GetRules))
+	router.GET("/admin/fsck", as.admin(as.auth(as.Fsck)))
+	router.POST("/admin/fsck", as.admin(as.auth(as.Fsck)))
+	router.GET("/admin/export", as.admin(as.auth(as.Export)))
+	router.POST("/admin/import", as.admin(as.auth(as.Import)))
 
 	if as.multiuser {
 		//router.POST("/users", as.admin(as.auth(as.CreateUser)))
@@ -101,12 +145,32 @@ func (as *ApiServer) auth(handler httprouter.Handle) httprouter.Handle {
 	}
 }
 
+// admin lets only admin users through, in single user mode too. The user has to send its key along with its name,
+// unknown users and wrong keys are refused with 403.
 func (as *ApiServer) admin(handler httprouter.Handle) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		name := r.Header.Get("X-Api-User")
+		token := r.Header.Get("X-Api-Key")
+		if name == "" || token == "" {
+			registryError(w, ErrUnauthorized, http.StatusForbidden, nil)
+			return
+		}
+		user, ok := as.storage.UserByName(name)
+		if !ok || user.Token != token {
+			authorized, err := auth.Authorize(name, token)
+			if err != nil {
+				registryError(w, ErrAuthStore, http.StatusInternalServerError, err)
+				return
+			}
+			if !authorized {
+				registryError(w, ErrUnauthorized, http.StatusForbidden, nil)
+				return
+			}
+		}
 		admin, err := auth.IsAdmin(name)
 		if err != nil {
 			registryError(w, ErrAuthStore, http.StatusInternalServerError, err)
+			return
 		}
 		if !admin {
 			registryError(w, ErrUnauthorized, http.StatusForbidden, nil)
@@ -118,7 +182,7 @@ func (as *ApiServer) admin(handler httprouter.Handle) httprouter.Handle {
 
 func schemaValid(schema string) bool {
 	log.Infof("Validating schema %s", schema)
-	_, err := avro.ParseSchema(schema)
+	_, err := validation.ParseSchema(schema)
 	if err != nil {
 		log.Infof("Schema is invalid: %s", err)
 		return false
@@ -127,21 +191,88 @@ func schemaValid(schema string) bool {
 	return true
 }
 
-func schemaCompatible(toValidate string, existing string, compatibilityLevel string) bool {
-	schemaToValidate := avro.MustParseSchema(toValidate)
-	existingSchema := avro.MustParseSchema(existing)
+// strictValidation tells if new schemas of a client are validated strictly, which they are unless the client opted out.
+func (as *ApiServer) strictValidation(client string) bool {
+	level, found, err := as.storage.GetSetting(client, "", storage.SettingValidation)
+	if err != nil {
+		log.Warningf("Can't get validation level of client %s, validating strictly: %s", client, err)
+	}
+	return !found || level != storage.ValidationLenient
+}
 
-	checker, ok := compatibilityCheckers[compatibilityLevel]
-	if !ok {
-		log.Warningf("Compatibility level %s does not exist", compatibilityLevel)
-		return false
+// compatibilityLevel returns the compatibility level of a subject, or the client's if the subject has none.
+func (as *ApiServer) compatibilityLevel(client string, subject string) string {
+	compatibility, found, _ := as.storage.GetSubjectConfig(client, subject)
+	if !found {
+		compatibility, _ = as.storage.GetGlobalConfig(client)
 	}
+	return compatibility
+}
+
+// schemaCompatible checks a schema against an existing one at the given compatibility level.
+// If the check fails because of the schemas and not because either can't be parsed, it returns every incompatibility found.
+func schemaCompatible(toValidate string, existing string, compatibilityLevel string) (bool, validation.Incompatibilities) {
+	return avroCompatibility.compatible(toValidate, existing, compatibilityLevel)
+}
+
+// compatibilityCheck is the compatibility check of a schema type: parse parses a schema of the type, and validate
+// checks two parsed schemas with the type's checker for a level, returning false if the type has none.
+type compatibilityCheck struct {
+	name     string
+	parse    func(schema string) (interface{}, error)
+	validate func(level string, toValidate interface{}, existing interface{}) (bool, error)
+}
 
-	err := checker.Validate(schemaToValidate, existingSchema)
+var avroCompatibility = &compatibilityCheck{
+	name: "Avro",
+	parse: func(schema string) (interface{}, error) {
+		return validation.ParseSchema(schema)
+	},
+	validate: func(level string, toValidate interface{}, existing interface{}) (bool, error) {
+		checker, ok := compatibilityCheckers[level]
+		if !ok {
+			return false, nil
+		}
+		return true, checker.Validate(toValidate.(avro.Schema), existing.(avro.Schema))
+	},
+}
+
+var jsonCompatibility = &compatibilityCheck{
+	name: "JSON",
+	parse: func(schema string) (interface{}, error) {
+		return validation.ParseJSONSchema(schema)
+	},
+	validate: func(level string, toValidate interface{}, existing interface{}) (bool, error) {
+		checker, ok := jsonCompatibilityCheckers[level]
+		if !ok {
+			return false, nil
+		}
+		return true, checker.Validate(toValidate.(*validation.JSONSchema), existing.(*validation.JSONSchema))
+	},
+}
+
+func (cc *compatibilityCheck) compatible(toValidate string, existing string, compatibilityLevel string) (bool, validation.Incompatibilities) {
+	schemaToValidate, err := cc.parse(toValidate)
+	if err != nil {
+		log.Infof("Schema is invalid: %s", err)
+		return false, nil
+	}
+	existingSchema, err := cc.parse(existing)
+	if err != nil {
+		log.Warningf("Registered schema is invalid: %s", err)
+		return false, nil
+	}
+
+	found, err := cc.validate(compatibilityLevel, schemaToValidate, existingSchema)
+	if !found {
+		log.Warningf("Compatibility level %s is not supported for %s schemas", compatibilityLevel, cc.name)
+		return false, nil
+	}
 	if err != nil {
 		log.Infof("Compatibility check for level %s did not pass: %s", compatibilityLevel, err)
-		return false
+		incompatibilities, _ := err.(validation.Incompatibilities)
+		return false, incompatibilities
 	}
 
-	return true
+	return true, nil
 }
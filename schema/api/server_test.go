@@ -0,0 +1,74 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/goavro/wednesday/auth"
+	"github.com/goavro/wednesday/schema/storage"
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestAdminRequiresKey(t *testing.T) {
+	authStorage := auth.Storage
+	defer func() { auth.Storage = authStorage }()
+	auth.Storage = auth.NewAuthInMemoryStorage()
+	adminToken, _ := auth.AddUser("root", true)
+	userToken, _ := auth.AddUser("user", false)
+
+	state := storage.NewInMemoryStorage()
+	as := NewApiServer(":0", &storage.CombinedStorage{StorageWriter: new(storage.MockStorageWriter),
+		StorageStateReader: state, StorageStateWriter: state}, nil, nil, nil, nil, false, "schemas")
+	handler := as.admin(func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	cases := []struct {
+		name  string
+		token string
+		code  int
+	}{
+		{"", "", http.StatusForbidden},
+		{"root", "", http.StatusForbidden},
+		{"root", userToken, http.StatusForbidden},
+		{"user", userToken, http.StatusForbidden},
+		{"nobody", "forged", http.StatusForbidden},
+		{"root", adminToken, http.StatusOK},
+	}
+	for _, c := range cases {
+		r, _ := http.NewRequest("POST", "/admin/fsck?repair=true", nil)
+		r.Header.Set("X-Api-User", c.name)
+		r.Header.Set("X-Api-Key", c.token)
+		w := httptest.NewRecorder()
+		handler(w, r, nil)
+		if w.Code != c.code {
+			t.Errorf("User %q with key %q: expected %d, got %d %s", c.name, c.token, c.code, w.Code, w.Body.String())
+		}
+	}
+}
+
+// runningClusterChecker refuses repairs, as the checker of a registry with Cassandra storage does.
+type runningClusterChecker struct{}
+
+func (runningClusterChecker) Check(repair bool) (*storage.ConsistencyReport, error) {
+	if repair {
+		return nil, storage.ErrRepairWhileRunning
+	}
+	return &storage.ConsistencyReport{Inconsistencies: make([]*storage.Inconsistency, 0)}, nil
+}
+
+func TestFsckRefusesRepairWhileRunning(t *testing.T) {
+	state := storage.NewInMemoryStorage()
+	as := NewApiServer(":0", &storage.CombinedStorage{StorageWriter: new(storage.MockStorageWriter),
+		StorageStateReader: state, StorageStateWriter: state}, nil, runningClusterChecker{}, nil, nil, false, "schemas")
+
+	for method, code := range map[string]int{"GET": http.StatusOK, "POST": http.StatusConflict} {
+		r, _ := http.NewRequest(method, "/admin/fsck?repair=true", nil)
+		w := httptest.NewRecorder()
+		as.Fsck(w, r, nil)
+		if w.Code != code {
+			t.Errorf("%s with repair=true: expected %d, got %d %s", method, code, w.Code, w.Body.String())
+		}
+	}
+}
@@ -2,18 +2,20 @@ package api
 
 import (
 	"encoding/json"
-	"fmt"
 	"log"
 	"net/http"
 	"strconv"
 
+	"github.com/goavro/wednesday/schema/storage"
+	"github.com/goavro/wednesday/schema/validation"
 	"github.com/julienschmidt/httprouter"
 )
 
 type VersionMessage struct {
-	Name    string `json:"name"`
-	Version int    `json:"version"`
-	Schema  string `json:"schema"`
+	Name       string `json:"name"`
+	Version    int    `json:"version"`
+	Schema     string `json:"schema"`
+	SchemaType string `json:"schemaType,omitempty"`
 }
 
 type Schema struct {
@@ -86,10 +88,12 @@ func (as *ApiServer) GetVersion(w http.ResponseWriter, r *http.Request, ps httpr
 		registryError(w, ErrSchemaNotFound, http.StatusNotFound, err)
 		return
 	}
+	schemaType, schema := storage.DecodeSchema(schema)
 	resp := VersionMessage{
-		Name:    subject,
-		Version: version,
-		Schema:  schema,
+		Name:       subject,
+		Version:    version,
+		Schema:     schema,
+		SchemaType: responseType(schemaType),
 	}
 	encoder := json.NewEncoder(w)
 	err = encoder.Encode(resp)
@@ -106,40 +110,109 @@ func (as *ApiServer) NewSchema(w http.ResponseWriter, r *http.Request, ps httpro
 	decoder := json.NewDecoder(r.Body)
 	var req SchemaMessage
 	err := decoder.Decode(&req)
-	if err != nil || !schemaValid(req.Schema) {
+	if err != nil {
 		registryError(w, ErrInvalidSchema, 422, err)
 		return
 	}
-	oldSchema, found, err := as.storage.GetLatestSchema(client, subject)
-	compatibility, found, _ := as.storage.GetSubjectConfig(client, subject)
-	if !found {
-		compatibility, _ = as.storage.GetGlobalConfig(client)
-	}
-	if found && !schemaCompatible(req.Schema, oldSchema.Schema, compatibility) {
-		registryError(w, ErrIncompatibleSchema, http.StatusConflict, err)
+	schemaType, schema, ok := requestSchema(w, &req)
+	if !ok {
 		return
 	}
+	id, warnings, ok := as.registerSchema(w, client, subject, schemaType, schema)
+	if ok {
+		writeID(w, id, warnings)
+	}
+}
+
+// registerSchema runs every check a new schema of a subject goes through and registers it, or writes the error
+// response. It returns the schema id and the warnings of the lint rules. A subject keeps the type of its first schema.
+func (as *ApiServer) registerSchema(w http.ResponseWriter, client string, subject string, schemaType string, schema string) (int64, validation.Violations, bool) {
+	if !parseSchema(w, schemaType, schema) {
+		return 0, nil, false
+	}
+	if as.strictValidation(client) {
+		if errors := schemaTypes[schemaType].Check(schema); len(errors) > 0 {
+			invalidSchemaError(w, errors)
+			return 0, nil, false
+		}
+	}
+	warnings := make(validation.Violations, 0)
+	avro := schemaType == SchemaTypeAvro
+	if ruleSet := as.rules(client, subject); ruleSet != nil && avro {
+		violations, err := validation.Lint(schema, ruleSet)
+		if err != nil {
+			registryError(w, ErrInvalidSchema, 422, err)
+			return 0, nil, false
+		}
+		if errors := violations.Errors(); len(errors) > 0 {
+			ruleViolationError(w, errors)
+			return 0, nil, false
+		}
+		warnings = violations.Warnings()
+	}
+	oldSchema, exists, _ := as.storage.GetLatestSchema(client, subject)
+	if exists {
+		if oldType, _ := storage.DecodeSchema(oldSchema.Schema); oldType != schemaType {
+			registryError(w, ErrSchemaTypeMismatch, http.StatusConflict, nil)
+			return 0, nil, false
+		}
+		// the subject's level or the client's, as POST /compatibility checks; a client without either isn't checked
+		if level := as.compatibilityLevel(client, subject); level != "" {
+			compatible, incompatibilities := typeCompatible(schemaType, schema, oldSchema.Schema, level)
+			if !compatible {
+				incompatibleSchemaError(w, incompatibilities)
+				return 0, nil, false
+			}
+		}
+	}
+	if avro {
+		broken, err := as.brokenReaders(client, subject, schema)
+		if err != nil {
+			registryError(w, ErrInBackendStore, http.StatusInternalServerError, err)
+			return 0, nil, false
+		}
+		if len(broken) > 0 {
+			brokenReadersError(w, broken)
+			return 0, nil, false
+		}
+	}
 
-	id := as.storage.GetID(client, req.Schema)
+	stored := storage.EncodeSchema(schemaType, schema)
+	id := as.storage.GetID(client, stored)
 	if id != -1 {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(fmt.Sprintf(`{"id": %d}`, id)))
-		return
+		return id, warnings, true
+	}
+	if as.admission != nil {
+		request := &AdmissionRequest{Client: client, Subject: subject, Schema: schema, SchemaType: responseType(schemaType)}
+		if exists {
+			request.LatestVersion = oldSchema.Version
+			_, request.LatestSchema = storage.DecodeSchema(oldSchema.Schema)
+		}
+		if response := as.admission.Admit(request); !response.Allowed {
+			admissionDeniedError(w, response.Reason)
+			return 0, nil, false
+		}
 	}
 
-	id, err = as.storage.StoreSchema(client, subject, req.Schema)
+	id, err := as.storage.StoreSchema(client, subject, stored)
 	if err != nil {
 		registryError(w, ErrInBackendStore, http.StatusInternalServerError, err)
-		return
+		return 0, nil, false
 	}
-	err = as.storage.AddSchema(client, subject, id, req.Schema)
+	err = as.storage.AddSchema(client, subject, id, stored)
 	if err != nil {
 		registryError(w, ErrInBackendStore, http.StatusInternalServerError, err)
-		return
+		return 0, nil, false
 	}
+	return id, warnings, true
+}
 
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(fmt.Sprintf(`{"id": %d}`, id)))
+func writeID(w http.ResponseWriter, id int64, warnings validation.Violations) {
+	encoder := json.NewEncoder(w)
+	err := encoder.Encode(&IDMessage{ID: id, Warnings: warnings})
+	if err != nil {
+		registryError(w, ErrEncoding, http.StatusInternalServerError, err)
+	}
 }
 
 func (as *ApiServer) CheckRegistered(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
@@ -154,6 +227,8 @@ func (as *ApiServer) CheckRegistered(w http.ResponseWriter, r *http.Request, ps
 		registryError(w, ErrSchemaNotFound, http.StatusNotFound, err)
 		return
 	}
+	schemaType, stored := storage.DecodeSchema(schema.Schema)
+	schema.Schema, schema.SchemaType = stored, responseType(schemaType)
 	encoder := json.NewEncoder(w)
 	err = encoder.Encode(schema)
 	if err != nil {
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/goavro/wednesday/schema/storage"
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestRegistrationCompatibilityLevel(t *testing.T) {
+	cases := []struct {
+		global  string
+		subject string
+		code    int
+	}{
+		// without a subject level the global one applies
+		{storage.CompatibilityBackward, "", http.StatusConflict},
+		{storage.CompatibilityBackward, storage.CompatibilityNone, http.StatusOK},
+		{storage.CompatibilityNone, storage.CompatibilityBackward, http.StatusConflict},
+		{"", "", http.StatusOK},
+	}
+	for _, c := range cases {
+		state := storage.NewInMemoryStorage()
+		state.AddSchema("snow", "s", 1, `"string"`)
+		if c.global != "" {
+			state.SetGlobalConfig("snow", c.global)
+		}
+		if c.subject != "" {
+			state.SetSubjectConfig("snow", "s", c.subject)
+		}
+		as := NewApiServer(":0", &storage.CombinedStorage{StorageWriter: new(storage.MockStorageWriter),
+			StorageStateReader: state, StorageStateWriter: state}, nil, nil, nil, nil, false, "schemas")
+
+		r, _ := http.NewRequest("POST", "/subjects/s/versions", strings.NewReader(`{"schema": "\"int\""}`))
+		w := httptest.NewRecorder()
+		as.NewSchema(w, r, httprouter.Params{{Key: "client", Value: "snow"}, {Key: "subject", Value: "s"}})
+		if w.Code != c.code {
+			t.Errorf("Global level %q and subject level %q: expected %d, got %d %s", c.global, c.subject, c.code, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/goavro/wednesday/schema/storage"
+	"github.com/goavro/wednesday/schema/validation"
+	"github.com/julienschmidt/httprouter"
+	"github.com/yanzay/log"
+)
+
+// NamedTypeUsage lists the versions of a subject that define a named type.
+type NamedTypeUsage struct {
+	Subject  string `json:"subject"`
+	Versions []int  `json:"versions"`
+}
+
+// ImpactMessage tells whether the latest version of a subject would stay compatible with a new definition of a named type.
+type ImpactMessage struct {
+	Subject           string                       `json:"subject"`
+	Version           int                          `json:"version"`
+	Compatibility     string                       `json:"compatibility"`
+	IsCompatible      bool                         `json:"is_compatible"`
+	Incompatibilities validation.Incompatibilities `json:"incompatibilities,omitempty"`
+}
+
+type typeIndexKey struct {
+	client  string
+	subject string
+}
+
+// subjectTypes maps the named types defined by the versions of a subject, up to version, to those versions.
+type subjectTypes struct {
+	version int
+	names   map[string][]int
+}
+
+// typeIndexCache keeps the named types of every subject. Versions are only ever added to a subject, so an entry
+// is extended with the versions registered after it rather than built again.
+type typeIndexCache struct {
+	lock     sync.Mutex
+	subjects map[typeIndexKey]*subjectTypes
+}
+
+func newTypeIndexCache() *typeIndexCache {
+	return &typeIndexCache{subjects: make(map[typeIndexKey]*subjectTypes)}
+}
+
+func (tc *typeIndexCache) get(key typeIndexKey) (*subjectTypes, bool) {
+	tc.lock.Lock()
+	defer tc.lock.Unlock()
+	types, ok := tc.subjects[key]
+	return types, ok
+}
+
+func (tc *typeIndexCache) put(key typeIndexKey, types *subjectTypes) {
+	tc.lock.Lock()
+	defer tc.lock.Unlock()
+	if cached, ok := tc.subjects[key]; ok && cached.version > types.version {
+		return
+	}
+	tc.subjects[key] = types
+}
+
+// GetNamedType lists every subject and version that defines the named type with the given full name.
+func (as *ApiServer) GetNamedType(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	client := ps.ByName("client")
+	index, err := as.namedTypeIndex(client)
+	if err != nil {
+		registryError(w, ErrInBackendStore, http.StatusInternalServerError, err)
+		return
+	}
+	usages, ok := index[ps.ByName("name")]
+	if !ok {
+		registryError(w, ErrNamedTypeNotFound, http.StatusNotFound, nil)
+		return
+	}
+	encoder := json.NewEncoder(w)
+	err = encoder.Encode(usages)
+	if err != nil {
+		registryError(w, ErrEncoding, http.StatusInternalServerError, err)
+	}
+}
+
+// CheckNamedTypeImpact replaces the named type with the posted definition in the latest version of every subject
+// that defines it and runs the subject's compatibility check against that version.
+func (as *ApiServer) CheckNamedTypeImpact(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	client := ps.ByName("client")
+	name := ps.ByName("name")
+	defer r.Body.Close()
+	var req SchemaMessage
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&req)
+	if err != nil || !schemaValid(req.Schema) {
+		registryError(w, ErrInvalidSchema, 422, err)
+		return
+	}
+	if definedName, err := validation.NamedTypeName(req.Schema); err != nil || definedName != name {
+		registryError(w, ErrNamedTypeMismatch, 422, err)
+		return
+	}
+	index, err := as.namedTypeIndex(client)
+	if err != nil {
+		registryError(w, ErrInBackendStore, http.StatusInternalServerError, err)
+		return
+	}
+
+	impacts := make([]*ImpactMessage, 0)
+	for _, usage := range index[name] {
+		latest, found, err := as.storage.GetLatestSchema(client, usage.Subject)
+		if err != nil {
+			registryError(w, ErrInBackendStore, http.StatusInternalServerError, err)
+			return
+		}
+		if !found {
+			continue
+		}
+		latestType, latestSchema := storage.DecodeSchema(latest.Schema)
+		if latestType != SchemaTypeAvro {
+			continue
+		}
+		replaced, defined, err := validation.ReplaceNamedType(latestSchema, name, req.Schema)
+		if err != nil {
+			log.Warningf("Can't replace %s in subject %s: %s", name, usage.Subject, err)
+			continue
+		}
+		if !defined {
+			// only earlier versions define it
+			continue
+		}
+		compatibility := as.compatibilityLevel(client, usage.Subject)
+		compatible, incompatibilities := schemaCompatible(replaced, latestSchema, compatibility)
+		impacts = append(impacts, &ImpactMessage{
+			Subject:           usage.Subject,
+			Version:           latest.Version,
+			Compatibility:     compatibility,
+			IsCompatible:      compatible,
+			Incompatibilities: incompatibilities,
+		})
+	}
+	encoder := json.NewEncoder(w)
+	err = encoder.Encode(impacts)
+	if err != nil {
+		registryError(w, ErrEncoding, http.StatusInternalServerError, err)
+	}
+}
+
+// namedTypeIndex maps the full name of every named type of the client to the subjects and versions that define it.
+func (as *ApiServer) namedTypeIndex(client string) (map[string][]*NamedTypeUsage, error) {
+	subjects, err := as.storage.GetSubjects(client)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(subjects)
+	index := make(map[string][]*NamedTypeUsage)
+	for _, subject := range subjects {
+		types, err := as.subjectTypes(client, subject)
+		if err != nil {
+			return nil, err
+		}
+		for name, versions := range types.names {
+			index[name] = append(index[name], &NamedTypeUsage{Subject: subject, Versions: versions})
+		}
+	}
+	return index, nil
+}
+
+// subjectTypes returns the named types defined by the versions of a subject, parsing only the versions
+// registered since the subject was last indexed.
+func (as *ApiServer) subjectTypes(client string, subject string) (*subjectTypes, error) {
+	versions, _, err := as.storage.GetVersions(client, subject)
+	if err != nil {
+		return nil, err
+	}
+	sort.Ints(versions)
+	key := typeIndexKey{client: client, subject: subject}
+	cached, ok := as.types.get(key)
+	if !ok {
+		cached = &subjectTypes{names: make(map[string][]int)}
+	}
+	if len(versions) == 0 || versions[len(versions)-1] <= cached.version {
+		return cached, nil
+	}
+
+	// the cached entry may be in use, the new one gets its own copy
+	types := &subjectTypes{version: cached.version, names: make(map[string][]int, len(cached.names))}
+	for name, versions := range cached.names {
+		types.names[name] = append(make([]int, 0, len(versions)+1), versions...)
+	}
+	for _, version := range versions {
+		if version <= types.version {
+			continue
+		}
+		stored, found, err := as.storage.GetSchema(client, subject, version)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			break
+		}
+		types.version = version
+		// only Avro schemas have named types
+		schemaType, schema := storage.DecodeSchema(stored)
+		if schemaType != SchemaTypeAvro {
+			continue
+		}
+		names, err := validation.NamedTypes(schema)
+		if err != nil {
+			continue
+		}
+		for _, name := range names {
+			defined := types.names[name]
+			if len(defined) == 0 || defined[len(defined)-1] != version {
+				types.names[name] = append(defined, version)
+			}
+		}
+	}
+	as.types.put(key, types)
+	return types, nil
+}
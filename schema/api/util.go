@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/goavro/wednesday/schema/validation"
 	"github.com/yanzay/log"
 )
 
@@ -17,13 +18,49 @@ const (
 	ErrInvalidSchema        = "Invalid Avro schema"
 	ErrIncompatibleSchema   = "Incompatible Avro schema"
 	ErrInvalidCompatibility = "Invalid compatibility level"
+	ErrInvalidValidation    = "Invalid validation level"
+	ErrInvalidRules         = "Invalid rule set"
+	ErrRulesNotFound        = "Rule set not found"
+	ErrRuleViolation        = "Schema breaks lint rules"
+	ErrAdmissionDenied      = "Schema admission denied"
+	ErrBrokenReaders        = "Schema can't be read by active consumers"
+	ErrReaderNotFound       = "Reader not found"
+	ErrInvalidTTL           = "Invalid reader TTL"
+	ErrNamedTypeNotFound    = "Named type not found"
+	ErrNamedTypeMismatch    = "Schema is not a definition of the named type"
 	ErrUnauthorized         = "Client authorization required"
 	ErrUserExists           = "User already exists"
+	ErrNotSupported         = "Not supported by this registry configuration"
+	ErrInvalidArchiveFormat = "Invalid archive format"
+	ErrRepairWhileRunning   = "Cassandra can't be repaired while the registry runs, stop every node and run fsck --repair"
+	ErrInvalidMatrixFormat  = "Invalid matrix format"
+	ErrInvalidDatum         = "Data can't be read with the schemas"
+	ErrDatumMismatch        = "Datum does not match the schema"
+	ErrInvalidContainerFile = "Invalid Avro container file"
+	ErrInvalidSchemaType    = "Invalid schema type"
+	ErrSchemaTypeMismatch   = "Schema type differs from the subject's"
+	// ErrSchemaTypeNotSupported is followed by the schema type
+	ErrSchemaTypeNotSupported = "Not supported for schema type"
 )
 
+// Request body limits of the data endpoints. Datums are read into memory, container files into a temporary file.
+const (
+	maxDatumBody     = 8 << 20
+	maxContainerBody = 256 << 20
+)
+
+// ErrorCodeInvalidSchema is the error code Confluent Schema Registry responds with to schemas that don't pass validation.
+const ErrorCodeInvalidSchema = 42201
+
 type ErrorMessage struct {
-	ErrorCode int    `json:"error_code"`
-	Message   string `json:"message"`
+	ErrorCode         int                          `json:"error_code"`
+	Message           string                       `json:"message"`
+	Incompatibilities validation.Incompatibilities `json:"incompatibilities,omitempty"`
+	Errors            validation.SchemaErrors      `json:"errors,omitempty"`
+	Violations        validation.Violations        `json:"violations,omitempty"`
+	Reason            string                       `json:"reason,omitempty"`
+	Consumers         []*BrokenReader              `json:"consumers,omitempty"`
+	DatumErrors       validation.DatumErrors       `json:"datum_errors,omitempty"`
 }
 
 func registryError(w http.ResponseWriter, errorMessage string, code int, err error) {
@@ -31,13 +68,82 @@ func registryError(w http.ResponseWriter, errorMessage string, code int, err err
 		log.Error(err)
 	}
 	log.Warningf("Registry error: %s, %s", errorMessage, err)
-	w.WriteHeader(code)
-	mes := &ErrorMessage{
+	writeError(w, code, &ErrorMessage{
 		ErrorCode: code,
 		Message:   errorMessage,
+	})
+}
+
+// incompatibleSchemaError responds with 409 and every incompatibility the compatibility check found.
+func incompatibleSchemaError(w http.ResponseWriter, incompatibilities validation.Incompatibilities) {
+	log.Warningf("Registry error: %s, %s", ErrIncompatibleSchema, incompatibilities)
+	writeError(w, http.StatusConflict, &ErrorMessage{
+		ErrorCode:         http.StatusConflict,
+		Message:           ErrIncompatibleSchema,
+		Incompatibilities: incompatibilities,
+	})
+}
+
+// invalidSchemaError responds with 422 and every problem strict validation found.
+func invalidSchemaError(w http.ResponseWriter, errors validation.SchemaErrors) {
+	log.Warningf("Registry error: %s, %s", ErrInvalidSchema, errors)
+	writeError(w, 422, &ErrorMessage{
+		ErrorCode: ErrorCodeInvalidSchema,
+		Message:   ErrInvalidSchema,
+		Errors:    errors,
+	})
+}
+
+// ruleViolationError responds with 422 and every violation of an ERROR level lint rule.
+func ruleViolationError(w http.ResponseWriter, violations validation.Violations) {
+	log.Warningf("Registry error: %s, %d violations", ErrRuleViolation, len(violations))
+	writeError(w, 422, &ErrorMessage{
+		ErrorCode:  ErrorCodeInvalidSchema,
+		Message:    ErrRuleViolation,
+		Violations: violations,
+	})
+}
+
+// brokenReadersError responds with 409 and every active consumer the schema would break.
+func brokenReadersError(w http.ResponseWriter, broken []*BrokenReader) {
+	log.Warningf("Registry error: %s, %d consumers", ErrBrokenReaders, len(broken))
+	writeError(w, http.StatusConflict, &ErrorMessage{
+		ErrorCode: http.StatusConflict,
+		Message:   ErrBrokenReaders,
+		Consumers: broken,
+	})
+}
+
+// admissionDeniedError responds with 403 and the reason an admission webhook gave.
+func admissionDeniedError(w http.ResponseWriter, reason string) {
+	log.Warningf("Registry error: %s, %s", ErrAdmissionDenied, reason)
+	writeError(w, http.StatusForbidden, &ErrorMessage{
+		ErrorCode: http.StatusForbidden,
+		Message:   ErrAdmissionDenied,
+		Reason:    reason,
+	})
+}
+
+// invalidDatumError responds with 422 and why the data can't be read, or every field of a datum that doesn't
+// match its schema.
+func invalidDatumError(w http.ResponseWriter, errorMessage string, err error) {
+	log.Warningf("Registry error: %s, %s", errorMessage, err)
+	message := &ErrorMessage{
+		ErrorCode: 422,
+		Message:   errorMessage,
+	}
+	if errs, ok := err.(validation.DatumErrors); ok {
+		message.DatumErrors = errs
+	} else {
+		message.Reason = err.Error()
 	}
+	writeError(w, 422, message)
+}
+
+func writeError(w http.ResponseWriter, code int, mes *ErrorMessage) {
+	w.WriteHeader(code)
 	encoder := json.NewEncoder(w)
-	err = encoder.Encode(mes)
+	err := encoder.Encode(mes)
 	if err != nil {
 		log.Errorf("Can't respond with error: %s\n", err)
 	}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/elodina/go-avro"
+	"github.com/goavro/wednesday/schema/validation"
+	"github.com/julienschmidt/httprouter"
+	"github.com/yanzay/log"
+)
+
+// DatumValidationMessage is the result of validating a datum. Line is the line of the datum in a batch.
+type DatumValidationMessage struct {
+	Line   int                    `json:"line,omitempty"`
+	Valid  bool                   `json:"valid"`
+	Errors validation.DatumErrors `json:"errors,omitempty"`
+}
+
+// ValidateVersionDatum validates Avro JSON encoded datums against a version of a subject.
+func (as *ApiServer) ValidateVersionDatum(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	_, schema, ok := as.versionSchema(w, ps.ByName("client"), ps.ByName("subject"), ps.ByName("version"))
+	if !ok {
+		return
+	}
+	as.validateDatums(w, r, schema)
+}
+
+// ValidateIDDatum validates Avro JSON encoded datums against the schema with the given id.
+func (as *ApiServer) ValidateIDDatum(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	id, err := strconv.ParseInt(ps.ByName("id"), 10, 64)
+	if err != nil {
+		registryError(w, ErrDecoding, http.StatusBadRequest, err)
+		return
+	}
+	stored, found, err := as.storage.GetSchemaByID(ps.ByName("client"), id)
+	if err != nil {
+		registryError(w, ErrInBackendStore, http.StatusInternalServerError, err)
+		return
+	}
+	if !found {
+		registryError(w, ErrSchemaNotFound, http.StatusNotFound, nil)
+		return
+	}
+	schema, ok := avroOnly(w, stored)
+	if !ok {
+		return
+	}
+	as.validateDatums(w, r, schema)
+}
+
+// validateDatums validates the request body as a single datum, or with the application/x-ndjson content type
+// as a datum per line, responding with a result per line as NDJSON.
+func (as *ApiServer) validateDatums(w http.ResponseWriter, r *http.Request, rawSchema string) {
+	defer r.Body.Close()
+	schema, err := validation.ParseSchema(rawSchema)
+	if err != nil {
+		registryError(w, ErrInvalidSchema, 422, err)
+		return
+	}
+
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if contentType != "application/x-ndjson" {
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			registryError(w, ErrDecoding, http.StatusBadRequest, err)
+			return
+		}
+		encoder := json.NewEncoder(w)
+		err = encoder.Encode(validateDatum(schema, data, 0))
+		if err != nil {
+			registryError(w, ErrEncoding, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	encoder := json.NewEncoder(w)
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		err = encoder.Encode(validateDatum(schema, scanner.Bytes(), line))
+		if err != nil {
+			log.Errorf("Can't write datum validation: %s", err)
+			return
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		encoder.Encode(&DatumValidationMessage{Errors: validation.DatumErrors{{Path: "/", Message: err.Error()}}})
+	}
+}
+
+func validateDatum(schema avro.Schema, data []byte, line int) *DatumValidationMessage {
+	errors := validation.ValidateDatum(schema, data)
+	return &DatumValidationMessage{Line: line, Valid: len(errors) == 0, Errors: errors}
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/goavro/wednesday/schema/validation"
+	"github.com/julienschmidt/httprouter"
+)
+
+// WireMessage is a message in the Confluent wire format, as hex or base64.
+type WireMessage struct {
+	ID     int64  `json:"id,omitempty"`
+	Hex    string `json:"hex,omitempty"`
+	Base64 string `json:"base64,omitempty"`
+}
+
+// DecodedMessage is a wire format message decoded with the schema it was written with.
+// Messages are read and written with validation.ReadDatum and WriteDatum rather than the generic datum reader and
+// writer of go-avro: those drop the union branch the Avro JSON encoding names, pick a branch by Go type when writing,
+// and allocate whatever block count the data claims.
+type DecodedMessage struct {
+	ID     int64       `json:"id"`
+	Schema string      `json:"schema"`
+	Datum  interface{} `json:"datum"`
+}
+
+// DecodeWireFormat looks up the schema of a hex or base64 encoded wire format message and decodes it with it.
+func (as *ApiServer) DecodeWireFormat(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	client := ps.ByName("client")
+	defer r.Body.Close()
+	var message WireMessage
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxDatumBody))
+	err := decoder.Decode(&message)
+	if err != nil {
+		registryError(w, ErrDecoding, http.StatusBadRequest, err)
+		return
+	}
+	data, err := message.bytes()
+	if err != nil {
+		registryError(w, ErrDecoding, http.StatusBadRequest, err)
+		return
+	}
+	id, data, err := validation.SplitWireFormat(data)
+	if err != nil {
+		invalidDatumError(w, ErrInvalidDatum, err)
+		return
+	}
+
+	stored, found, err := as.storage.GetSchemaByID(client, id)
+	if err != nil {
+		registryError(w, ErrInBackendStore, http.StatusInternalServerError, err)
+		return
+	}
+	if !found {
+		registryError(w, ErrSchemaNotFound, http.StatusNotFound, nil)
+		return
+	}
+	rawSchema, ok := avroOnly(w, stored)
+	if !ok {
+		return
+	}
+	schema, err := validation.ParseSchema(rawSchema)
+	if err != nil {
+		registryError(w, ErrInvalidSchema, 422, err)
+		return
+	}
+	datum, err := validation.ReadDatum(schema, schema, data)
+	if err != nil {
+		invalidDatumError(w, ErrInvalidDatum, err)
+		return
+	}
+	encoder := json.NewEncoder(w)
+	err = encoder.Encode(DecodedMessage{ID: id, Schema: rawSchema, Datum: datum})
+	if err != nil {
+		registryError(w, ErrEncoding, http.StatusInternalServerError, err)
+	}
+}
+
+// EncodeWireFormat encodes an Avro JSON encoded datum with a version of a subject as a wire format message.
+func (as *ApiServer) EncodeWireFormat(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	client := ps.ByName("client")
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		registryError(w, ErrDecoding, http.StatusBadRequest, err)
+		return
+	}
+	_, rawSchema, ok := as.versionSchema(w, client, ps.ByName("subject"), ps.ByName("version"))
+	if !ok {
+		return
+	}
+	schema, err := validation.ParseSchema(rawSchema)
+	if err != nil {
+		registryError(w, ErrInvalidSchema, 422, err)
+		return
+	}
+	data, errs := validation.WriteDatum(schema, body)
+	if len(errs) > 0 {
+		invalidDatumError(w, ErrDatumMismatch, errs)
+		return
+	}
+
+	id := as.storage.GetID(client, rawSchema)
+	message := validation.WireFormat(id, data)
+	resp := WireMessage{ID: id, Hex: hex.EncodeToString(message), Base64: base64.StdEncoding.EncodeToString(message)}
+	encoder := json.NewEncoder(w)
+	err = encoder.Encode(resp)
+	if err != nil {
+		registryError(w, ErrEncoding, http.StatusInternalServerError, err)
+	}
+}
+
+// bytes decodes the message, which must be given either as hex or as base64. Whitespace in hex is ignored.
+func (wm *WireMessage) bytes() ([]byte, error) {
+	switch {
+	case wm.Hex != "" && wm.Base64 == "":
+		return hex.DecodeString(strings.Join(strings.Fields(wm.Hex), ""))
+	case wm.Base64 != "" && wm.Hex == "":
+		return base64.StdEncoding.DecodeString(wm.Base64)
+	}
+	return nil, errors.New("Either hex or base64 is required")
+}
@@ -3,6 +3,7 @@ package schema
 import (
 	"fmt"
 	"os"
+	"time"
 
 	producer "github.com/elodina/siesta-producer"
 	"github.com/goavro/wednesday/auth"
@@ -32,6 +33,12 @@ type SchemaRegistryConfig struct {
 	Cassandra    string
 	ProtoVersion int
 	CQLVersion   string
+	// AdmissionWebhooks are asked to admit every new schema, AdmissionFailOpen admits schemas when a webhook fails
+	AdmissionWebhooks []string
+	AdmissionTimeout  time.Duration
+	AdmissionFailOpen bool
+	// ImportSkipRecords lets imports pad the log with skip records, set it once every node applies them
+	ImportSkipRecords bool
 }
 
 func DefaultRegistryConfig() SchemaRegistryConfig {
@@ -46,6 +53,10 @@ func DefaultRegistryConfig() SchemaRegistryConfig {
 		Cassandra:    "",
 		ProtoVersion: 3,
 		CQLVersion:   "3.0.0",
+
+		AdmissionWebhooks: []string{},
+		AdmissionTimeout:  5 * time.Second,
+		AdmissionFailOpen: false,
 	}
 }
 
@@ -55,23 +66,37 @@ func (*MockWatcher) Watch(topic string) {
 
 }
 
+// Watchers passes every watched topic to all of its watchers.
+type Watchers []api.Watcher
+
+func (ws Watchers) Watch(topic string) {
+	for _, watcher := range ws {
+		watcher.Watch(topic)
+	}
+}
+
 func NewApp(config SchemaRegistryConfig) *App {
 	auth.InitStorage(config.VaultURL, os.Getenv("VAULT_TOKEN"))
 
 	inmemStorage := storage.NewInMemoryStorage()
 
+	gate := NewLogGate()
 	var consumer api.Watcher
 	var kafkaStorage storage.StorageWriter
+	var logProducer storage.Sender
 	if len(config.Brokers) > 0 {
-		producer := createProducer(config.Brokers)
+		producer := NewProducer(config.Brokers)
+		logProducer = producer
 		kafkaStorage = storage.NewKafkaStorage(producer)
-		consumer = NewConsumer(config.Brokers, inmemStorage, config.Multiuser)
+		consumer = NewConsumer(config.Brokers, inmemStorage, gate, config.Multiuser)
 	} else {
 		kafkaStorage = &storage.MockStorageWriter{}
 		consumer = &MockWatcher{}
 	}
 
 	var store storage.Storage
+	var cassandraStorage *storage.CassandraStorage
+	var follower *storage.LogFollower
 
 	if config.Cassandra == "" {
 		store = &storage.CombinedStorage{
@@ -80,18 +105,41 @@ func NewApp(config SchemaRegistryConfig) *App {
 			StorageStateWriter: inmemStorage,
 		}
 	} else {
-		cassandraStorage := storage.NewCassandraStorage(config.Cassandra, config.ProtoVersion, config.CQLVersion)
+		if len(config.Brokers) == 0 {
+			log.Fatal("Cassandra storage is populated from the Kafka log, please set brokers")
+		}
+		cassandraStorage = storage.NewCassandraStorage(config.Cassandra, config.ProtoVersion, config.CQLVersion)
+		follower = storage.NewLogFollower(cassandraStorage, cassandraStorage)
+		consumer = Watchers{consumer, NewFollower(config.Brokers, follower, gate, config.Multiuser)}
 		store = &storage.CachedStorage{
-			StorageWriter:      storage.NewStorageMultiwriter(kafkaStorage, cassandraStorage),
+			StorageWriter:      storage.NewStorageMultiwriter(kafkaStorage, follower),
 			StorageStateWriter: inmemStorage,
 			Cache:              inmemStorage,
 			Backend:            cassandraStorage,
 		}
 	}
 
+	var checker api.ConsistencyChecker
+	if len(config.Brokers) > 0 {
+		checker = NewConsistencyChecker(config, inmemStorage, cassandraStorage, follower, gate)
+	}
+	// Cassandra has every tenant, the in-memory state only the ones watched so far
+	var archiver *Archiver
+	if cassandraStorage != nil {
+		archiver = NewArchiver(config, cassandraStorage, logProducer)
+	} else {
+		archiver = NewArchiver(config, inmemStorage, logProducer)
+	}
+
+	var admission api.Admission
+	if len(config.AdmissionWebhooks) > 0 {
+		admission = NewWebhookAdmission(config.AdmissionWebhooks, config.AdmissionTimeout, config.AdmissionFailOpen)
+	}
+
 	return &App{
-		store:     store,
-		server:    api.NewApiServer(fmt.Sprintf(":%d", config.Port), store, consumer, config.Multiuser, config.Topic),
+		store: store,
+		server: api.NewApiServer(fmt.Sprintf(":%d", config.Port), store, consumer, checker, archiver, admission,
+			config.Multiuser, config.Topic),
 		registrar: config.Registrar,
 		host:      config.Host,
 		port:      config.Port,
@@ -0,0 +1,250 @@
+package schema
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/goavro/wednesday/schema/storage"
+	"github.com/goavro/wednesday/schema/validation"
+	"github.com/yanzay/log"
+)
+
+// Archiver exports the registry state to archives and imports archives back through the schema log.
+type Archiver struct {
+	brokers   []string
+	topic     string
+	multiuser bool
+	state     storage.Dumper
+	producer  storage.Sender
+	// skipRecords lets imports pad the log with skip records
+	skipRecords bool
+}
+
+// NewArchiver creates an archiver reading the registry state from the given store,
+// or from the schema log if state is nil. Without a producer the archiver can't import.
+func NewArchiver(config SchemaRegistryConfig, state storage.Dumper, producer storage.Sender) *Archiver {
+	return &Archiver{
+		brokers:     config.Brokers,
+		topic:       config.Topic,
+		multiuser:   config.Multiuser,
+		skipRecords: config.ImportSkipRecords,
+		state:       state,
+		producer:    producer,
+	}
+}
+
+// Export writes every client's schemas and configs to an archive in the given format, reading one client
+// at a time from the store. Users are left out.
+func (a *Archiver) Export(w io.Writer, format string) error {
+	writer, err := storage.NewArchiveWriter(w, format)
+	if err != nil {
+		return err
+	}
+	state := a.state
+	if state == nil {
+		state, err = a.replay()
+		if err != nil {
+			return err
+		}
+	}
+	clients, err := state.DumpClients()
+	if err != nil {
+		return err
+	}
+	for _, client := range clients {
+		clientState, err := state.DumpClient(client)
+		if err != nil {
+			return err
+		}
+		err = writer.WriteClient(client, clientState)
+		if err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// Import writes every schema and config of the archive the registry doesn't have yet to the schema log,
+// keeping schema ids and versions. Nothing is written if there is any conflict or dryRun is set. Schemas that need
+// skip records to keep their ids conflict unless the registry is configured to import with skip records.
+// In single user mode the archive must hold exactly one client, which is imported into the registry topic.
+func (a *Archiver) Import(archive *storage.Snapshot, dryRun bool) (*storage.ImportPlan, error) {
+	if a.producer == nil || len(a.brokers) == 0 {
+		return nil, storage.ErrNoLog
+	}
+	archive, err := a.clients(archive)
+	if err != nil {
+		return nil, err
+	}
+	current, err := a.current()
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := NewLogReader(a.brokers)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	ends := make(map[string]int64)
+	for client := range archive.Clients {
+		ends[client], err = reader.EndOffset(client)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// a failed import can't be rolled back, so everything that can be checked is checked before the first write
+	plan := storage.PlanImport(current, archive, ends)
+	if !a.skipRecords {
+		plan.ForbidSkipRecords(ends)
+	}
+	checkRecords(plan)
+	if dryRun || len(plan.Conflicts) > 0 {
+		return plan, nil
+	}
+	for client, end := range ends {
+		latestEnd, err := reader.EndOffset(client)
+		if err != nil {
+			return nil, err
+		}
+		if latestEnd != end {
+			return nil, fmt.Errorf("The log of %s was written to while planning the import, nothing was imported", client)
+		}
+	}
+	for _, record := range plan.Schemas {
+		err = a.writeSchema(record, ends)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, record := range plan.Configs {
+		_, err = storage.SendMessage(a.producer, record.Type, record.Content())
+		if err != nil {
+			return nil, err
+		}
+	}
+	log.Infof("[Archiver] Imported %d schemas and %d configs", len(plan.Schemas), len(plan.Configs))
+	plan.Imported = true
+	return plan, nil
+}
+
+// checkRecords reports a conflict for every record of the plan the API wouldn't have accepted: a schema that
+// doesn't parse, an unknown compatibility level or a setting with an invalid value.
+func checkRecords(plan *storage.ImportPlan) {
+	invalid := func(record *storage.ArchiveRecord, format string, args ...interface{}) {
+		plan.Conflicts = append(plan.Conflicts, &storage.Inconsistency{Store: "import", Kind: storage.ConflictInvalid,
+			Client: record.Client, Subject: record.Subject, Version: record.Version, ID: record.ID, Message: fmt.Sprintf(format, args...)})
+	}
+	for _, record := range plan.Schemas {
+		if err := parseStoredSchema(record.Schema); err != nil {
+			invalid(record, "Schema id %d is invalid: %s", record.ID, err)
+		}
+	}
+	for _, record := range plan.Configs {
+		switch record.Type {
+		case storage.MessageGlobalConfig, storage.MessageSubjectConfig:
+			switch record.Compatibility {
+			case storage.CompatibilityNone, storage.CompatibilityFull, storage.CompatibilityForward,
+				storage.CompatibilityBackward, storage.CompatibilityKeyStable:
+			default:
+				invalid(record, "Compatibility level %q is unknown", record.Compatibility)
+			}
+		case storage.MessageSetting:
+			if err := checkSetting(record.Name, record.Value); err != nil {
+				invalid(record, "Setting %s is invalid: %s", record.Name, err)
+			}
+		}
+	}
+}
+
+func parseStoredSchema(stored string) error {
+	schemaType, schema := storage.DecodeSchema(stored)
+	var err error
+	switch schemaType {
+	case storage.SchemaTypeAvro:
+		_, err = validation.ParseSchema(schema)
+	case storage.SchemaTypeJSON:
+		_, err = validation.ParseJSONSchema(schema)
+	default:
+		err = fmt.Errorf("unknown schema type %s", schemaType)
+	}
+	return err
+}
+
+func checkSetting(name string, value string) error {
+	var err error
+	switch {
+	case name == storage.SettingValidation:
+		if value != storage.ValidationStrict && value != storage.ValidationLenient {
+			err = fmt.Errorf("unknown validation %q", value)
+		}
+	case name == storage.SettingRules:
+		_, err = validation.ParseRuleSet(value)
+	case strings.HasPrefix(name, storage.ReaderSetting("")):
+		_, err = storage.ParseReader(value)
+	default:
+		err = fmt.Errorf("unknown setting")
+	}
+	return err
+}
+
+// writeSchema pads the client topic with skip records up to the offset the schema id corresponds to.
+func (a *Archiver) writeSchema(record *storage.ArchiveRecord, ends map[string]int64) error {
+	for ends[record.Client] < record.ID-1 {
+		metadata, err := storage.SendMessage(a.producer, storage.MessageSkip, map[string]string{"client": record.Client})
+		if err != nil {
+			return err
+		}
+		ends[record.Client] = metadata.Offset + 1
+	}
+	metadata, err := storage.SendMessage(a.producer, record.Type, record.Content())
+	if err != nil {
+		return err
+	}
+	ends[record.Client] = metadata.Offset + 1
+	if metadata.Offset+1 != record.ID {
+		return fmt.Errorf("Schema %d of subject %s was written with id %d, the log was written to during import",
+			record.ID, record.Subject, metadata.Offset+1)
+	}
+	return nil
+}
+
+func (a *Archiver) clients(archive *storage.Snapshot) (*storage.Snapshot, error) {
+	if a.multiuser {
+		return archive, nil
+	}
+	if len(archive.Clients) > 1 {
+		return nil, fmt.Errorf("Archive has %d clients, a single user registry can import only one", len(archive.Clients))
+	}
+	renamed := storage.NewSnapshot()
+	for _, state := range archive.Clients {
+		renamed.Clients[a.topic] = state
+	}
+	return renamed, nil
+}
+
+func (a *Archiver) current() (*storage.Snapshot, error) {
+	if a.state != nil {
+		return a.state.Dump()
+	}
+	replayed, err := a.replay()
+	if err != nil {
+		return nil, err
+	}
+	return replayed.Dump()
+}
+
+// replay reads the registry state from the schema log, for archivers without a store.
+func (a *Archiver) replay() (*storage.InMemoryStorage, error) {
+	if len(a.brokers) == 0 {
+		return nil, fmt.Errorf("Reading the schema log requires Kafka brokers")
+	}
+	replayed := storage.NewInMemoryStorage()
+	_, err := replayLog(a.brokers, a.topic, a.multiuser, replayed)
+	if err != nil {
+		return nil, err
+	}
+	return replayed, nil
+}
@@ -0,0 +1,39 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/goavro/wednesday/schema/storage"
+)
+
+func TestCheckRecords(t *testing.T) {
+	plan := &storage.ImportPlan{
+		Schemas: []*storage.ArchiveRecord{
+			{Type: storage.MessageSchema, Client: "snow", Subject: "s", Version: 1, ID: 1, Schema: `{"type": "string"}`},
+			{Type: storage.MessageSchema, Client: "snow", Subject: "s", Version: 2, ID: 2, Schema: `{"type": "strin"}`},
+			{Type: storage.MessageSchema, Client: "snow", Subject: "j", Version: 1, ID: 3,
+				Schema: storage.EncodeSchema(storage.SchemaTypeJSON, `{"type": "object"}`)},
+		},
+		Configs: []*storage.ArchiveRecord{
+			{Type: storage.MessageGlobalConfig, Client: "snow", Compatibility: storage.CompatibilityFull},
+			{Type: storage.MessageSubjectConfig, Client: "snow", Subject: "s", Compatibility: "SIDEWAYS"},
+			{Type: storage.MessageSetting, Client: "snow", Name: storage.SettingValidation, Value: storage.ValidationLenient},
+			{Type: storage.MessageSetting, Client: "snow", Subject: "s", Name: storage.SettingRules, Value: `{"rules": [{"name": "unknown"}]}`},
+			{Type: storage.MessageSetting, Client: "snow", Subject: "s", Name: storage.ReaderSetting("billing"), Value: ""},
+			{Type: storage.MessageSetting, Client: "snow", Name: "unknown", Value: "on"},
+		},
+	}
+	checkRecords(plan)
+
+	if len(plan.Conflicts) != 4 {
+		t.Fatalf("Expected 4 invalid records, got %v", plan.Conflicts)
+	}
+	for _, conflict := range plan.Conflicts {
+		if conflict.Kind != storage.ConflictInvalid {
+			t.Errorf("Expected %s, got %v", storage.ConflictInvalid, conflict)
+		}
+	}
+	if plan.Conflicts[0].ID != 2 || plan.Conflicts[1].Subject != "s" {
+		t.Errorf("Expected schema id 2 and the config of subject s to be invalid first, got %v", plan.Conflicts)
+	}
+}
@@ -12,10 +12,11 @@ import (
 type Consumer struct {
 	consumer gonsumer.Consumer
 	storage  storage.StorageStateWriter
+	gate     *LogGate
 }
 
-func NewConsumer(brokerList []string, store storage.StorageStateWriter, multiuser bool) *Consumer {
-	c := &Consumer{storage: store}
+func NewConsumer(brokerList []string, store storage.StorageStateWriter, gate *LogGate, multiuser bool) *Consumer {
+	c := &Consumer{storage: store, gate: gate}
 	config := client.NewConfig()
 	config.FetchMinBytes = 1
 	config.BrokerList = brokerList
@@ -47,28 +48,30 @@ func (c *Consumer) consumerStrategy(data *gonsumer.FetchData, _ *gonsumer.KafkaP
 		log.Errorf("[Consumer] Fetch error: %s\n", data.Error)
 	}
 
+	c.gate.Enter()
+	defer c.gate.Leave()
 	for _, msg := range data.Messages {
-		var err error
-		log.Info(string(msg.Value))
-		content := messageContent(msg.Value)
-		switch storage.MessageType(msg.Key) {
-		case storage.MessageSchema:
-			err = c.storage.AddSchema(content["client"], content["subject"], msg.Offset+1, content["schema"])
-		case storage.MessageGlobalConfig:
-			err = c.storage.SetGlobalConfig(content["client"], content["compatibility"])
-		case storage.MessageSubjectConfig:
-			err = c.storage.SetSubjectConfig(content["client"], content["subject"], content["compatibility"])
-		case storage.MessageCreateUser:
-			err = c.storage.AddUser(content["name"], content["token"], content["admin"] == "true")
-		default:
-			log.Error("[Consumer] Unexpected message type")
+		if c.gate.Rewritten(msg.Topic, msg.Offset) {
+			continue
 		}
+		log.Info(string(msg.Value))
+		err := storage.ApplyRecord(c.storage, logRecord(msg))
 		if err != nil {
 			log.Errorf("[Consumer] %s", err)
 		}
 	}
 }
 
+func logRecord(msg *gonsumer.MessageAndMetadata) *storage.LogRecord {
+	return &storage.LogRecord{
+		Topic:     msg.Topic,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Type:      storage.MessageType(msg.Key),
+		Content:   messageContent(msg.Value),
+	}
+}
+
 func messageContent(message []byte) map[string]string {
 	var content map[string]string
 	err := json.Unmarshal(message, &content)
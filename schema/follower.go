@@ -0,0 +1,70 @@
+package schema
+
+import (
+	"github.com/goavro/wednesday/schema/storage"
+	"github.com/serejja/gonsumer"
+	client "github.com/serejja/kafka-client"
+	"github.com/yanzay/log"
+)
+
+// Follower feeds the schema log into a storage.LogFollower.
+// It consumes with its own group, so a stalled derived store never holds back the in-memory state.
+type Follower struct {
+	consumer gonsumer.Consumer
+	follower *storage.LogFollower
+	gate     *LogGate
+}
+
+func NewFollower(brokerList []string, follower *storage.LogFollower, gate *LogGate, multiuser bool) *Follower {
+	f := &Follower{follower: follower, gate: gate}
+	config := client.NewConfig()
+	config.FetchMinBytes = 1
+	config.BrokerList = brokerList
+	client, err := client.New(config)
+	if err != nil {
+		panic(err)
+	}
+	consumerConfig := gonsumer.NewConfig()
+	consumerConfig.Group = "wednesday-follower"
+	consumerConfig.AutoCommitEnable = false
+	f.consumer = gonsumer.New(client, consumerConfig, f.followerStrategy)
+	if multiuser {
+		f.consumer.Add("admin", 0)
+	}
+	return f
+}
+
+func (f *Follower) Watch(topic string) {
+	f.consumer.Add(topic, 0)
+}
+
+func (f *Follower) followerStrategy(data *gonsumer.FetchData, consumer *gonsumer.KafkaPartitionConsumer) {
+	if data.Error != nil {
+		log.Errorf("[Follower] Fetch error: %s\n", data.Error)
+	}
+
+	f.gate.Enter()
+	defer f.gate.Leave()
+	applied := int64(-1)
+	for _, msg := range data.Messages {
+		if f.gate.Rewritten(msg.Topic, msg.Offset) {
+			continue
+		}
+		err := f.follower.Apply(logRecord(msg))
+		if err != nil {
+			// the store is unreachable, rewind so this record and everything after it is fetched and applied again
+			log.Errorf("[Follower] Can't apply %s/%d at offset %d: %s", msg.Topic, msg.Partition, msg.Offset, err)
+			consumer.SetOffset(msg.Offset)
+			break
+		}
+		applied = msg.Offset
+	}
+
+	// the checkpoint in the derived store is authoritative, the group offset only saves a replay on restart
+	if applied >= 0 {
+		err := consumer.Commit(applied)
+		if err != nil {
+			log.Warningf("[Follower] Can't commit offset %d: %s", applied, err)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package schema
+
+import (
+	"fmt"
+
+	"github.com/goavro/wednesday/schema/storage"
+	"github.com/yanzay/log"
+)
+
+// ConsistencyChecker compares the state replayed from the schema log with the stores derived from it.
+// The live in-memory state keeps moving while the log is replayed, so a record written during the check
+// may show up as an inconsistency; running the check again tells drift from a lagging consumer.
+type ConsistencyChecker struct {
+	brokers   []string
+	topic     string
+	multiuser bool
+	memory    *storage.InMemoryStorage
+	cassandra *storage.CassandraStorage
+	// follower applies the log to cassandra and gate holds back the consumers during a repair,
+	// both are nil if nothing in this process consumes the log
+	follower *storage.LogFollower
+	gate     *LogGate
+}
+
+// NewConsistencyChecker creates a checker for the given stores, any of which may be nil.
+func NewConsistencyChecker(config SchemaRegistryConfig, memory *storage.InMemoryStorage, cassandra *storage.CassandraStorage,
+	follower *storage.LogFollower, gate *LogGate) *ConsistencyChecker {
+	return &ConsistencyChecker{
+		brokers:   config.Brokers,
+		topic:     config.Topic,
+		multiuser: config.Multiuser,
+		memory:    memory,
+		cassandra: cassandra,
+		follower:  follower,
+		gate:      gate,
+	}
+}
+
+// Check reports every difference between the log and the derived stores.
+// With repair set, every store that differs is rewritten from the log. The consumers of the log are paused
+// from before the replay until the stores are rewritten and then go on after the last replayed record,
+// so nothing they would apply in between is overwritten or applied twice.
+// Cassandra is truncated and reloaded by a repair, which is only safe with every node of the cluster stopped,
+// so a checker running inside a registry (one with a follower) refuses to repair it.
+func (cc *ConsistencyChecker) Check(repair bool) (*storage.ConsistencyReport, error) {
+	if len(cc.brokers) == 0 {
+		return nil, fmt.Errorf("Consistency check requires Kafka brokers")
+	}
+	if repair && cc.cassandra != nil && cc.follower != nil {
+		return nil, storage.ErrRepairWhileRunning
+	}
+	var rewritten map[string]int64
+	if repair && cc.gate != nil {
+		cc.gate.Pause()
+		defer func() { cc.gate.Resume(rewritten) }()
+	}
+	replayed := storage.NewInMemoryStorage()
+	offsets, err := replayLog(cc.brokers, cc.topic, cc.multiuser, replayed)
+	if err != nil {
+		return nil, err
+	}
+	expected, err := replayed.Dump()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &storage.ConsistencyReport{Inconsistencies: storage.CheckSnapshot("log", expected)}
+	if cc.memory != nil {
+		inconsistencies, err := cc.compare("memory", expected, cc.memory)
+		if err != nil {
+			return nil, err
+		}
+		report.Inconsistencies = append(report.Inconsistencies, inconsistencies...)
+		if repair && len(inconsistencies) > 0 {
+			log.Warning("[ConsistencyChecker] Rewriting in-memory state from the log")
+			err = cc.memory.Load(expected)
+			if err != nil {
+				return nil, err
+			}
+			report.Repaired = true
+		}
+	}
+	if cc.cassandra != nil {
+		inconsistencies, err := cc.compare("cassandra", expected, cc.cassandra)
+		if err != nil {
+			return nil, err
+		}
+		// skipped records are kept along with the checkpoints, fsck run from the command line finds them too
+		follower := cc.follower
+		if follower == nil {
+			follower = storage.NewLogFollower(cc.cassandra, cc.cassandra)
+		}
+		skipped, err := follower.SkippedRecords("cassandra")
+		if err != nil {
+			return nil, err
+		}
+		inconsistencies = append(inconsistencies, skipped...)
+		report.Inconsistencies = append(report.Inconsistencies, inconsistencies...)
+		if repair && len(inconsistencies) > 0 {
+			log.Warning("[ConsistencyChecker] Rewriting Cassandra from the log")
+			err = cc.cassandra.Load(expected)
+			if err != nil {
+				return nil, err
+			}
+			// checkpoints the rewritten offsets and forgets the records skipped before them
+			for topic, offset := range offsets {
+				err = follower.Reset(topic, 0, offset)
+				if err != nil {
+					return nil, err
+				}
+			}
+			report.Repaired = true
+		}
+	}
+	// the stores match the log up to these offsets, whether they were rewritten or not
+	rewritten = offsets
+	return report, nil
+}
+
+func (cc *ConsistencyChecker) compare(store string, expected *storage.Snapshot, dumper storage.Dumper) ([]*storage.Inconsistency, error) {
+	actual, err := dumper.Dump()
+	if err != nil {
+		return nil, err
+	}
+	return storage.CompareSnapshots(store, expected, actual), nil
+}
@@ -0,0 +1,18 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/goavro/wednesday/schema/storage"
+)
+
+func TestCheckRefusesCassandraRepairWhileRunning(t *testing.T) {
+	cassandra := &storage.CassandraStorage{}
+	follower := storage.NewLogFollower(cassandra, cassandra)
+	checker := NewConsistencyChecker(SchemaRegistryConfig{Brokers: []string{"localhost:9092"}}, storage.NewInMemoryStorage(),
+		cassandra, follower, NewLogGate())
+
+	if _, err := checker.Check(true); err != storage.ErrRepairWhileRunning {
+		t.Errorf("Expected a running node to refuse rewriting Cassandra, got %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package schema
+
+import (
+	"sync"
+)
+
+// LogGate holds back the consumers of the schema log while the stores they apply records to are rewritten.
+// A consumer applies every batch between Enter and Leave and drops the records the rewritten stores already have.
+type LogGate struct {
+	lock *sync.RWMutex
+
+	mutex     *sync.Mutex
+	rewritten map[string]int64
+}
+
+func NewLogGate() *LogGate {
+	return &LogGate{
+		lock:      &sync.RWMutex{},
+		mutex:     &sync.Mutex{},
+		rewritten: make(map[string]int64),
+	}
+}
+
+// Enter blocks while the gate is paused.
+func (g *LogGate) Enter() {
+	g.lock.RLock()
+}
+
+func (g *LogGate) Leave() {
+	g.lock.RUnlock()
+}
+
+// Pause waits for the batches being applied and holds back the next ones until Resume.
+func (g *LogGate) Pause() {
+	g.lock.Lock()
+}
+
+// Resume lets the consumers go on after the stores were rewritten up to the given offset of each topic.
+func (g *LogGate) Resume(offsets map[string]int64) {
+	g.mutex.Lock()
+	for topic, offset := range offsets {
+		if offset > g.rewritten[topic] {
+			g.rewritten[topic] = offset
+		}
+	}
+	g.mutex.Unlock()
+	g.lock.Unlock()
+}
+
+// Rewritten tells if the record at the given offset is already in the rewritten stores,
+// a batch fetched before the pause may hold such records.
+func (g *LogGate) Rewritten(topic string, offset int64) bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	rewritten, ok := g.rewritten[topic]
+	return ok && offset <= rewritten
+}
@@ -0,0 +1,48 @@
+package schema
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLogGatePause(t *testing.T) {
+	gate := NewLogGate()
+	gate.Pause()
+
+	entered := make(chan struct{})
+	go func() {
+		gate.Enter()
+		defer gate.Leave()
+		close(entered)
+	}()
+	select {
+	case <-entered:
+		t.Fatal("Consumer entered a paused gate")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	gate.Resume(map[string]int64{"schemas": 4, "empty": -1})
+	select {
+	case <-entered:
+	case <-time.After(time.Second):
+		t.Fatal("Consumer is still held back after resume")
+	}
+
+	if !gate.Rewritten("schemas", 4) || gate.Rewritten("schemas", 5) {
+		t.Error("Records up to offset 4 should be rewritten and only those")
+	}
+	if gate.Rewritten("empty", 0) || gate.Rewritten("other", 0) {
+		t.Error("No records of empty or unknown topics should be rewritten")
+	}
+}
+
+func TestLogGateResumeKeepsHighestOffset(t *testing.T) {
+	gate := NewLogGate()
+	gate.Pause()
+	gate.Resume(map[string]int64{"schemas": 7})
+	gate.Pause()
+	gate.Resume(nil)
+	if !gate.Rewritten("schemas", 7) {
+		t.Error("A failed repair should not forget the offsets of an earlier one")
+	}
+}
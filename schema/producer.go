@@ -6,7 +6,8 @@ import (
 	"github.com/yanzay/log"
 )
 
-func createProducer(brokerList []string) *producer.KafkaProducer {
+// NewProducer creates a producer for the schema log.
+func NewProducer(brokerList []string) *producer.KafkaProducer {
 	connector := createConnector(brokerList)
 	producerConfig := producer.NewProducerConfig()
 	producerConfig.BatchSize = 1
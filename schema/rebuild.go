@@ -0,0 +1,120 @@
+package schema
+
+import (
+	"fmt"
+
+	"github.com/goavro/wednesday/schema/storage"
+	"github.com/yanzay/log"
+)
+
+const rebuildProgressInterval = 1000
+
+// Rebuilder repopulates Cassandra from the schema log. Records are applied through a LogFollower,
+// so ids and versions are the same as in the log and a rebuild interrupted at any point
+// continues from the last applied offset when started again.
+type Rebuilder struct {
+	brokers   []string
+	topic     string
+	multiuser bool
+	target    rebuildTarget
+	follower  *storage.LogFollower
+	openLog   func() (logSource, error)
+}
+
+// rebuildTarget is a store that keeps its own checkpoints, as Cassandra does.
+type rebuildTarget interface {
+	storage.StorageStateWriter
+	storage.Checkpointer
+	Reset() error
+}
+
+// logSource is the part of LogReader a rebuild reads the log with.
+type logSource interface {
+	EndOffset(topic string) (int64, error)
+	ReplayAll(topic string, multiuser bool, from func(topic string) (int64, error), apply func(*storage.LogRecord) error) (map[string]int64, error)
+	Close()
+}
+
+func NewRebuilder(config SchemaRegistryConfig, cassandra *storage.CassandraStorage) *Rebuilder {
+	brokers := config.Brokers
+	return &Rebuilder{
+		brokers:   brokers,
+		topic:     config.Topic,
+		multiuser: config.Multiuser,
+		target:    cassandra,
+		follower:  storage.NewLogFollower(cassandra, cassandra),
+		openLog: func() (logSource, error) {
+			return NewLogReader(brokers)
+		},
+	}
+}
+
+// Rebuild applies every log record Cassandra doesn't have yet and returns the number of records replayed.
+// With reset set, Cassandra is emptied first and the whole log is applied. A rebuild that leaves records
+// skipped by the follower is an error, Cassandra still differs from the log.
+func (r *Rebuilder) Rebuild(reset bool) (int, error) {
+	if len(r.brokers) == 0 {
+		return 0, fmt.Errorf("Rebuild requires Kafka brokers")
+	}
+	if reset {
+		log.Warning("[Rebuilder] Removing all data from Cassandra")
+		err := r.target.Reset()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	reader, err := r.openLog()
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	replayed := 0
+	var end int64
+	from := func(topic string) (int64, error) {
+		topicEnd, err := reader.EndOffset(topic)
+		if err != nil {
+			return 0, err
+		}
+		end = topicEnd
+		if r.multiuser && topic == "admin" {
+			// user topics are only discovered by reading their create-user records,
+			// the follower skips the ones already applied
+			log.Infof("[Rebuilder] Replaying topic %s, %d records", topic, end)
+			return 0, nil
+		}
+		offset, err := r.follower.AppliedOffset(topic, 0)
+		if err != nil {
+			return 0, err
+		}
+		log.Infof("[Rebuilder] Replaying topic %s from offset %d, %d records left", topic, offset+1, end-offset-1)
+		return offset + 1, nil
+	}
+	_, err = reader.ReplayAll(r.topic, r.multiuser, from, func(record *storage.LogRecord) error {
+		err := r.follower.Apply(record)
+		if err != nil {
+			return err
+		}
+		replayed++
+		if replayed%rebuildProgressInterval == 0 {
+			log.Infof("[Rebuilder] Replayed %d records, topic %s at offset %d of %d", replayed, record.Topic, record.Offset, end-1)
+		}
+		return nil
+	})
+	if err != nil {
+		return replayed, err
+	}
+	skipped, err := r.follower.SkippedRecords("cassandra")
+	if err != nil {
+		return replayed, err
+	}
+	if len(skipped) > 0 {
+		for _, record := range skipped {
+			log.Error("[Rebuilder] " + record.Message)
+		}
+		return replayed, fmt.Errorf("Rebuild replayed %d records but %d of them were skipped, Cassandra doesn't match the log", replayed, len(skipped))
+	}
+	log.Infof("[Rebuilder] Rebuild complete, replayed %d records", replayed)
+	return replayed, nil
+}
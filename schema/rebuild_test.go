@@ -0,0 +1,171 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/goavro/wednesday/schema/storage"
+)
+
+// memoryLog is a single topic schema log.
+type memoryLog []*storage.LogRecord
+
+func (ml memoryLog) EndOffset(string) (int64, error) {
+	return int64(len(ml)), nil
+}
+
+func (ml memoryLog) ReplayAll(topic string, multiuser bool, from func(topic string) (int64, error), apply func(*storage.LogRecord) error) (map[string]int64, error) {
+	start, err := from(topic)
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range ml[start:] {
+		err = apply(record)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return map[string]int64{topic: int64(len(ml)) - 1}, nil
+}
+
+func (ml memoryLog) Close() {}
+
+// memoryTarget keeps its checkpoints along with the state, as Cassandra does.
+type memoryTarget struct {
+	*storage.InMemoryStorage
+	offsets map[string]int64
+	skipped []*storage.SkippedRecord
+}
+
+func newMemoryTarget() *memoryTarget {
+	return &memoryTarget{InMemoryStorage: storage.NewInMemoryStorage(), offsets: make(map[string]int64)}
+}
+
+func (mt *memoryTarget) AppliedOffset(topic string, partition int32) (int64, error) {
+	if offset, ok := mt.offsets[topic]; ok {
+		return offset, nil
+	}
+	return -1, nil
+}
+
+func (mt *memoryTarget) CommitOffset(topic string, partition int32, offset int64) error {
+	mt.offsets[topic] = offset
+	return nil
+}
+
+func (mt *memoryTarget) SkipRecord(skipped *storage.SkippedRecord) error {
+	mt.skipped = append(mt.skipped, skipped)
+	return nil
+}
+
+func (mt *memoryTarget) SkippedRecords() ([]*storage.SkippedRecord, error) {
+	return mt.skipped, nil
+}
+
+func (mt *memoryTarget) ForgetSkipped(topic string, partition int32, offset int64) error {
+	kept := make([]*storage.SkippedRecord, 0, len(mt.skipped))
+	for _, skipped := range mt.skipped {
+		if skipped.Topic != topic || skipped.Offset > offset {
+			kept = append(kept, skipped)
+		}
+	}
+	mt.skipped = kept
+	return nil
+}
+
+func (mt *memoryTarget) Reset() error {
+	mt.InMemoryStorage = storage.NewInMemoryStorage()
+	mt.offsets = make(map[string]int64)
+	mt.skipped = nil
+	return nil
+}
+
+func testRebuilder(target *memoryTarget, schemaLog memoryLog) *Rebuilder {
+	return &Rebuilder{
+		brokers:  []string{"localhost:9092"},
+		topic:    "schemas",
+		target:   target,
+		follower: storage.NewLogFollower(target, target),
+		openLog: func() (logSource, error) {
+			return schemaLog, nil
+		},
+	}
+}
+
+func rebuildLog() memoryLog {
+	record := func(offset int64, recordType storage.MessageType, schema string) *storage.LogRecord {
+		return &storage.LogRecord{Topic: "schemas", Offset: offset, Type: recordType,
+			Content: map[string]string{"client": "snow", "subject": "s", "schema": schema}}
+	}
+	// the skip record pads the log, so the last schema keeps id 4
+	return memoryLog{
+		record(0, storage.MessageSchema, `{"type": "string"}`),
+		record(1, storage.MessageSchema, `{"type": "int"}`),
+		record(2, storage.MessageSkip, ""),
+		record(3, storage.MessageSchema, `{"type": "long"}`),
+	}
+}
+
+func TestRebuildResumesFromCheckpoint(t *testing.T) {
+	target := newMemoryTarget()
+	target.AddSchema("snow", "s", 1, `{"type": "string"}`)
+	target.CommitOffset("schemas", 0, 0)
+
+	replayed, err := testRebuilder(target, rebuildLog()).Rebuild(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed != 3 {
+		t.Errorf("Expected the 3 records after the checkpoint to be replayed, got %d", replayed)
+	}
+	snapshot, _ := target.Dump()
+	versions := snapshot.Client("snow").Subjects["s"]
+	for version, id := range map[int]int64{1: 1, 2: 2, 3: 4} {
+		if versions[version] != id {
+			t.Errorf("Expected version %d to keep id %d, got versions %v", version, id, versions)
+		}
+	}
+
+	// a second run has nothing left to replay
+	replayed, err = testRebuilder(target, rebuildLog()).Rebuild(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed != 0 {
+		t.Errorf("Expected nothing to replay, got %d", replayed)
+	}
+}
+
+func TestRebuildReset(t *testing.T) {
+	target := newMemoryTarget()
+	target.AddSchema("snow", "other", 7, `{"type": "string"}`)
+	target.CommitOffset("schemas", 0, 3)
+
+	replayed, err := testRebuilder(target, rebuildLog()).Rebuild(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed != 4 {
+		t.Errorf("Expected the whole log to be replayed, got %d", replayed)
+	}
+	if _, found, _ := target.GetSchemaByID("snow", 7); found {
+		t.Error("Expected the state before the reset to be removed")
+	}
+	snapshot, _ := target.Dump()
+	if versions := snapshot.Client("snow").Subjects["s"]; versions[3] != 4 {
+		t.Errorf("Expected version 3 to keep id 4, got versions %v", versions)
+	}
+}
+
+func TestRebuildFailsWithSkippedRecords(t *testing.T) {
+	target := newMemoryTarget()
+	target.SkipRecord(&storage.SkippedRecord{Topic: "schemas", Offset: 1, Type: storage.MessageSchema, Client: "snow", Reason: "rejected"})
+	target.CommitOffset("schemas", 0, 1)
+
+	if _, err := testRebuilder(target, rebuildLog()).Rebuild(false); err == nil {
+		t.Error("Expected a rebuild that leaves a skipped record to fail")
+	}
+	// a reset rebuild applies the skipped record again
+	if _, err := testRebuilder(target, rebuildLog()).Rebuild(true); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,138 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/goavro/wednesday/schema/storage"
+	client "github.com/serejja/kafka-client"
+)
+
+// LogReader reads schema log topics from the beginning up to their current end.
+type LogReader struct {
+	client *client.KafkaClient
+}
+
+func NewLogReader(brokerList []string) (*LogReader, error) {
+	config := client.NewConfig()
+	config.BrokerList = brokerList
+	kafkaClient, err := client.New(config)
+	if err != nil {
+		return nil, err
+	}
+	return &LogReader{client: kafkaClient}, nil
+}
+
+// Replay passes every record of the topic, starting at the given offset, to apply.
+// Returns the offset of the last record in the topic at the moment of the call, or -1 if the topic is empty.
+func (lr *LogReader) Replay(topic string, from int64, apply func(*storage.LogRecord) error) (int64, error) {
+	end, err := lr.EndOffset(topic)
+	if err != nil || end == 0 {
+		return -1, err
+	}
+	start, err := lr.client.GetAvailableOffset(topic, 0, client.EarliestTime)
+	if err != nil {
+		return -1, err
+	}
+	if from < start {
+		from = start
+	}
+
+	offset := from
+	for offset < end {
+		fetched := offset
+		response, err := lr.client.Fetch(topic, 0, offset)
+		if err != nil {
+			return -1, err
+		}
+		err = response.CollectMessages(func(topic string, partition int32, messageOffset int64, key []byte, value []byte) error {
+			// compressed message sets can start before the requested offset
+			if messageOffset < offset || messageOffset >= end {
+				return nil
+			}
+			content := make(map[string]string)
+			err := json.Unmarshal(value, &content)
+			if err != nil {
+				return err
+			}
+			err = apply(&storage.LogRecord{
+				Topic:     topic,
+				Partition: partition,
+				Offset:    messageOffset,
+				Type:      storage.MessageType(key),
+				Content:   content,
+			})
+			if err != nil {
+				return err
+			}
+			offset = messageOffset + 1
+			return nil
+		})
+		if err != nil {
+			return -1, err
+		}
+		if offset == fetched {
+			return -1, fmt.Errorf("No records fetched from topic %s at offset %d", topic, offset)
+		}
+	}
+	return end - 1, nil
+}
+
+// ReplayAll replays every topic of the registry: the given topic, or in multiuser mode the admin topic
+// followed by the topic of every user created in it. from returns the offset to start each topic at.
+// Returns the last offset of each topic, -1 for empty ones.
+func (lr *LogReader) ReplayAll(topic string, multiuser bool, from func(topic string) (int64, error), apply func(*storage.LogRecord) error) (map[string]int64, error) {
+	topics := []string{topic}
+	if multiuser {
+		topics = []string{"admin"}
+	}
+	offsets := make(map[string]int64)
+	for i := 0; i < len(topics); i++ {
+		start, err := from(topics[i])
+		if err != nil {
+			return nil, err
+		}
+		offsets[topics[i]], err = lr.Replay(topics[i], start, func(record *storage.LogRecord) error {
+			err := apply(record)
+			if err != nil || !multiuser || record.Type != storage.MessageCreateUser {
+				return err
+			}
+			if _, ok := offsets[record.Content["name"]]; !ok {
+				offsets[record.Content["name"]] = -1
+				topics = append(topics, record.Content["name"])
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return offsets, nil
+}
+
+// replayLog reads every topic of the registry into the given storage and returns the last offset of each topic.
+func replayLog(brokers []string, topic string, multiuser bool, store storage.StorageStateWriter) (map[string]int64, error) {
+	reader, err := NewLogReader(brokers)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	fromStart := func(string) (int64, error) { return 0, nil }
+	return reader.ReplayAll(topic, multiuser, fromStart, func(record *storage.LogRecord) error {
+		return storage.ApplyRecord(store, record)
+	})
+}
+
+// EndOffset returns the offset the next record of the topic will get, 0 if the topic doesn't exist.
+func (lr *LogReader) EndOffset(topic string) (int64, error) {
+	end, err := lr.client.GetAvailableOffset(topic, 0, client.LatestTime)
+	if err == client.ErrUnknownTopicOrPartition {
+		return 0, nil
+	}
+	return end, err
+}
+
+func (lr *LogReader) Close() {
+	<-lr.client.Close()
+}
@@ -0,0 +1,391 @@
+package storage
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+const (
+	ArchiveNDJSON = "ndjson"
+	ArchiveTar    = "tar"
+)
+
+const (
+	ConflictID            = "ID_CONFLICT"
+	ConflictIDUnavailable = "ID_UNAVAILABLE"
+	ConflictVersion       = "VERSION_CONFLICT"
+	ConflictConfig        = "CONFIG_CONFLICT"
+	ConflictSkipRecords   = "SKIP_RECORDS_DISABLED"
+	ConflictInvalid       = "INVALID_RECORD"
+)
+
+// ErrNoLog is returned by imports into a registry running without Kafka.
+var ErrNoLog = errors.New("Import requires Kafka brokers")
+
+// ArchiveRecord is a single line of an export archive. Type is the log message type the record is restored with.
+// Users are never archived, their tokens are secrets and they are recreated by authorization.
+type ArchiveRecord struct {
+	Type          MessageType `json:"type"`
+	Client        string      `json:"client"`
+	Subject       string      `json:"subject,omitempty"`
+	Version       int         `json:"version,omitempty"`
+	ID            int64       `json:"id,omitempty"`
+	Schema        string      `json:"schema,omitempty"`
+	Compatibility string      `json:"compatibility,omitempty"`
+	Name          string      `json:"name,omitempty"`
+	Value         string      `json:"value,omitempty"`
+}
+
+// Content returns the log message content for the record.
+func (ar *ArchiveRecord) Content() map[string]string {
+	content := map[string]string{"client": ar.Client}
+	switch ar.Type {
+	case MessageSchema:
+		content["subject"] = ar.Subject
+		content["schema"] = ar.Schema
+	case MessageSubjectConfig:
+		content["subject"] = ar.Subject
+		content["compatibility"] = ar.Compatibility
+	case MessageGlobalConfig:
+		content["compatibility"] = ar.Compatibility
+	case MessageSetting:
+		content["subject"] = ar.Subject
+		content["name"] = ar.Name
+		content["value"] = ar.Value
+	}
+	return content
+}
+
+// ArchiveRecords lists the configs, settings and schemas of a client, schemas ordered by subject and version.
+func ArchiveRecords(client string, state *ClientSnapshot) []*ArchiveRecord {
+	records := make([]*ArchiveRecord, 0)
+	if state.GlobalConfig != "" {
+		records = append(records, &ArchiveRecord{Type: MessageGlobalConfig, Client: client, Compatibility: state.GlobalConfig})
+	}
+	for _, subject := range sortedConfigs(state.SubjectConfigs) {
+		records = append(records, &ArchiveRecord{Type: MessageSubjectConfig, Client: client, Subject: subject,
+			Compatibility: state.SubjectConfigs[subject]})
+	}
+	for _, key := range sortedSettings(state.Settings) {
+		records = append(records, &ArchiveRecord{Type: MessageSetting, Client: client, Subject: key.Subject, Name: key.Name,
+			Value: state.Settings[key]})
+	}
+	for _, subject := range sortedSubjects(state.Subjects) {
+		versions := state.Subjects[subject]
+		for _, version := range sortedVersions(versions) {
+			id := versions[version]
+			records = append(records, &ArchiveRecord{Type: MessageSchema, Client: client, Subject: subject, Version: version,
+				ID: id, Schema: state.Schemas[id]})
+		}
+	}
+	return records
+}
+
+// WriteArchive writes every client of the snapshot as NDJSON, or as a gzipped tarball with a <client>.ndjson file per client.
+func WriteArchive(w io.Writer, snapshot *Snapshot, format string) error {
+	writer, err := NewArchiveWriter(w, format)
+	if err != nil {
+		return err
+	}
+	for _, client := range snapshot.SortedClients() {
+		err = writer.WriteClient(client, snapshot.Clients[client])
+		if err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// ArchiveWriter writes an archive a client at a time, so only the records of one client are held in memory.
+type ArchiveWriter struct {
+	w       io.Writer
+	zipped  *gzip.Writer
+	archive *tar.Writer
+}
+
+// NewArchiveWriter starts an archive in the given format on w.
+func NewArchiveWriter(w io.Writer, format string) (*ArchiveWriter, error) {
+	switch format {
+	case ArchiveNDJSON:
+		return &ArchiveWriter{w: w}, nil
+	case ArchiveTar:
+		zipped := gzip.NewWriter(w)
+		return &ArchiveWriter{w: w, zipped: zipped, archive: tar.NewWriter(zipped)}, nil
+	}
+	return nil, fmt.Errorf("Unknown archive format %s", format)
+}
+
+// WriteClient writes the records of a client. A tarball file needs its size in the header, so the records are
+// encoded twice, first only counting the bytes, rather than buffered.
+func (aw *ArchiveWriter) WriteClient(client string, state *ClientSnapshot) error {
+	records := ArchiveRecords(client, state)
+	if aw.archive == nil {
+		return writeRecords(aw.w, records)
+	}
+	counter := new(byteCounter)
+	err := writeRecords(counter, records)
+	if err != nil {
+		return err
+	}
+	err = aw.archive.WriteHeader(&tar.Header{Name: client + ".ndjson", Mode: 0644, Size: counter.count})
+	if err != nil {
+		return err
+	}
+	return writeRecords(aw.archive, records)
+}
+
+// Close ends the archive, it doesn't close the writer the archive is written to.
+func (aw *ArchiveWriter) Close() error {
+	if aw.archive == nil {
+		return nil
+	}
+	err := aw.archive.Close()
+	if err != nil {
+		return err
+	}
+	return aw.zipped.Close()
+}
+
+type byteCounter struct {
+	count int64
+}
+
+func (bc *byteCounter) Write(p []byte) (int, error) {
+	bc.count += int64(len(p))
+	return len(p), nil
+}
+
+// ReadArchive reads an archive written by WriteArchive back into a snapshot.
+func ReadArchive(r io.Reader, format string) (*Snapshot, error) {
+	snapshot := NewSnapshot()
+	switch format {
+	case ArchiveNDJSON:
+		return snapshot, readRecords(r, snapshot)
+	case ArchiveTar:
+		zipped, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		archive := tar.NewReader(zipped)
+		for {
+			header, err := archive.Next()
+			if err == io.EOF {
+				return snapshot, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			if !strings.HasSuffix(header.Name, ".ndjson") {
+				continue
+			}
+			err = readRecords(archive, snapshot)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", header.Name, err)
+			}
+		}
+	}
+	return nil, fmt.Errorf("Unknown archive format %s", format)
+}
+
+func writeRecords(w io.Writer, records []*ArchiveRecord) error {
+	encoder := json.NewEncoder(w)
+	for _, record := range records {
+		err := encoder.Encode(record)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readRecords(r io.Reader, snapshot *Snapshot) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		record := &ArchiveRecord{}
+		err := json.Unmarshal(scanner.Bytes(), record)
+		if err != nil {
+			return fmt.Errorf("line %d: %s", line, err)
+		}
+		if record.Client == "" {
+			return fmt.Errorf("line %d: client is required", line)
+		}
+		state := snapshot.Client(record.Client)
+		switch record.Type {
+		case MessageSchema:
+			if record.ID <= 0 || record.Version <= 0 || record.Subject == "" {
+				return fmt.Errorf("line %d: schema record needs a subject, a version and an id", line)
+			}
+			// a snapshot keeps one schema per id and one id per version, so a repeated one must not overwrite the first
+			if schema, ok := state.Schemas[record.ID]; ok && schema != record.Schema {
+				return fmt.Errorf("line %d: schema id %d appears twice with different content", line, record.ID)
+			}
+			if _, ok := state.Subjects[record.Subject]; !ok {
+				state.Subjects[record.Subject] = make(Versions)
+			}
+			if id, ok := state.Subjects[record.Subject][record.Version]; ok && id != record.ID {
+				return fmt.Errorf("line %d: version %d of subject %s appears twice, with ids %d and %d", line, record.Version, record.Subject, id, record.ID)
+			}
+			state.Subjects[record.Subject][record.Version] = record.ID
+			state.Schemas[record.ID] = record.Schema
+		case MessageGlobalConfig:
+			state.GlobalConfig = record.Compatibility
+		case MessageSubjectConfig:
+			state.SubjectConfigs[record.Subject] = record.Compatibility
+		case MessageSetting:
+			if record.Name == "" {
+				return fmt.Errorf("line %d: setting record needs a name", line)
+			}
+			state.Settings[SettingKey{record.Subject, record.Name}] = record.Value
+		default:
+			return fmt.Errorf("line %d: unexpected record type %s", line, record.Type)
+		}
+	}
+	return scanner.Err()
+}
+
+// ImportPlan lists the records an import writes to the log, or the conflicts that prevent it.
+type ImportPlan struct {
+	Conflicts []*Inconsistency `json:"conflicts"`
+	Schemas   []*ArchiveRecord `json:"schemas"`
+	Configs   []*ArchiveRecord `json:"configs"`
+	Imported  bool             `json:"imported"`
+}
+
+// PlanImport compares an archive with the current state. ends holds the next offset of every client topic:
+// a schema id is its offset + 1, so a new schema can keep its id only if that offset is still ahead.
+// Schemas and configs already in place are skipped, so importing the same archive twice is harmless.
+func PlanImport(current *Snapshot, archive *Snapshot, ends map[string]int64) *ImportPlan {
+	plan := &ImportPlan{
+		Conflicts: make([]*Inconsistency, 0),
+		Schemas:   make([]*ArchiveRecord, 0),
+		Configs:   make([]*ArchiveRecord, 0),
+	}
+	conflict := func(kind string, client string, subject string, version int, id int64, format string, args ...interface{}) {
+		plan.Conflicts = append(plan.Conflicts, &Inconsistency{"import", kind, client, subject, version, id, fmt.Sprintf(format, args...)})
+	}
+
+	for _, client := range archive.SortedClients() {
+		want := archive.Clients[client]
+		got, exists := current.Clients[client]
+		if !exists {
+			got = NewSnapshot().Client(client)
+		}
+
+		// every id belongs to a single version, one the archive gives to several can't be imported at all
+		uses := make(map[int64]int)
+		for _, versions := range want.Subjects {
+			for _, id := range versions {
+				uses[id]++
+			}
+		}
+
+		schemas := make([]*ArchiveRecord, 0)
+		for _, subject := range sortedSubjects(want.Subjects) {
+			existing := got.Subjects[subject]
+			next := 1
+			if len(existing) > 0 {
+				_, next = latestVersion(existing)
+				next++
+			}
+			var lastID int64
+			for _, version := range sortedVersions(want.Subjects[subject]) {
+				id := want.Subjects[subject][version]
+				schema := want.Schemas[id]
+				if uses[id] > 1 {
+					conflict(ConflictID, client, subject, version, id, "Schema id %d appears %d times in the archive", id, uses[id])
+					continue
+				}
+				if existingID, ok := existing[version]; ok {
+					if existingID != id {
+						conflict(ConflictVersion, client, subject, version, id, "Version %d of subject %s has id %d instead of %d", version, subject, existingID, id)
+					} else if got.Schemas[id] != schema {
+						conflict(ConflictID, client, subject, version, id, "Schema id %d has different content", id)
+					}
+					continue
+				}
+				if version != next {
+					conflict(ConflictVersion, client, subject, version, id, "Version %d of subject %s would be registered as version %d", version, subject, next)
+					continue
+				}
+				next++
+				if _, ok := got.Schemas[id]; ok {
+					conflict(ConflictID, client, subject, version, id, "Schema id %d is already used by another subject", id)
+					continue
+				}
+				if id <= lastID {
+					conflict(ConflictVersion, client, subject, version, id, "Schema id %d of version %d is lower than the id of the previous version", id, version)
+					continue
+				}
+				lastID = id
+				if id-1 < ends[client] {
+					conflict(ConflictIDUnavailable, client, subject, version, id, "Schema id %d can't be kept, the log is already at offset %d", id, ends[client])
+					continue
+				}
+				schemas = append(schemas, &ArchiveRecord{Type: MessageSchema, Client: client, Subject: subject, Version: version, ID: id, Schema: schema})
+			}
+		}
+		sort.Sort(recordsByID(schemas))
+		plan.Schemas = append(plan.Schemas, schemas...)
+
+		if want.GlobalConfig != "" && want.GlobalConfig != got.GlobalConfig {
+			if got.GlobalConfig != "" {
+				conflict(ConflictConfig, client, "", 0, 0, "Global config is %q, the archive has %q", got.GlobalConfig, want.GlobalConfig)
+			} else {
+				plan.Configs = append(plan.Configs, &ArchiveRecord{Type: MessageGlobalConfig, Client: client, Compatibility: want.GlobalConfig})
+			}
+		}
+		for _, subject := range sortedConfigs(want.SubjectConfigs) {
+			level := want.SubjectConfigs[subject]
+			existing, ok := got.SubjectConfigs[subject]
+			if !ok {
+				plan.Configs = append(plan.Configs, &ArchiveRecord{Type: MessageSubjectConfig, Client: client, Subject: subject, Compatibility: level})
+			} else if existing != level {
+				conflict(ConflictConfig, client, subject, 0, 0, "Config for subject %s is %q, the archive has %q", subject, existing, level)
+			}
+		}
+		for _, key := range sortedSettings(want.Settings) {
+			value := want.Settings[key]
+			existing, ok := got.Settings[key]
+			if !ok {
+				plan.Configs = append(plan.Configs, &ArchiveRecord{Type: MessageSetting, Client: client, Subject: key.Subject, Name: key.Name, Value: value})
+			} else if existing != value {
+				conflict(ConflictConfig, client, key.Subject, 0, 0, "Setting %s is %q, the archive has %q", key, existing, value)
+			}
+		}
+	}
+	return plan
+}
+
+// ForbidSkipRecords reports a conflict for every schema that needs skip records written before it to keep its id.
+// Nodes older than skip records fail to apply them, so imports only pad the log once every node can.
+func (plan *ImportPlan) ForbidSkipRecords(ends map[string]int64) {
+	next := make(map[string]int64, len(ends))
+	for client, end := range ends {
+		next[client] = end
+	}
+	for _, record := range plan.Schemas {
+		if skips := record.ID - 1 - next[record.Client]; skips > 0 {
+			plan.Conflicts = append(plan.Conflicts, &Inconsistency{"import", ConflictSkipRecords, record.Client, record.Subject,
+				record.Version, record.ID, fmt.Sprintf("Schema id %d needs %d skip records before it, skip records are not enabled", record.ID, skips)})
+		}
+		next[record.Client] = record.ID
+	}
+}
+
+type recordsByID []*ArchiveRecord
+
+func (r recordsByID) Len() int           { return len(r) }
+func (r recordsByID) Less(i, j int) bool { return r[i].ID < r[j].ID }
+func (r recordsByID) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
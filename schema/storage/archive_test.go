@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"bytes"
+	"testing"
+)
+
+func archiveStore() *InMemoryStorage {
+	store := NewInMemoryStorage()
+	store.AddSchema(client, subject, 2, testSchema)
+	store.AddSchema(client, subject, 5, anotherSchema)
+	store.AddSchema(client, "other", 3, `{"type": "int"}`)
+	store.SetGlobalConfig(client, CompatibilityFull)
+	store.SetSubjectConfig(client, subject, CompatibilityNone)
+	store.SetSetting(client, "", SettingValidation, "lenient")
+	store.AddSchema("tenant", subject, 1, testSchema)
+	return store
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	snapshot, _ := archiveStore().Dump()
+	for _, format := range []string{ArchiveNDJSON, ArchiveTar} {
+		buffer := &bytes.Buffer{}
+		err := WriteArchive(buffer, snapshot, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		restored, err := ReadArchive(buffer, format)
+		if err != nil {
+			t.Fatalf("%s: %s", format, err)
+		}
+		if inconsistencies := CompareSnapshots(format, snapshot, restored); len(inconsistencies) != 0 {
+			t.Errorf("%s: expected identical snapshots, got %v", format, inconsistencies)
+		}
+	}
+}
+
+func TestReadArchiveErrors(t *testing.T) {
+	archives := []string{
+		`{"type": "schema", "client": "snow", "subject": "s", "version": 1}`,
+		`{"type": "create-user", "client": "admin"}`,
+		`{"type": "global-config", "compatibility": "FULL"}`,
+		`not json`,
+		`{"type": "schema", "client": "snow", "subject": "s", "version": 1, "id": 1, "schema": "\"int\""}` + "\n" +
+			`{"type": "schema", "client": "snow", "subject": "t", "version": 1, "id": 1, "schema": "\"long\""}`,
+		`{"type": "schema", "client": "snow", "subject": "s", "version": 1, "id": 1, "schema": "\"int\""}` + "\n" +
+			`{"type": "schema", "client": "snow", "subject": "s", "version": 1, "id": 2, "schema": "\"int\""}`,
+	}
+	for _, archive := range archives {
+		if _, err := ReadArchive(bytes.NewBufferString(archive), ArchiveNDJSON); err == nil {
+			t.Errorf("Expected error for archive %s", archive)
+		}
+	}
+}
+
+func TestPlanImport(t *testing.T) {
+	archive, _ := archiveStore().Dump()
+
+	plan := PlanImport(NewSnapshot(), archive, map[string]int64{})
+	if len(plan.Conflicts) != 0 {
+		t.Fatalf("Expected no conflicts for an empty registry, got %v", plan.Conflicts)
+	}
+	if len(plan.Schemas) != 4 || len(plan.Configs) != 3 {
+		t.Errorf("Expected 4 schemas and 3 configs, got %d and %d", len(plan.Schemas), len(plan.Configs))
+	}
+	// schemas are written in id order, so offsets only grow
+	var lastID int64
+	for _, record := range plan.Schemas {
+		if record.Client == client {
+			if record.ID <= lastID {
+				t.Errorf("Expected schemas ordered by id, got %d after %d", record.ID, lastID)
+			}
+			lastID = record.ID
+		}
+	}
+
+	// replaying the plan the way the log would gives the same ids and versions
+	store := NewInMemoryStorage()
+	for _, record := range append(plan.Schemas, plan.Configs...) {
+		var err error
+		if record.Type == MessageSchema {
+			err = ApplyRecord(store, &LogRecord{Type: record.Type, Offset: record.ID - 1, Content: record.Content()})
+		} else {
+			err = ApplyRecord(store, &LogRecord{Type: record.Type, Content: record.Content()})
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	imported, _ := store.Dump()
+	if inconsistencies := CompareSnapshots("import", archive, imported); len(inconsistencies) != 0 {
+		t.Errorf("Expected imported state to match the archive, got %v", inconsistencies)
+	}
+
+	plan = PlanImport(imported, archive, map[string]int64{client: 5, "tenant": 1})
+	if len(plan.Conflicts) != 0 || len(plan.Schemas) != 0 || len(plan.Configs) != 0 {
+		t.Errorf("Expected importing the same archive twice to do nothing, got %v", plan)
+	}
+}
+
+func TestForbidSkipRecords(t *testing.T) {
+	archive, _ := archiveStore().Dump()
+
+	// ids 2 and 5 of the first client need a skip record each before them, 3 and the tenant's 1 don't
+	plan := PlanImport(NewSnapshot(), archive, map[string]int64{})
+	plan.ForbidSkipRecords(map[string]int64{})
+	if len(plan.Conflicts) != 2 || plan.Conflicts[0].ID != 2 || plan.Conflicts[1].ID != 5 {
+		t.Errorf("Expected ids 2 and 5 to need skip records, got %v", plan.Conflicts)
+	}
+	if kinds(plan.Conflicts)[ConflictSkipRecords] != 2 {
+		t.Errorf("Expected skip record conflicts, got %v", plan.Conflicts)
+	}
+
+	current := NewInMemoryStorage()
+	current.AddSchema(client, subject, 2, testSchema)
+	currentSnapshot, _ := current.Dump()
+	plan = PlanImport(currentSnapshot, archive, map[string]int64{client: 2})
+	plan.ForbidSkipRecords(map[string]int64{client: 2})
+	if len(plan.Conflicts) != 1 || plan.Conflicts[0].ID != 5 {
+		t.Errorf("Expected only id 5 to need skip records, got %v", plan.Conflicts)
+	}
+}
+
+func TestPlanImportConflicts(t *testing.T) {
+	archive, _ := archiveStore().Dump()
+
+	current := NewInMemoryStorage()
+	current.AddSchema(client, subject, 2, anotherSchema)
+	current.AddSchema(client, "taken", 3, `{"type": "long"}`)
+	current.SetGlobalConfig(client, CompatibilityBackward)
+	currentSnapshot, _ := current.Dump()
+
+	plan := PlanImport(currentSnapshot, archive, map[string]int64{client: 3, "tenant": 1})
+	counts := kinds(plan.Conflicts)
+	// id 2 has different content, id 3 is used by another subject
+	if counts[ConflictID] != 2 {
+		t.Errorf("Expected 2 id conflicts, got %v", plan.Conflicts)
+	}
+	// the tenant topic is already past offset 0
+	if counts[ConflictIDUnavailable] != 1 {
+		t.Errorf("Expected 1 unavailable id, got %v", plan.Conflicts)
+	}
+	if counts[ConflictConfig] != 1 {
+		t.Errorf("Expected 1 config conflict, got %v", plan.Conflicts)
+	}
+	if counts[ConflictVersion] != 0 {
+		t.Errorf("Expected no version conflicts, got %v", plan.Conflicts)
+	}
+
+	current = NewInMemoryStorage()
+	current.AddSchema(client, subject, 1, `{"type": "long"}`)
+	currentSnapshot, _ = current.Dump()
+	plan = PlanImport(currentSnapshot, archive, map[string]int64{client: 1})
+	if counts := kinds(plan.Conflicts); counts[ConflictVersion] != 1 {
+		t.Errorf("Expected version 1 to conflict, got %v", plan.Conflicts)
+	}
+}
+
+func TestPlanImportDuplicateIDs(t *testing.T) {
+	// the same id under two subjects reads fine, it's the same schema, but can't be written twice
+	records := `{"type": "schema", "client": "snow", "subject": "s", "version": 1, "id": 1, "schema": "\"int\""}` + "\n" +
+		`{"type": "schema", "client": "snow", "subject": "t", "version": 1, "id": 1, "schema": "\"int\""}` + "\n" +
+		`{"type": "schema", "client": "snow", "subject": "t", "version": 2, "id": 2, "schema": "\"long\""}`
+	archive, err := ReadArchive(bytes.NewBufferString(records), ArchiveNDJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan := PlanImport(NewSnapshot(), archive, map[string]int64{})
+	if counts := kinds(plan.Conflicts); counts[ConflictID] != 2 {
+		t.Errorf("Expected both uses of id 1 to conflict, got %v", plan.Conflicts)
+	}
+	for _, record := range plan.Schemas {
+		if record.ID == 1 {
+			t.Errorf("A duplicate id must not be planned, got %v", record)
+		}
+	}
+}
@@ -75,6 +75,22 @@ func (cs *CachedStorage) GetSubjectConfig(client string, subject string) (string
 	return level, found, err
 }
 
+func (cs *CachedStorage) GetSetting(client string, subject string, name string) (string, bool, error) {
+	value, found, err := cs.Cache.GetSetting(client, subject, name)
+	if !found || err != nil {
+		return cs.Backend.GetSetting(client, subject, name)
+	}
+	return value, found, err
+}
+
+func (cs *CachedStorage) GetSettings(client string, subject string) (map[string]string, error) {
+	settings, err := cs.Cache.GetSettings(client, subject)
+	if len(settings) == 0 || err != nil {
+		return cs.Backend.GetSettings(client, subject)
+	}
+	return settings, nil
+}
+
 func (cs *CachedStorage) UserByName(name string) (*User, bool) {
 	return cs.Cache.UserByName(name)
 }
@@ -0,0 +1,16 @@
+// +build integration
+
+package storage_test
+
+import (
+	"testing"
+
+	"github.com/goavro/wednesday/schema/storage"
+	"github.com/goavro/wednesday/schema/storage/storagetest"
+)
+
+func TestCassandraStorageConformance(t *testing.T) {
+	storagetest.Run(t, func() storage.StorageStater {
+		return storage.PrepareCassandraStorage()
+	})
+}
@@ -1,6 +1,8 @@
 package storage
 
 import (
+	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -45,7 +47,7 @@ func NewCassandraStorage(urls string, protoVersion int, cqlVersion string) *Cass
 // implement StorageStateReader interface
 func (cs *CassandraStorage) Empty() bool {
 	var count *int
-	err := cs.connection.Query("SELECT COUNT(*) FROM avro.schemas;").Scan(&count)
+	err := cs.connection.Query("SELECT COUNT(*) FROM avro.users;").Scan(&count)
 	if err != nil {
 		log.Error(err)
 		return false
@@ -83,8 +85,14 @@ func (cs *CassandraStorage) GetSchemaByID(client string, id int64) (string, bool
 func (cs *CassandraStorage) GetSubjects(client string) ([]string, error) {
 	iter := cs.connection.Query("SELECT subject FROM avro.schemas WHERE client = ?", client).Iter()
 	subjects := make([]string, 0)
+	seen := make(map[string]struct{})
 	var subject *string
 	for iter.Scan(&subject) {
+		// every version is a separate row, so the same subject comes back once per version
+		if _, ok := seen[*subject]; ok {
+			continue
+		}
+		seen[*subject] = struct{}{}
 		subjects = append(subjects, *subject)
 	}
 	if err := iter.Close(); err != nil {
@@ -110,6 +118,9 @@ func (cs *CassandraStorage) GetSchema(client string, subject string, version int
 	var schema *string
 	err := cs.connection.Query("SELECT avro_schema FROM avro.schemas WHERE client = ? AND subject = ? AND version = ?",
 		client, subject, version).Consistency(gocql.One).Scan(&schema)
+	if err == gocql.ErrNotFound {
+		return "", false, cs.clientExists(client)
+	}
 	if err != nil {
 		return "", false, err
 	}
@@ -151,36 +162,142 @@ func (cs *CassandraStorage) GetSubjectConfig(client string, subject string) (str
 	var level string
 	err := cs.connection.Query("SELECT level FROM avro.configs WHERE client = ? AND global = false AND subject = ?",
 		client, subject).Consistency(gocql.One).Scan(&level)
+	if err == gocql.ErrNotFound {
+		return "", false, nil
+	}
 	if err != nil {
 		return "", false, err
 	}
 	return level, true, nil
 }
 
+func (cs *CassandraStorage) GetSetting(client string, subject string, name string) (string, bool, error) {
+	var value string
+	err := cs.connection.Query("SELECT value FROM avro.settings WHERE client = ? AND subject = ? AND name = ?",
+		client, subject, name).Consistency(gocql.One).Scan(&value)
+	if err == gocql.ErrNotFound {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+func (cs *CassandraStorage) GetSettings(client string, subject string) (map[string]string, error) {
+	settings := make(map[string]string)
+	var name, value string
+	iter := cs.connection.Query("SELECT name, value FROM avro.settings WHERE client = ? AND subject = ?",
+		client, subject).Consistency(gocql.One).Iter()
+	for iter.Scan(&name, &value) {
+		settings[name] = value
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
 func (cs *CassandraStorage) UserByName(name string) (*User, bool) {
+	iter := cs.connection.Query("SELECT name, token, admin FROM avro.users").Iter()
+	user := &User{}
+	for iter.Scan(&user.Name, &user.Token, &user.Admin) {
+		if user.Name == name {
+			iter.Close()
+			return user, true
+		}
+	}
+	if err := iter.Close(); err != nil {
+		log.Error(err)
+	}
 	return nil, false
 }
 
 func (cs *CassandraStorage) UserByToken(token string) (*User, bool) {
-	return nil, false
+	user := &User{}
+	err := cs.connection.Query("SELECT name, token, admin FROM avro.users WHERE token = ?",
+		token).Consistency(gocql.One).Scan(&user.Name, &user.Token, &user.Admin)
+	if err != nil {
+		if err != gocql.ErrNotFound {
+			log.Error(err)
+		}
+		return nil, false
+	}
+	return user, true
 }
 
 // implement StorageStateWriter interface
 func (cs *CassandraStorage) AddSchema(client string, subject string, id int64, schema string) error {
-	versions, found, err := cs.GetVersions(client, subject)
+	// every node follows the log into the same keyspace, so the version of an id is claimed in avro.schema_ids
+	// with a lightweight transaction first, and every node applying the same record writes it at the claimed version
+	for retries := 0; retries < maxRetries; retries++ {
+		_, found, err := cs.GetSchemaByID(client, id)
+		if err != nil || found {
+			return err
+		}
+		version, claimed, err := cs.claimedVersion(client, id)
+		if err != nil {
+			return err
+		}
+		if claimed {
+			// lightweight transaction so concurrent writers of different ids can't both take the same version
+			existing := make(map[string]interface{})
+			applied, err := cs.connection.Query("INSERT INTO avro.schemas (client, subject, version, id, avro_schema) VALUES (?, ?, ?, ?, ?) IF NOT EXISTS",
+				client, subject, version, id, schema).MapScanCAS(existing)
+			if err != nil {
+				return err
+			}
+			// the id column is a CQL int, which gocql reads into an int
+			if existingID, _ := existing["id"].(int); applied || int64(existingID) == id {
+				return nil
+			}
+		}
+
+		// the claimed version went to another id, or nothing is claimed yet: claim the next free one
+		newVersion, err := cs.nextVersion(client, subject)
+		if err != nil {
+			return err
+		}
+		if claimed {
+			_, err = cs.connection.Query("UPDATE avro.schema_ids SET version = ? WHERE client = ? AND id = ? IF version = ?",
+				newVersion, client, id, version).MapScanCAS(make(map[string]interface{}))
+		} else {
+			_, err = cs.connection.Query("INSERT INTO avro.schema_ids (client, id, subject, version) VALUES (?, ?, ?, ?) IF NOT EXISTS",
+				client, id, subject, newVersion).MapScanCAS(make(map[string]interface{}))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("Can't allocate a version for subject %s: too many concurrent writes", subject)
+}
+
+// claimedVersion returns the version claimed for a schema id by the node that applied it first.
+func (cs *CassandraStorage) claimedVersion(client string, id int64) (int, bool, error) {
+	var version int
+	err := cs.connection.Query("SELECT version FROM avro.schema_ids WHERE client = ? AND id = ?",
+		client, id).Consistency(gocql.Quorum).Scan(&version)
+	if err == gocql.ErrNotFound {
+		return 0, false, nil
+	}
 	if err != nil {
-		return err
+		return 0, false, err
+	}
+	return version, true, nil
+}
+
+func (cs *CassandraStorage) nextVersion(client string, subject string) (int, error) {
+	versions, _, err := cs.GetVersions(client, subject)
+	if err != nil {
+		return 0, err
 	}
 	newVersion := 1
-	if found {
-		for _, version := range versions {
-			if newVersion <= version {
-				newVersion = version + 1
-			}
+	for _, version := range versions {
+		if newVersion <= version {
+			newVersion = version + 1
 		}
 	}
-	return cs.connection.Query("INSERT INTO avro.schemas (client, subject, version, id, avro_schema) VALUES (?, ?, ?, ?, ?)",
-		client, subject, newVersion, id, schema).Exec()
+	return newVersion, nil
 }
 
 func (cs *CassandraStorage) SetGlobalConfig(client string, level string) error {
@@ -191,10 +308,268 @@ func (cs *CassandraStorage) SetSubjectConfig(client string, subject string, leve
 	return cs.connection.Query("INSERT INTO avro.configs (client, global, subject, level) VALUES (?, false, ?, ?)", client, subject, level).Exec()
 }
 
+func (cs *CassandraStorage) SetSetting(client string, subject string, name string, value string) error {
+	return cs.connection.Query("INSERT INTO avro.settings (client, subject, name, value) VALUES (?, ?, ?, ?)", client, subject, name, value).Exec()
+}
+
 func (cs *CassandraStorage) AddUser(name string, token string, admin bool) error {
+	return cs.connection.Query("INSERT INTO avro.users (token, name, admin) VALUES (?, ?, ?)", token, name, admin).Exec()
+}
+
+// implement Dumper interface
+func (cs *CassandraStorage) Dump() (*Snapshot, error) {
+	snapshot := NewSnapshot()
+	var client, subject, schema, level, name, token string
+	var version int
+	var id int64
+	var global, admin bool
+
+	iter := cs.connection.Query("SELECT client, subject, version, id, avro_schema FROM avro.schemas").Iter()
+	for iter.Scan(&client, &subject, &version, &id, &schema) {
+		state := snapshot.Client(client)
+		state.Schemas[id] = schema
+		if _, ok := state.Subjects[subject]; !ok {
+			state.Subjects[subject] = make(Versions)
+		}
+		state.Subjects[subject][version] = id
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+
+	iter = cs.connection.Query("SELECT client, global, subject, level FROM avro.configs").Iter()
+	for iter.Scan(&client, &global, &subject, &level) {
+		if global {
+			snapshot.Client(client).GlobalConfig = level
+		} else {
+			snapshot.Client(client).SubjectConfigs[subject] = level
+		}
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+
+	var value string
+	iter = cs.connection.Query("SELECT client, subject, name, value FROM avro.settings").Iter()
+	for iter.Scan(&client, &subject, &name, &value) {
+		snapshot.Client(client).Settings[SettingKey{subject, name}] = value
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+
+	iter = cs.connection.Query("SELECT token, name, admin FROM avro.users").Iter()
+	for iter.Scan(&token, &name, &admin) {
+		snapshot.Users[token] = &User{Name: name, Token: token, Admin: admin}
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// DumpClients returns every client with schemas, configs or settings in order.
+func (cs *CassandraStorage) DumpClients() ([]string, error) {
+	found := make(map[string]bool)
+	var client string
+	for _, table := range []string{"avro.schemas", "avro.configs", "avro.settings"} {
+		iter := cs.connection.Query("SELECT DISTINCT client FROM " + table).Iter()
+		for iter.Scan(&client) {
+			found[client] = true
+		}
+		if err := iter.Close(); err != nil {
+			return nil, err
+		}
+	}
+	clients := make([]string, 0, len(found))
+	for client := range found {
+		clients = append(clients, client)
+	}
+	sort.Strings(clients)
+	return clients, nil
+}
+
+func (cs *CassandraStorage) DumpClient(client string) (*ClientSnapshot, error) {
+	state := NewSnapshot().Client(client)
+	var subject, schema, level, name, value string
+	var version int
+	var id int64
+	var global bool
+
+	iter := cs.connection.Query("SELECT subject, version, id, avro_schema FROM avro.schemas WHERE client = ?", client).Iter()
+	for iter.Scan(&subject, &version, &id, &schema) {
+		state.Schemas[id] = schema
+		if _, ok := state.Subjects[subject]; !ok {
+			state.Subjects[subject] = make(Versions)
+		}
+		state.Subjects[subject][version] = id
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+
+	iter = cs.connection.Query("SELECT global, subject, level FROM avro.configs WHERE client = ?", client).Iter()
+	for iter.Scan(&global, &subject, &level) {
+		if global {
+			state.GlobalConfig = level
+		} else {
+			state.SubjectConfigs[subject] = level
+		}
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+
+	iter = cs.connection.Query("SELECT subject, name, value FROM avro.settings WHERE client = ?", client).Iter()
+	for iter.Scan(&subject, &name, &value) {
+		state.Settings[SettingKey{subject, name}] = value
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// Load replaces schemas, configs, settings and users with the snapshot, keeping its ids and versions.
+// Applied offsets are left as is, the caller should commit the offsets the snapshot was taken at.
+func (cs *CassandraStorage) Load(snapshot *Snapshot) error {
+	err := cs.truncate("avro.schemas", "avro.schema_ids", "avro.configs", "avro.settings", "avro.users")
+	if err != nil {
+		return err
+	}
+
+	for client, state := range snapshot.Clients {
+		for subject, versions := range state.Subjects {
+			for version, id := range versions {
+				err := cs.connection.Query("INSERT INTO avro.schemas (client, subject, version, id, avro_schema) VALUES (?, ?, ?, ?, ?)",
+					client, subject, version, id, state.Schemas[id]).Exec()
+				if err != nil {
+					return err
+				}
+				err = cs.connection.Query("INSERT INTO avro.schema_ids (client, id, subject, version) VALUES (?, ?, ?, ?)",
+					client, id, subject, version).Exec()
+				if err != nil {
+					return err
+				}
+			}
+		}
+		if state.GlobalConfig != "" {
+			err := cs.SetGlobalConfig(client, state.GlobalConfig)
+			if err != nil {
+				return err
+			}
+		}
+		for subject, level := range state.SubjectConfigs {
+			err := cs.SetSubjectConfig(client, subject, level)
+			if err != nil {
+				return err
+			}
+		}
+		for key, value := range state.Settings {
+			err := cs.SetSetting(client, key.Subject, key.Name, value)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for _, user := range snapshot.Users {
+		err := cs.AddUser(user.Name, user.Token, user.Admin)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// implement Checkpointer interface
+func (cs *CassandraStorage) AppliedOffset(topic string, partition int32) (int64, error) {
+	var offset int64
+	err := cs.connection.Query("SELECT applied_offset FROM avro.offsets WHERE topic = ? AND partition = ?",
+		topic, partition).Consistency(gocql.Quorum).Scan(&offset)
+	if err == gocql.ErrNotFound {
+		return -1, nil
+	}
+	if err != nil {
+		return -1, err
+	}
+	return offset, nil
+}
+
+func (cs *CassandraStorage) CommitOffset(topic string, partition int32, offset int64) error {
+	return cs.connection.Query("INSERT INTO avro.offsets (topic, partition, applied_offset) VALUES (?, ?, ?)",
+		topic, partition, offset).Consistency(gocql.Quorum).Exec()
+}
+
+func (cs *CassandraStorage) SkipRecord(skipped *SkippedRecord) error {
+	return cs.connection.Query(`INSERT INTO avro.skipped (topic, partition, skipped_offset, record_type, client, subject, reason)
+VALUES (?, ?, ?, ?, ?, ?, ?)`, skipped.Topic, skipped.Partition, skipped.Offset, string(skipped.Type), skipped.Client,
+		skipped.Subject, skipped.Reason).Consistency(gocql.Quorum).Exec()
+}
+
+func (cs *CassandraStorage) SkippedRecords() ([]*SkippedRecord, error) {
+	iter := cs.connection.Query("SELECT topic, partition, skipped_offset, record_type, client, subject, reason FROM avro.skipped").
+		Consistency(gocql.Quorum).Iter()
+	skipped := make([]*SkippedRecord, 0)
+	var (
+		topic, recordType, client, subject, reason string
+		partition                                  int32
+		offset                                     int64
+	)
+	for iter.Scan(&topic, &partition, &offset, &recordType, &client, &subject, &reason) {
+		skipped = append(skipped, &SkippedRecord{Topic: topic, Partition: partition, Offset: offset, Type: MessageType(recordType),
+			Client: client, Subject: subject, Reason: reason})
+	}
+	return skipped, iter.Close()
+}
+
+func (cs *CassandraStorage) ForgetSkipped(topic string, partition int32, offset int64) error {
+	iter := cs.connection.Query("SELECT skipped_offset FROM avro.skipped WHERE topic = ? AND partition = ? AND skipped_offset <= ?",
+		topic, partition, offset).Consistency(gocql.Quorum).Iter()
+	offsets := make([]int64, 0)
+	var skippedOffset int64
+	for iter.Scan(&skippedOffset) {
+		offsets = append(offsets, skippedOffset)
+	}
+	err := iter.Close()
+	if err != nil {
+		return err
+	}
+	for _, skippedOffset := range offsets {
+		err = cs.connection.Query("DELETE FROM avro.skipped WHERE topic = ? AND partition = ? AND skipped_offset = ?",
+			topic, partition, skippedOffset).Consistency(gocql.Quorum).Exec()
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// Reset removes all schemas, configs, settings, users, applied offsets and skipped records,
+// so the next log follower starts from the beginning of the log.
+func (cs *CassandraStorage) Reset() error {
+	return cs.truncate("avro.schemas", "avro.schema_ids", "avro.configs", "avro.settings", "avro.users", "avro.offsets", "avro.skipped")
+}
+
+func (cs *CassandraStorage) truncate(tables ...string) error {
+	for _, table := range tables {
+		err := cs.connection.Query("TRUNCATE " + table).Exec()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cs *CassandraStorage) clientExists(client string) error {
+	var storedClient *string
+	err := cs.connection.Query("SELECT client FROM avro.schemas WHERE client = ? LIMIT 1",
+		client).Consistency(gocql.One).Scan(&storedClient)
+	if err == gocql.ErrNotFound {
+		return clientNotFoundError(client)
+	}
+	return err
+}
+
 func initStorage(session *gocql.Session) error {
 	createKeyspace := `
 CREATE KEYSPACE IF NOT EXISTS avro WITH REPLICATION = {'class': 'SimpleStrategy', 'replication_factor': 1};
@@ -207,6 +582,14 @@ CREATE KEYSPACE IF NOT EXISTS avro WITH REPLICATION = {'class': 'SimpleStrategy'
   avro_schema text,
   PRIMARY KEY (client, subject, version),
 );
+`
+	createSchemaIDs := `CREATE TABLE IF NOT EXISTS avro.schema_ids (
+  client varchar,
+  id int,
+  subject varchar,
+  version int,
+  PRIMARY KEY (client, id),
+);
 `
 	createConfigs := `CREATE TABLE IF NOT EXISTS avro.configs (
   client varchar,
@@ -216,6 +599,39 @@ CREATE KEYSPACE IF NOT EXISTS avro WITH REPLICATION = {'class': 'SimpleStrategy'
   PRIMARY KEY (client, global, subject),
 );
 	`
+	createSettings := `CREATE TABLE IF NOT EXISTS avro.settings (
+  client varchar,
+  subject varchar,
+  name varchar,
+  value text,
+  PRIMARY KEY (client, subject, name),
+);
+`
+	createUsers := `CREATE TABLE IF NOT EXISTS avro.users (
+  token varchar,
+  name varchar,
+  admin boolean,
+  PRIMARY KEY (token),
+);
+`
+	createOffsets := `CREATE TABLE IF NOT EXISTS avro.offsets (
+  topic varchar,
+  partition int,
+  applied_offset bigint,
+  PRIMARY KEY (topic, partition),
+);
+`
+	createSkipped := `CREATE TABLE IF NOT EXISTS avro.skipped (
+  topic varchar,
+  partition int,
+  skipped_offset bigint,
+  record_type varchar,
+  client varchar,
+  subject varchar,
+  reason text,
+  PRIMARY KEY ((topic, partition), skipped_offset),
+);
+`
 	err := session.Query(createKeyspace).Exec()
 	if err != nil {
 		return err
@@ -224,5 +640,25 @@ CREATE KEYSPACE IF NOT EXISTS avro WITH REPLICATION = {'class': 'SimpleStrategy'
 	if err != nil {
 		return err
 	}
-	return session.Query(createConfigs).Exec()
+	err = session.Query(createSchemaIDs).Exec()
+	if err != nil {
+		return err
+	}
+	err = session.Query(createConfigs).Exec()
+	if err != nil {
+		return err
+	}
+	err = session.Query(createSettings).Exec()
+	if err != nil {
+		return err
+	}
+	err = session.Query(createUsers).Exec()
+	if err != nil {
+		return err
+	}
+	err = session.Query(createOffsets).Exec()
+	if err != nil {
+		return err
+	}
+	return session.Query(createSkipped).Exec()
 }
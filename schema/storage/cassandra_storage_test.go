@@ -6,13 +6,20 @@ import "testing"
 
 func prepare() *CassandraStorage {
 	store := NewCassandraStorage("cassandra", 3, "3.0.0")
-	err := store.connection.Query("TRUNCATE avro.schemas").Exec()
-	if err != nil {
-		panic(err)
+	for _, table := range []string{"avro.schemas", "avro.schema_ids", "avro.configs", "avro.users", "avro.offsets", "avro.skipped"} {
+		err := store.connection.Query("TRUNCATE " + table).Exec()
+		if err != nil {
+			panic(err)
+		}
 	}
 	return store
 }
 
+// PrepareCassandraStorage exposes prepare to the conformance tests in package storage_test.
+func PrepareCassandraStorage() *CassandraStorage {
+	return prepare()
+}
+
 func TestNewCassandraStorage(t *testing.T) {
 	store := NewCassandraStorage("cassandra", 3, "3.0.0")
 	if store == nil {
@@ -230,3 +237,81 @@ func TestCassandraGetLatestSchema(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestCassandraAddSchemaConcurrently(t *testing.T) {
+	store := prepare()
+	done := make(chan error)
+	for i := 0; i < 4; i++ {
+		go func() {
+			done <- store.AddSchema("snow", "testsubject", 5, testSchema)
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	}
+	versions, _, err := store.GetVersions("snow", "testsubject")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 1 {
+		t.Errorf("Expected one version for one log record, got %v", versions)
+	}
+}
+
+func TestCassandraTwoStoresAddSameID(t *testing.T) {
+	first := prepare()
+	second := NewCassandraStorage("cassandra", 3, "3.0.0")
+	done := make(chan error)
+	for _, store := range []*CassandraStorage{first, second, first, second} {
+		go func(store *CassandraStorage) {
+			done <- store.AddSchema("snow", "testsubject", 7, testSchema)
+		}(store)
+	}
+	// another record of the same subject competes for the same version
+	go func() {
+		done <- second.AddSchema("snow", "testsubject", 8, testSchema)
+	}()
+	for i := 0; i < 5; i++ {
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	}
+
+	versions, _, err := first.GetVersions("snow", "testsubject")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 {
+		t.Errorf("Expected one version per id no matter how many stores applied it, got %v", versions)
+	}
+}
+
+func TestCassandraSkippedRecords(t *testing.T) {
+	store := prepare()
+	for offset := int64(3); offset <= 4; offset++ {
+		err := store.SkipRecord(&SkippedRecord{Topic: "snow", Offset: offset, Type: MessageSchema, Client: "snow", Reason: "rejected"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	skipped, err := store.SkippedRecords()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(skipped) != 2 || skipped[0].Offset != 3 || skipped[0].Type != MessageSchema {
+		t.Fatalf("Expected both skipped records back, got %v", skipped)
+	}
+
+	if err := store.ForgetSkipped("snow", 0, 3); err != nil {
+		t.Fatal(err)
+	}
+	skipped, err = store.SkippedRecords()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(skipped) != 1 || skipped[0].Offset != 4 {
+		t.Errorf("Expected only the record after the reset offset to be kept, got %v", skipped)
+	}
+}
@@ -0,0 +1,76 @@
+package storage_test
+
+import (
+	"testing"
+
+	"github.com/goavro/wednesday/schema/storage"
+	"github.com/goavro/wednesday/schema/storage/storagetest"
+)
+
+func TestInMemoryStorageConformance(t *testing.T) {
+	storagetest.Run(t, func() storage.StorageStater {
+		return storage.NewInMemoryStorage()
+	})
+}
+
+func TestCachedStorageConformance(t *testing.T) {
+	storagetest.Run(t, func() storage.StorageStater {
+		cache := storage.NewInMemoryStorage()
+		backend := storage.NewInMemoryStorage()
+		return &storage.CachedStorage{
+			StorageWriter:      &storage.MockStorageWriter{},
+			StorageStateWriter: stateWriters{cache, backend},
+			Cache:              cache,
+			Backend:            backend,
+		}
+	})
+}
+
+// stateWriters applies every write to all of its writers, the way the consumer and the
+// multiwriter keep the cache and the backend of a CachedStorage in sync.
+type stateWriters []storage.StorageStateWriter
+
+func (sw stateWriters) AddSchema(client string, subject string, id int64, schema string) error {
+	for _, writer := range sw {
+		if err := writer.AddSchema(client, subject, id, schema); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sw stateWriters) SetGlobalConfig(client string, level string) error {
+	for _, writer := range sw {
+		if err := writer.SetGlobalConfig(client, level); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sw stateWriters) SetSubjectConfig(client string, subject string, level string) error {
+	for _, writer := range sw {
+		if err := writer.SetSubjectConfig(client, subject, level); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sw stateWriters) SetSetting(client string, subject string, name string, value string) error {
+	for _, writer := range sw {
+		if err := writer.SetSetting(client, subject, name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sw stateWriters) AddUser(name string, token string, admin bool) error {
+	for _, writer := range sw {
+		if err := writer.AddUser(name, token, admin); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 )
 
@@ -15,6 +16,7 @@ type InMemoryStorage struct {
 	subjects     map[string]ClientSubjects
 	configs      map[string]SubjectConfigs
 	globalConfig map[string]string
+	settings     map[string]Settings
 	users        map[string]*User
 
 	empty bool
@@ -27,6 +29,7 @@ func NewInMemoryStorage() *InMemoryStorage {
 		subjects:     make(map[string]ClientSubjects),
 		configs:      make(map[string]SubjectConfigs),
 		globalConfig: make(map[string]string),
+		settings:     make(map[string]Settings),
 		users:        make(map[string]*User),
 		empty:        true,
 		mutex:        &sync.RWMutex{},
@@ -35,6 +38,8 @@ func NewInMemoryStorage() *InMemoryStorage {
 }
 
 func (ims *InMemoryStorage) Empty() bool {
+	ims.mutex.RLock()
+	defer ims.mutex.RUnlock()
 	return ims.empty
 }
 
@@ -120,6 +125,7 @@ func (ims *InMemoryStorage) GetVersions(client string, subject string) ([]int, b
 			for version, _ := range clientVersions {
 				versions = append(versions, version)
 			}
+			sort.Ints(versions)
 			return versions, true, nil
 		}
 		return nil, false, nil
@@ -194,6 +200,25 @@ func (ims *InMemoryStorage) GetSubjectConfig(client string, subject string) (str
 	return "", false, clientNotFoundError(client)
 }
 
+func (ims *InMemoryStorage) GetSetting(client string, subject string, name string) (string, bool, error) {
+	ims.mutex.RLock()
+	defer ims.mutex.RUnlock()
+	value, found := ims.settings[client][SettingKey{subject, name}]
+	return value, found, nil
+}
+
+func (ims *InMemoryStorage) GetSettings(client string, subject string) (map[string]string, error) {
+	ims.mutex.RLock()
+	defer ims.mutex.RUnlock()
+	settings := make(map[string]string)
+	for key, value := range ims.settings[client] {
+		if key.Subject == subject {
+			settings[key.Name] = value
+		}
+	}
+	return settings, nil
+}
+
 func (ims *InMemoryStorage) AddSchema(client string, subject string, id int64, schema string) error {
 	ims.mutex.Lock()
 	defer ims.mutex.Unlock()
@@ -241,6 +266,138 @@ func (ims *InMemoryStorage) SetSubjectConfig(client string, subject string, leve
 	return nil
 }
 
+func (ims *InMemoryStorage) SetSetting(client string, subject string, name string, value string) error {
+	ims.mutex.Lock()
+	defer ims.mutex.Unlock()
+	if _, ok := ims.settings[client]; !ok {
+		ims.settings[client] = make(Settings)
+	}
+	ims.settings[client][SettingKey{subject, name}] = value
+	return nil
+}
+
+// implement Dumper interface
+func (ims *InMemoryStorage) Dump() (*Snapshot, error) {
+	ims.mutex.RLock()
+	defer ims.mutex.RUnlock()
+	snapshot := NewSnapshot()
+	for _, client := range ims.clients() {
+		snapshot.Clients[client] = ims.dumpClient(client)
+	}
+	for token, user := range ims.users {
+		copied := *user
+		snapshot.Users[token] = &copied
+	}
+	return snapshot, nil
+}
+
+func (ims *InMemoryStorage) DumpClients() ([]string, error) {
+	ims.mutex.RLock()
+	defer ims.mutex.RUnlock()
+	return ims.clients(), nil
+}
+
+func (ims *InMemoryStorage) DumpClient(client string) (*ClientSnapshot, error) {
+	ims.mutex.RLock()
+	defer ims.mutex.RUnlock()
+	return ims.dumpClient(client), nil
+}
+
+// clients returns every client with schemas, configs or settings in order.
+func (ims *InMemoryStorage) clients() []string {
+	found := make(map[string]bool)
+	for client := range ims.schemas {
+		found[client] = true
+	}
+	for client := range ims.subjects {
+		found[client] = true
+	}
+	for client := range ims.globalConfig {
+		found[client] = true
+	}
+	for client := range ims.configs {
+		found[client] = true
+	}
+	for client := range ims.settings {
+		found[client] = true
+	}
+	clients := make([]string, 0, len(found))
+	for client := range found {
+		clients = append(clients, client)
+	}
+	sort.Strings(clients)
+	return clients
+}
+
+func (ims *InMemoryStorage) dumpClient(client string) *ClientSnapshot {
+	state := NewSnapshot().Client(client)
+	for id, schema := range ims.schemas[client] {
+		state.Schemas[id] = schema
+	}
+	for subject, versions := range ims.subjects[client] {
+		copied := make(Versions)
+		for version, id := range versions {
+			copied[version] = id
+		}
+		state.Subjects[subject] = copied
+	}
+	state.GlobalConfig = ims.globalConfig[client]
+	for subject, level := range ims.configs[client] {
+		state.SubjectConfigs[subject] = level
+	}
+	for key, value := range ims.settings[client] {
+		state.Settings[key] = value
+	}
+	return state
+}
+
+func (ims *InMemoryStorage) Load(snapshot *Snapshot) error {
+	loaded := NewInMemoryStorage()
+	for client, state := range snapshot.Clients {
+		loaded.schemas[client] = make(ClientSchemas)
+		for id, schema := range state.Schemas {
+			loaded.schemas[client][id] = schema
+		}
+		loaded.subjects[client] = make(ClientSubjects)
+		for subject, versions := range state.Subjects {
+			loaded.subjects[client][subject] = make(Versions)
+			for version, id := range versions {
+				loaded.subjects[client][subject][version] = id
+			}
+		}
+		if state.GlobalConfig != "" {
+			loaded.globalConfig[client] = state.GlobalConfig
+		}
+		if len(state.SubjectConfigs) > 0 {
+			loaded.configs[client] = make(SubjectConfigs)
+			for subject, level := range state.SubjectConfigs {
+				loaded.configs[client][subject] = level
+			}
+		}
+		if len(state.Settings) > 0 {
+			loaded.settings[client] = make(Settings)
+			for key, value := range state.Settings {
+				loaded.settings[client][key] = value
+			}
+		}
+	}
+	for token, user := range snapshot.Users {
+		copied := *user
+		loaded.users[token] = &copied
+	}
+
+	ims.mutex.Lock()
+	defer ims.mutex.Unlock()
+	ims.schemas = loaded.schemas
+	ims.subjects = loaded.subjects
+	ims.globalConfig = loaded.globalConfig
+	ims.configs = loaded.configs
+	ims.settings = loaded.settings
+	ims.users = loaded.users
+	ims.empty = len(loaded.users) == 0
+	return nil
+}
+
 func latestVersion(versions Versions) (int64, int) {
 	var schemaId int64
 	maxVersion := -1
@@ -16,6 +16,9 @@ const (
 	MessageGlobalConfig              = "global-config"
 	MessageSubjectConfig             = "subject-config"
 	MessageCreateUser                = "create-user"
+	MessageSetting                   = "setting"
+	// MessageSkip only takes up an offset, so an imported schema can keep its original id
+	MessageSkip = "skip"
 )
 
 type Sender interface {
@@ -72,6 +75,17 @@ func (ks *KafkaStorage) UpdateSubjectConfig(client string, subject string, confi
 	return err
 }
 
+func (ks *KafkaStorage) UpdateSetting(client string, subject string, name string, value string) error {
+	content := map[string]string{
+		"client":  client,
+		"subject": subject,
+		"name":    name,
+		"value":   value,
+	}
+	_, err := ks.send(MessageSetting, content)
+	return err
+}
+
 func (ks *KafkaStorage) CreateUser(name string, token string, admin bool) (string, error) {
 	content := map[string]string{
 		"client": "admin",
@@ -84,12 +98,17 @@ func (ks *KafkaStorage) CreateUser(name string, token string, admin bool) (strin
 }
 
 func (ks *KafkaStorage) send(messageType MessageType, content map[string]string) (*producer.RecordMetadata, error) {
+	return SendMessage(ks.producer, messageType, content)
+}
+
+// SendMessage writes a message to the log and waits until it is acknowledged.
+func SendMessage(sender Sender, messageType MessageType, content map[string]string) (*producer.RecordMetadata, error) {
 	log.Info("Sending to Kafka")
 	record, err := NewMessageRecord(messageType, content)
 	if err != nil {
 		return nil, err
 	}
-	metadata := <-ks.producer.Send(record)
+	metadata := <-sender.Send(record)
 	log.Infof("METADATA: %v", *metadata)
 	if metadata.Error != siesta.ErrNoError {
 		return nil, metadata.Error
@@ -0,0 +1,220 @@
+package storage
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/yanzay/log"
+)
+
+const (
+	followerRetries = 5
+	followerBackoff = time.Second
+)
+
+// LogRecord is a single mutation read from the schema log.
+type LogRecord struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Type      MessageType
+	Content   map[string]string
+}
+
+// Checkpointer durably remembers the offset of the last log record applied to a store,
+// and the records skipped before it until the store is rewritten from the log.
+type Checkpointer interface {
+	// AppliedOffset returns the last applied offset or -1 if nothing was applied yet.
+	AppliedOffset(topic string, partition int32) (int64, error)
+	CommitOffset(topic string, partition int32, offset int64) error
+	SkipRecord(skipped *SkippedRecord) error
+	SkippedRecords() ([]*SkippedRecord, error)
+	// ForgetSkipped removes the records skipped at or below the given offset.
+	ForgetSkipped(topic string, partition int32, offset int64) error
+}
+
+// SkippedRecord is a log record a follower gave up applying to its store.
+type SkippedRecord struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Type      MessageType
+	Client    string
+	Subject   string
+	Reason    string
+}
+
+// ApplyRecord applies a log record to the given state writer.
+// Every record type is idempotent, so applying the same record twice is harmless.
+func ApplyRecord(writer StorageStateWriter, record *LogRecord) error {
+	content := record.Content
+	switch record.Type {
+	case MessageSchema:
+		return writer.AddSchema(content["client"], content["subject"], record.Offset+1, content["schema"])
+	case MessageGlobalConfig:
+		return writer.SetGlobalConfig(content["client"], content["compatibility"])
+	case MessageSubjectConfig:
+		return writer.SetSubjectConfig(content["client"], content["subject"], content["compatibility"])
+	case MessageSetting:
+		return writer.SetSetting(content["client"], content["subject"], content["name"], content["value"])
+	case MessageCreateUser:
+		return writer.AddUser(content["name"], content["token"], content["admin"] == "true")
+	case MessageSkip:
+		return nil
+	}
+	return fmt.Errorf("Unexpected message type %s", record.Type)
+}
+
+// LogFollower keeps a derived store in sync with the schema log.
+// Records are applied in log order with retries, and the offset of every applied record is checkpointed,
+// so a follower restarted after a failure picks up right where it stopped. A record the store keeps failing
+// to apply while its checkpoints can be read is skipped, stored with the checkpoints and reported by SkippedRecords
+// until the store is repaired, even by a follower started after it.
+type LogFollower struct {
+	target      StorageStateWriter
+	checkpoints Checkpointer
+	retries     int
+	backoff     time.Duration
+
+	mutex   *sync.Mutex
+	applied map[string]int64
+	changed chan struct{}
+}
+
+func NewLogFollower(target StorageStateWriter, checkpoints Checkpointer) *LogFollower {
+	return &LogFollower{
+		target:      target,
+		checkpoints: checkpoints,
+		retries:     followerRetries,
+		backoff:     followerBackoff,
+		mutex:       &sync.Mutex{},
+		applied:     make(map[string]int64),
+		changed:     make(chan struct{}),
+	}
+}
+
+// Apply applies a record unless it is already behind the checkpoint.
+// An error means the record was not applied and neither should the records after it be,
+// the caller is expected to retry from the same offset once the store is reachable again.
+func (lf *LogFollower) Apply(record *LogRecord) error {
+	applied, err := lf.AppliedOffset(record.Topic, record.Partition)
+	if err != nil {
+		return err
+	}
+	if record.Offset <= applied {
+		return nil
+	}
+
+	for attempt := 0; ; attempt++ {
+		err = ApplyRecord(lf.target, record)
+		if err == nil {
+			break
+		}
+		if attempt >= lf.retries {
+			if _, unreachable := lf.checkpoints.AppliedOffset(record.Topic, record.Partition); unreachable != nil {
+				return err
+			}
+			err = lf.skip(record, err)
+			if err != nil {
+				return err
+			}
+			break
+		}
+		log.Warningf("[LogFollower] Can't apply %s/%d at offset %d, retrying: %s", record.Topic, record.Partition, record.Offset, err)
+		time.Sleep(lf.backoff)
+	}
+
+	err = lf.checkpoints.CommitOffset(record.Topic, record.Partition, record.Offset)
+	if err != nil {
+		return err
+	}
+	lf.setApplied(record.Topic, record.Partition, record.Offset)
+	return nil
+}
+
+// Reset checkpoints the given offset after the target was rewritten up to it, so the follower goes on after it.
+func (lf *LogFollower) Reset(topic string, partition int32, offset int64) error {
+	err := lf.checkpoints.CommitOffset(topic, partition, offset)
+	if err != nil {
+		return err
+	}
+	lf.setApplied(topic, partition, offset)
+	return lf.checkpoints.ForgetSkipped(topic, partition, offset)
+}
+
+// AppliedOffset returns the last applied offset for a topic and partition, or -1 if nothing was applied yet.
+func (lf *LogFollower) AppliedOffset(topic string, partition int32) (int64, error) {
+	lf.mutex.Lock()
+	defer lf.mutex.Unlock()
+	key := checkpointKey(topic, partition)
+	if offset, ok := lf.applied[key]; ok {
+		return offset, nil
+	}
+	offset, err := lf.checkpoints.AppliedOffset(topic, partition)
+	if err != nil {
+		return -1, err
+	}
+	lf.applied[key] = offset
+	return offset, nil
+}
+
+// WaitApplied blocks until the record at the given offset is applied or the timeout expires.
+// Returns true if the record was applied in time.
+func (lf *LogFollower) WaitApplied(topic string, partition int32, offset int64, timeout time.Duration) bool {
+	deadline := time.After(timeout)
+	for {
+		lf.mutex.Lock()
+		applied, ok := lf.applied[checkpointKey(topic, partition)]
+		changed := lf.changed
+		lf.mutex.Unlock()
+		if ok && applied >= offset {
+			return true
+		}
+
+		select {
+		case <-changed:
+		case <-deadline:
+			return false
+		}
+	}
+}
+
+func (lf *LogFollower) setApplied(topic string, partition int32, offset int64) {
+	lf.mutex.Lock()
+	defer lf.mutex.Unlock()
+	lf.applied[checkpointKey(topic, partition)] = offset
+	close(lf.changed)
+	lf.changed = make(chan struct{})
+}
+
+// skip gives up on a record the store is reachable for but can't apply, so the records after it don't wait for good.
+// The record is stored before its offset is committed, so it can't be lost to a restart in between.
+func (lf *LogFollower) skip(record *LogRecord, err error) error {
+	log.Errorf("[LogFollower] Skipping %s/%d at offset %d after %d attempts, the store needs a repair: %s",
+		record.Topic, record.Partition, record.Offset, lf.retries+1, err)
+	return lf.checkpoints.SkipRecord(&SkippedRecord{Topic: record.Topic, Partition: record.Partition, Offset: record.Offset,
+		Type: record.Type, Client: record.Content["client"], Subject: record.Content["subject"], Reason: err.Error()})
+}
+
+// SkippedRecords reports every record skipped since the store was last rewritten from the log.
+func (lf *LogFollower) SkippedRecords(store string) ([]*Inconsistency, error) {
+	skipped, err := lf.checkpoints.SkippedRecords()
+	if err != nil {
+		return nil, err
+	}
+	inconsistencies := make([]*Inconsistency, 0, len(skipped))
+	for _, record := range skipped {
+		inconsistency := &Inconsistency{Store: store, Kind: InconsistencySkipped, Client: record.Client, Subject: record.Subject,
+			Message: fmt.Sprintf("Record %s at offset %d of topic %s was not applied: %s", record.Type, record.Offset, record.Topic, record.Reason)}
+		if record.Type == MessageSchema {
+			inconsistency.ID = record.Offset + 1
+		}
+		inconsistencies = append(inconsistencies, inconsistency)
+	}
+	return inconsistencies, nil
+}
+
+func checkpointKey(topic string, partition int32) string {
+	return fmt.Sprintf("%s/%d", topic, partition)
+}
@@ -0,0 +1,297 @@
+package storage
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type memoryCheckpoints struct {
+	offsets map[string]int64
+	skipped []*SkippedRecord
+}
+
+func newMemoryCheckpoints() *memoryCheckpoints {
+	return &memoryCheckpoints{offsets: make(map[string]int64)}
+}
+
+func (mc *memoryCheckpoints) AppliedOffset(topic string, partition int32) (int64, error) {
+	if offset, ok := mc.offsets[checkpointKey(topic, partition)]; ok {
+		return offset, nil
+	}
+	return -1, nil
+}
+
+func (mc *memoryCheckpoints) CommitOffset(topic string, partition int32, offset int64) error {
+	mc.offsets[checkpointKey(topic, partition)] = offset
+	return nil
+}
+
+func (mc *memoryCheckpoints) SkipRecord(skipped *SkippedRecord) error {
+	mc.skipped = append(mc.skipped, skipped)
+	return nil
+}
+
+func (mc *memoryCheckpoints) SkippedRecords() ([]*SkippedRecord, error) {
+	return mc.skipped, nil
+}
+
+func (mc *memoryCheckpoints) ForgetSkipped(topic string, partition int32, offset int64) error {
+	kept := make([]*SkippedRecord, 0, len(mc.skipped))
+	for _, skipped := range mc.skipped {
+		if skipped.Topic != topic || skipped.Partition != partition || skipped.Offset > offset {
+			kept = append(kept, skipped)
+		}
+	}
+	mc.skipped = kept
+	return nil
+}
+
+// flakyStateWriter fails the given number of writes before passing them to the in-memory storage.
+type flakyStateWriter struct {
+	*InMemoryStorage
+	failures int
+}
+
+func (fw *flakyStateWriter) AddSchema(client string, subject string, id int64, schema string) error {
+	if fw.failures > 0 {
+		fw.failures--
+		return errors.New("backend unavailable")
+	}
+	return fw.InMemoryStorage.AddSchema(client, subject, id, schema)
+}
+
+// outageCheckpoints can't be read while down, as the store they are kept in.
+type outageCheckpoints struct {
+	*memoryCheckpoints
+	down bool
+}
+
+func (oc *outageCheckpoints) AppliedOffset(topic string, partition int32) (int64, error) {
+	if oc.down {
+		return -1, errors.New("backend unavailable")
+	}
+	return oc.memoryCheckpoints.AppliedOffset(topic, partition)
+}
+
+func newTestFollower(target StorageStateWriter, checkpoints Checkpointer) *LogFollower {
+	follower := NewLogFollower(target, checkpoints)
+	follower.retries = 2
+	follower.backoff = time.Millisecond
+	return follower
+}
+
+func schemaRecord(offset int64, schema string) *LogRecord {
+	return &LogRecord{
+		Topic:   client,
+		Offset:  offset,
+		Type:    MessageSchema,
+		Content: map[string]string{"client": client, "subject": subject, "schema": schema},
+	}
+}
+
+func TestApplyRecord(t *testing.T) {
+	store := NewInMemoryStorage()
+	records := []*LogRecord{
+		schemaRecord(4, testSchema),
+		{Type: MessageGlobalConfig, Content: map[string]string{"client": client, "compatibility": CompatibilityFull}},
+		{Type: MessageSubjectConfig, Content: map[string]string{"client": client, "subject": subject, "compatibility": CompatibilityNone}},
+		{Type: MessageCreateUser, Content: map[string]string{"name": "admin", "token": "secret", "admin": "true"}},
+		{Type: MessageSkip, Content: map[string]string{"client": client}},
+		{Type: MessageSetting, Content: map[string]string{"client": client, "subject": "", "name": SettingValidation, "value": "lenient"}},
+	}
+	for _, record := range records {
+		if err := ApplyRecord(store, record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if schema, _, _ := store.GetSchemaByID(client, 5); schema != testSchema {
+		t.Errorf("Expected schema with id offset + 1, got %q", schema)
+	}
+	if level, _ := store.GetGlobalConfig(client); level != CompatibilityFull {
+		t.Errorf("Expected global config %s, got %s", CompatibilityFull, level)
+	}
+	if level, _, _ := store.GetSubjectConfig(client, subject); level != CompatibilityNone {
+		t.Errorf("Expected subject config %s, got %s", CompatibilityNone, level)
+	}
+	if value, found, _ := store.GetSetting(client, "", SettingValidation); !found || value != "lenient" {
+		t.Errorf("Expected validation setting lenient, got %q (found %t)", value, found)
+	}
+	if user, found := store.UserByToken("secret"); !found || !user.Admin {
+		t.Errorf("Expected admin user, got %v", user)
+	}
+	if err := ApplyRecord(store, &LogRecord{Type: "unknown"}); err == nil {
+		t.Error("Expected error for unknown record type")
+	}
+}
+
+func TestLogFollowerRetries(t *testing.T) {
+	target := &flakyStateWriter{InMemoryStorage: NewInMemoryStorage(), failures: 2}
+	checkpoints := newMemoryCheckpoints()
+	follower := newTestFollower(target, checkpoints)
+
+	if err := follower.Apply(schemaRecord(0, testSchema)); err != nil {
+		t.Fatal(err)
+	}
+	if _, found, _ := target.GetSchemaByID(client, 1); !found {
+		t.Error("Expected schema to be applied after retries")
+	}
+	if offset, _ := checkpoints.AppliedOffset(client, 0); offset != 0 {
+		t.Errorf("Expected checkpoint 0, got %d", offset)
+	}
+}
+
+func TestLogFollowerFailureKeepsCheckpoint(t *testing.T) {
+	target := &flakyStateWriter{InMemoryStorage: NewInMemoryStorage()}
+	checkpoints := &outageCheckpoints{memoryCheckpoints: newMemoryCheckpoints()}
+	follower := newTestFollower(target, checkpoints)
+	if err := follower.Apply(schemaRecord(0, testSchema)); err != nil {
+		t.Fatal(err)
+	}
+
+	target.failures, checkpoints.down = 3, true
+	if err := follower.Apply(schemaRecord(1, anotherSchema)); err == nil {
+		t.Fatal("Expected error after retries are exhausted")
+	}
+	if offset, _ := checkpoints.memoryCheckpoints.AppliedOffset(client, 0); offset != 0 {
+		t.Errorf("Checkpoint should not move on failure, got %d", offset)
+	}
+
+	// the caller retries from the failed offset once the backend is back
+	checkpoints.down = false
+	if err := follower.Apply(schemaRecord(1, anotherSchema)); err != nil {
+		t.Fatal(err)
+	}
+	if err := follower.Apply(schemaRecord(2, `{"type": "int"}`)); err != nil {
+		t.Fatal(err)
+	}
+	schema, _, _ := target.GetLatestSchema(client, subject)
+	if schema.Version != 3 || schema.ID != 3 {
+		t.Errorf("Expected records to be applied in log order, got %v", schema)
+	}
+	if skipped, _ := follower.SkippedRecords("test"); len(skipped) != 0 {
+		t.Errorf("Nothing should be skipped while the backend is down, got %v", skipped)
+	}
+}
+
+func TestLogFollowerSkipsRecordItCantApply(t *testing.T) {
+	target := NewInMemoryStorage()
+	checkpoints := newMemoryCheckpoints()
+	follower := newTestFollower(target, checkpoints)
+
+	if err := follower.Apply(&LogRecord{Topic: client, Offset: 0, Type: "UNKNOWN", Content: map[string]string{"client": client}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := follower.Apply(schemaRecord(1, testSchema)); err != nil {
+		t.Fatal(err)
+	}
+	if offset, _ := checkpoints.AppliedOffset(client, 0); offset != 1 {
+		t.Errorf("Expected the follower to go on after the skipped record, got checkpoint %d", offset)
+	}
+	skipped, err := follower.SkippedRecords("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(skipped) != 1 || skipped[0].Kind != InconsistencySkipped || skipped[0].Client != client {
+		t.Fatalf("Expected the skipped record to be reported, got %v", skipped)
+	}
+
+	if err := follower.Reset(client, 0, 1); err != nil {
+		t.Fatal(err)
+	}
+	if skipped, _ := follower.SkippedRecords("test"); len(skipped) != 0 {
+		t.Errorf("A rewritten store has no skipped records, got %v", skipped)
+	}
+}
+
+func TestLogFollowerSkipSurvivesRestart(t *testing.T) {
+	target := NewInMemoryStorage()
+	checkpoints := newMemoryCheckpoints()
+	follower := newTestFollower(target, checkpoints)
+	if err := follower.Apply(&LogRecord{Topic: client, Offset: 0, Type: "UNKNOWN", Content: map[string]string{"client": client}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// a follower started over the same checkpoints must not lose the skipped record along with the first one
+	restarted := newTestFollower(target, checkpoints)
+	if err := restarted.Apply(schemaRecord(1, testSchema)); err != nil {
+		t.Fatal(err)
+	}
+	skipped, err := restarted.SkippedRecords("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(skipped) != 1 || !strings.Contains(skipped[0].Message, "offset 0") {
+		t.Fatalf("Expected the record skipped before the restart to be reported, got %v", skipped)
+	}
+
+	if err := restarted.Reset(client, 0, 1); err != nil {
+		t.Fatal(err)
+	}
+	if skipped, _ := newTestFollower(target, checkpoints).SkippedRecords("test"); len(skipped) != 0 {
+		t.Errorf("A rewritten store has no skipped records after a restart either, got %v", skipped)
+	}
+}
+
+func TestLogFollowerResumesFromCheckpoint(t *testing.T) {
+	target := NewInMemoryStorage()
+	checkpoints := newMemoryCheckpoints()
+	checkpoints.CommitOffset(client, 0, 1)
+	follower := newTestFollower(target, checkpoints)
+
+	for offset, schema := range []string{testSchema, anotherSchema, `{"type": "int"}`} {
+		if err := follower.Apply(schemaRecord(int64(offset), schema)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	versions, _, _ := target.GetVersions(client, subject)
+	if len(versions) != 1 {
+		t.Errorf("Expected only the record after the checkpoint to be applied, got versions %v", versions)
+	}
+	if offset, _ := follower.AppliedOffset(client, 0); offset != 2 {
+		t.Errorf("Expected applied offset 2, got %d", offset)
+	}
+}
+
+func TestLogFollowerWaitApplied(t *testing.T) {
+	follower := newTestFollower(NewInMemoryStorage(), newMemoryCheckpoints())
+
+	if follower.WaitApplied(client, 0, 0, 10*time.Millisecond) {
+		t.Error("Nothing is applied yet")
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		follower.Apply(schemaRecord(0, testSchema))
+	}()
+	if !follower.WaitApplied(client, 0, 0, time.Second) {
+		t.Error("Expected record to be applied")
+	}
+}
+
+func TestLogFollowerReset(t *testing.T) {
+	target := NewInMemoryStorage()
+	checkpoints := newMemoryCheckpoints()
+	follower := newTestFollower(target, checkpoints)
+	if err := follower.Apply(schemaRecord(0, testSchema)); err != nil {
+		t.Fatal(err)
+	}
+
+	// the target was rewritten up to offset 1, so the cached offset must not send record 1 through again
+	if err := follower.Reset(client, 0, 1); err != nil {
+		t.Fatal(err)
+	}
+	if offset, _ := checkpoints.AppliedOffset(client, 0); offset != 1 {
+		t.Errorf("Expected checkpoint 1, got %d", offset)
+	}
+	if err := follower.Apply(schemaRecord(1, anotherSchema)); err != nil {
+		t.Fatal(err)
+	}
+	versions, _, _ := target.GetVersions(client, subject)
+	if len(versions) != 1 {
+		t.Errorf("Expected the record before the reset offset to be skipped, got versions %v", versions)
+	}
+}
@@ -14,6 +14,10 @@ func (*MockStorageWriter) UpdateSubjectConfig(string, string, CompatibilityConfi
 	return nil
 }
 
+func (*MockStorageWriter) UpdateSetting(string, string, string, string) error {
+	return nil
+}
+
 func (*MockStorageWriter) CreateUser(string, string, bool) (string, error) {
 	return "", nil
 }
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yanzay/log"
+)
+
+// Reader is the reader schema a consumer registered for a subject. It is active until it expires,
+// consumers keep it active by registering it again before that.
+type Reader struct {
+	Schema  string    `json:"schema"`
+	Expires time.Time `json:"expires"`
+}
+
+// ParseReader decodes the reader setting of a consumer, an empty setting is a deleted reader.
+func ParseReader(value string) (*Reader, error) {
+	if value == "" {
+		return nil, nil
+	}
+	reader := new(Reader)
+	err := json.Unmarshal([]byte(value), reader)
+	if err != nil {
+		return nil, err
+	}
+	return reader, nil
+}
+
+func (r *Reader) String() string {
+	value, _ := json.Marshal(r)
+	return string(value)
+}
+
+// ReaderSetting is the name of the setting a consumer's reader schema of a subject is kept in.
+// Every consumer has its own, so registrations of different consumers don't overwrite each other.
+func ReaderSetting(consumer string) string {
+	return SettingReaders + "/" + consumer
+}
+
+// Readers are the reader schemas of a subject by consumer.
+type Readers map[string]*Reader
+
+// ParseReaders decodes the reader settings among the settings of a subject.
+// A setting that doesn't decode is logged and left out, so it doesn't hide the readers of the other consumers.
+func ParseReaders(settings map[string]string) Readers {
+	readers := make(Readers)
+	prefix := ReaderSetting("")
+	for name, value := range settings {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		reader, err := ParseReader(value)
+		if err != nil {
+			log.Warningf("Skipping invalid reader setting %s: %s", name, err)
+			continue
+		}
+		if reader != nil {
+			readers[strings.TrimPrefix(name, prefix)] = reader
+		}
+	}
+	return readers
+}
+
+// Active returns the readers that haven't expired at the given time.
+func (r Readers) Active(now time.Time) Readers {
+	active := make(Readers)
+	for consumer, reader := range r {
+		if reader.Expires.After(now) {
+			active[consumer] = reader
+		}
+	}
+	return active
+}
+
+// SortedConsumers returns the names of the consumers in order.
+func (r Readers) SortedConsumers() []string {
+	consumers := make([]string, 0, len(r))
+	for consumer := range r {
+		consumers = append(consumers, consumer)
+	}
+	sort.Strings(consumers)
+	return consumers
+}
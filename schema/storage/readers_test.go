@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReaders(t *testing.T) {
+	now := time.Date(2016, 10, 1, 12, 0, 0, 0, time.UTC)
+	settings := map[string]string{
+		ReaderSetting("billing"):   (&Reader{Schema: testSchema, Expires: now.Add(time.Minute)}).String(),
+		ReaderSetting("analytics"): (&Reader{Schema: anotherSchema, Expires: now.Add(-time.Minute)}).String(),
+		ReaderSetting("audit"):     (&Reader{Schema: testSchema, Expires: now.Add(time.Hour)}).String(),
+		ReaderSetting("stopped"):   "",
+		ReaderSetting("broken"):    "{",
+		SettingRules:               `{"rules":[]}`,
+	}
+
+	// the setting that doesn't decode is left out, the others are still checked
+	parsed := ParseReaders(settings)
+	if len(parsed) != 3 || parsed["billing"].Schema != testSchema || !parsed["billing"].Expires.Equal(now.Add(time.Minute)) {
+		t.Errorf("Readers did not survive encoding: %v", parsed)
+	}
+
+	active := parsed.Active(now)
+	consumers := active.SortedConsumers()
+	if len(consumers) != 2 || consumers[0] != "audit" || consumers[1] != "billing" {
+		t.Errorf("Expected audit and billing to be active, got %v", consumers)
+	}
+	if len(parsed.Active(now.Add(time.Hour))) != 0 {
+		t.Error("Every reader should have expired")
+	}
+
+	if empty := ParseReaders(map[string]string{}); len(empty) != 0 {
+		t.Errorf("No settings should have no readers, got %v", empty)
+	}
+	if _, err := ParseReader("{"); err == nil {
+		t.Error("Invalid setting should not parse")
+	}
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Schema types. Avro schemas are stored as they are, schemas of other types are stored as a JSON document
+// holding the type and the schema, so stores and the log don't need to know about types and the same text
+// registered as two types gets two ids.
+const (
+	SchemaTypeAvro = "AVRO"
+	SchemaTypeJSON = "JSON"
+)
+
+// typedSchemaPrefix starts every stored schema that isn't Avro, an Avro schema never has a schemaType
+// without a type.
+const typedSchemaPrefix = `{"schemaType":`
+
+type typedSchema struct {
+	SchemaType string `json:"schemaType"`
+	Schema     string `json:"schema"`
+}
+
+// EncodeSchema returns the form a schema of a type is stored in.
+func EncodeSchema(schemaType string, schema string) string {
+	if schemaType == "" || schemaType == SchemaTypeAvro {
+		return schema
+	}
+	stored, _ := json.Marshal(&typedSchema{schemaType, schema})
+	return string(stored)
+}
+
+// DecodeSchema returns the type of a stored schema and the schema itself.
+func DecodeSchema(stored string) (string, string) {
+	if !strings.HasPrefix(stored, typedSchemaPrefix) {
+		return SchemaTypeAvro, stored
+	}
+	var typed typedSchema
+	if err := json.Unmarshal([]byte(stored), &typed); err != nil || typed.SchemaType == "" {
+		return SchemaTypeAvro, stored
+	}
+	return typed.SchemaType, typed.Schema
+}
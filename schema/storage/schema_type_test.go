@@ -0,0 +1,23 @@
+package storage
+
+import "testing"
+
+func TestEncodeSchema(t *testing.T) {
+	if stored := EncodeSchema(SchemaTypeAvro, testSchema); stored != testSchema {
+		t.Errorf("Avro schema stored as %s", stored)
+	}
+	schemaType, schema := DecodeSchema(testSchema)
+	if schemaType != SchemaTypeAvro || schema != testSchema {
+		t.Errorf("Avro schema decoded as %s %s", schemaType, schema)
+	}
+
+	jsonSchema := `{"type": "object", "properties": {"id": {"type": "integer"}}}`
+	stored := EncodeSchema(SchemaTypeJSON, jsonSchema)
+	if stored == jsonSchema {
+		t.Error("JSON Schema stored without its type")
+	}
+	schemaType, schema = DecodeSchema(stored)
+	if schemaType != SchemaTypeJSON || schema != jsonSchema {
+		t.Errorf("JSON Schema decoded as %s %s", schemaType, schema)
+	}
+}
@@ -0,0 +1,301 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+const (
+	InconsistencyVersion = "VERSION_MISMATCH"
+	InconsistencyOrphan  = "ORPHANED_ID"
+	InconsistencySchema  = "SCHEMA_MISMATCH"
+	InconsistencyConfig  = "CONFIG_MISMATCH"
+	InconsistencyUser    = "USER_MISMATCH"
+	// InconsistencySkipped is a record a log follower gave up applying
+	InconsistencySkipped = "SKIPPED_RECORD"
+)
+
+// Snapshot is a complete copy of the registry state: every client's schemas, subjects, configs and settings and all users.
+type Snapshot struct {
+	Clients map[string]*ClientSnapshot
+	Users   map[string]*User
+}
+
+type ClientSnapshot struct {
+	Schemas        ClientSchemas
+	Subjects       ClientSubjects
+	GlobalConfig   string
+	SubjectConfigs SubjectConfigs
+	Settings       Settings
+}
+
+func NewSnapshot() *Snapshot {
+	return &Snapshot{
+		Clients: make(map[string]*ClientSnapshot),
+		Users:   make(map[string]*User),
+	}
+}
+
+// Client returns the snapshot of the given client, creating an empty one if needed.
+func (s *Snapshot) Client(client string) *ClientSnapshot {
+	if _, ok := s.Clients[client]; !ok {
+		s.Clients[client] = &ClientSnapshot{
+			Schemas:        make(ClientSchemas),
+			Subjects:       make(ClientSubjects),
+			SubjectConfigs: make(SubjectConfigs),
+			Settings:       make(Settings),
+		}
+	}
+	return s.Clients[client]
+}
+
+// SortedClients returns client names in a stable order.
+func (s *Snapshot) SortedClients() []string {
+	clients := make([]string, 0, len(s.Clients))
+	for client := range s.Clients {
+		clients = append(clients, client)
+	}
+	sort.Strings(clients)
+	return clients
+}
+
+// Dumper is implemented by storages that can copy their whole state into a Snapshot and replace it with one.
+// DumpClient copies the state of a single client, so exports don't have to hold every client in memory at once.
+type Dumper interface {
+	Dump() (*Snapshot, error)
+	Load(*Snapshot) error
+	DumpClients() ([]string, error)
+	DumpClient(client string) (*ClientSnapshot, error)
+}
+
+type Inconsistency struct {
+	Store   string `json:"store"`
+	Kind    string `json:"kind"`
+	Client  string `json:"client,omitempty"`
+	Subject string `json:"subject,omitempty"`
+	Version int    `json:"version,omitempty"`
+	ID      int64  `json:"id,omitempty"`
+	Message string `json:"message"`
+}
+
+// ErrRepairWhileRunning is returned for a repair that would rewrite Cassandra from a running node:
+// the other nodes sharing the keyspace keep applying the log, and their writes would be lost.
+var ErrRepairWhileRunning = errors.New("Repairing Cassandra needs every node of the cluster stopped, use fsck --repair")
+
+type ConsistencyReport struct {
+	Inconsistencies []*Inconsistency `json:"inconsistencies"`
+	Repaired        bool             `json:"repaired"`
+}
+
+func (cr *ConsistencyReport) Consistent() bool {
+	return len(cr.Inconsistencies) == 0
+}
+
+// CheckSnapshot reports subject versions that point to missing schema ids and schema ids no subject refers to.
+func CheckSnapshot(store string, snapshot *Snapshot) []*Inconsistency {
+	inconsistencies := make([]*Inconsistency, 0)
+	for _, client := range snapshot.SortedClients() {
+		state := snapshot.Clients[client]
+		referenced := make(map[int64]bool)
+		for _, subject := range sortedSubjects(state.Subjects) {
+			for _, version := range sortedVersions(state.Subjects[subject]) {
+				id := state.Subjects[subject][version]
+				referenced[id] = true
+				if _, ok := state.Schemas[id]; !ok {
+					inconsistencies = append(inconsistencies, &Inconsistency{store, InconsistencyOrphan, client, subject, version, id,
+						fmt.Sprintf("Version %d of subject %s points to missing schema id %d", version, subject, id)})
+				}
+			}
+		}
+		for _, id := range sortedIDs(state.Schemas) {
+			if !referenced[id] {
+				inconsistencies = append(inconsistencies, &Inconsistency{store, InconsistencyOrphan, client, "", 0, id,
+					fmt.Sprintf("Schema id %d is not used by any subject", id)})
+			}
+		}
+	}
+	return inconsistencies
+}
+
+// CompareSnapshots reports every difference of actual from expected, plus the orphaned ids in actual.
+func CompareSnapshots(store string, expected *Snapshot, actual *Snapshot) []*Inconsistency {
+	inconsistencies := CheckSnapshot(store, actual)
+	report := func(kind string, client string, subject string, version int, id int64, format string, args ...interface{}) {
+		inconsistencies = append(inconsistencies, &Inconsistency{store, kind, client, subject, version, id, fmt.Sprintf(format, args...)})
+	}
+
+	clients := NewSnapshot()
+	for client := range expected.Clients {
+		clients.Client(client)
+	}
+	for client := range actual.Clients {
+		clients.Client(client)
+	}
+	for _, client := range clients.SortedClients() {
+		want := expected.Client(client)
+		got := actual.Client(client)
+
+		for _, id := range sortedIDs(want.Schemas) {
+			schema, ok := got.Schemas[id]
+			if !ok {
+				report(InconsistencySchema, client, "", 0, id, "Schema id %d is missing", id)
+			} else if schema != want.Schemas[id] {
+				report(InconsistencySchema, client, "", 0, id, "Schema id %d has different content", id)
+			}
+		}
+		for _, id := range sortedIDs(got.Schemas) {
+			if _, ok := want.Schemas[id]; !ok {
+				report(InconsistencySchema, client, "", 0, id, "Schema id %d is not in the log", id)
+			}
+		}
+
+		subjects := make(ClientSubjects)
+		for subject := range want.Subjects {
+			subjects[subject] = nil
+		}
+		for subject := range got.Subjects {
+			subjects[subject] = nil
+		}
+		for _, subject := range sortedSubjects(subjects) {
+			versions := make(Versions)
+			for version := range want.Subjects[subject] {
+				versions[version] = 0
+			}
+			for version := range got.Subjects[subject] {
+				versions[version] = 0
+			}
+			for _, version := range sortedVersions(versions) {
+				wantID, wanted := want.Subjects[subject][version]
+				gotID, found := got.Subjects[subject][version]
+				switch {
+				case !found:
+					report(InconsistencyVersion, client, subject, version, wantID, "Version %d of subject %s is missing", version, subject)
+				case !wanted:
+					report(InconsistencyVersion, client, subject, version, gotID, "Version %d of subject %s is not in the log", version, subject)
+				case wantID != gotID:
+					report(InconsistencyVersion, client, subject, version, gotID,
+						"Version %d of subject %s points to schema id %d instead of %d", version, subject, gotID, wantID)
+				}
+			}
+		}
+
+		if want.GlobalConfig != got.GlobalConfig {
+			report(InconsistencyConfig, client, "", 0, 0, "Global config is %q instead of %q", got.GlobalConfig, want.GlobalConfig)
+		}
+		configs := make(SubjectConfigs)
+		for subject := range want.SubjectConfigs {
+			configs[subject] = ""
+		}
+		for subject := range got.SubjectConfigs {
+			configs[subject] = ""
+		}
+		for _, subject := range sortedConfigs(configs) {
+			wantLevel, wanted := want.SubjectConfigs[subject]
+			gotLevel, found := got.SubjectConfigs[subject]
+			switch {
+			case !found:
+				report(InconsistencyConfig, client, subject, 0, 0, "Config for subject %s is missing", subject)
+			case !wanted:
+				report(InconsistencyConfig, client, subject, 0, 0, "Config for subject %s is not in the log", subject)
+			case wantLevel != gotLevel:
+				report(InconsistencyConfig, client, subject, 0, 0, "Config for subject %s is %q instead of %q", subject, gotLevel, wantLevel)
+			}
+		}
+		settings := make(Settings)
+		for key := range want.Settings {
+			settings[key] = ""
+		}
+		for key := range got.Settings {
+			settings[key] = ""
+		}
+		for _, key := range sortedSettings(settings) {
+			wantValue, wanted := want.Settings[key]
+			gotValue, found := got.Settings[key]
+			switch {
+			case !found:
+				report(InconsistencyConfig, client, key.Subject, 0, 0, "Setting %s is missing", key)
+			case !wanted:
+				report(InconsistencyConfig, client, key.Subject, 0, 0, "Setting %s is not in the log", key)
+			case wantValue != gotValue:
+				report(InconsistencyConfig, client, key.Subject, 0, 0, "Setting %s is %q instead of %q", key, gotValue, wantValue)
+			}
+		}
+	}
+
+	for token, user := range expected.Users {
+		if other, ok := actual.Users[token]; !ok || *other != *user {
+			report(InconsistencyUser, "", "", 0, 0, "User %s is missing or different", user.Name)
+		}
+	}
+	for token, user := range actual.Users {
+		if _, ok := expected.Users[token]; !ok {
+			report(InconsistencyUser, "", "", 0, 0, "User %s is not in the log", user.Name)
+		}
+	}
+
+	return inconsistencies
+}
+
+func sortedSubjects(subjects ClientSubjects) []string {
+	names := make([]string, 0, len(subjects))
+	for subject := range subjects {
+		names = append(names, subject)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedConfigs(configs SubjectConfigs) []string {
+	names := make([]string, 0, len(configs))
+	for subject := range configs {
+		names = append(names, subject)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sortedSettings orders the client settings first, then the settings of every subject.
+func sortedSettings(settings Settings) []SettingKey {
+	keys := make([]SettingKey, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Sort(settingKeys(keys))
+	return keys
+}
+
+func sortedVersions(versions Versions) []int {
+	sorted := make([]int, 0, len(versions))
+	for version := range versions {
+		sorted = append(sorted, version)
+	}
+	sort.Ints(sorted)
+	return sorted
+}
+
+func sortedIDs(schemas ClientSchemas) []int64 {
+	ids := make([]int64, 0, len(schemas))
+	for id := range schemas {
+		ids = append(ids, id)
+	}
+	sort.Sort(int64Slice(ids))
+	return ids
+}
+
+type settingKeys []SettingKey
+
+func (s settingKeys) Len() int      { return len(s) }
+func (s settingKeys) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s settingKeys) Less(i, j int) bool {
+	if s[i].Subject != s[j].Subject {
+		return s[i].Subject < s[j].Subject
+	}
+	return s[i].Name < s[j].Name
+}
+
+type int64Slice []int64
+
+func (s int64Slice) Len() int           { return len(s) }
+func (s int64Slice) Less(i, j int) bool { return s[i] < s[j] }
+func (s int64Slice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
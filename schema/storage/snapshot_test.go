@@ -0,0 +1,112 @@
+package storage
+
+import "testing"
+
+func snapshotStore() *InMemoryStorage {
+	store := NewInMemoryStorage()
+	store.AddSchema(client, subject, 1, testSchema)
+	store.AddSchema(client, subject, 2, anotherSchema)
+	store.SetGlobalConfig(client, CompatibilityFull)
+	store.SetSubjectConfig(client, subject, CompatibilityNone)
+	store.SetSetting(client, subject, SettingValidation, "lenient")
+	store.AddUser("admin", "token", true)
+	return store
+}
+
+func kinds(inconsistencies []*Inconsistency) map[string]int {
+	counts := make(map[string]int)
+	for _, inconsistency := range inconsistencies {
+		counts[inconsistency.Kind]++
+	}
+	return counts
+}
+
+func TestDumpLoad(t *testing.T) {
+	snapshot, err := snapshotStore().Dump()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := NewInMemoryStorage()
+	store.AddSchema("other", subject, 7, testSchema)
+	err = store.Load(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.GetSubjects("other"); err == nil {
+		t.Error("Load should replace the existing state")
+	}
+	latest, found, err := store.GetLatestSchema(client, subject)
+	if err != nil || !found || latest.ID != 2 || latest.Version != 2 {
+		t.Errorf("Expected loaded latest schema id 2 version 2, got %v (%v)", latest, err)
+	}
+	if store.Empty() {
+		t.Error("Loaded users should make storage non-empty")
+	}
+
+	reloaded, _ := store.Dump()
+	if inconsistencies := CompareSnapshots("memory", snapshot, reloaded); len(inconsistencies) != 0 {
+		t.Errorf("Expected identical snapshots, got %v", inconsistencies)
+	}
+}
+
+func TestDumpClient(t *testing.T) {
+	store := snapshotStore()
+	store.SetGlobalConfig("configured", CompatibilityBackward)
+	expected, _ := store.Dump()
+
+	clients, err := store.DumpClients()
+	if err != nil || len(clients) != 2 || clients[0] != "configured" || clients[1] != client {
+		t.Fatalf("Expected clients configured and %s, got %v (%v)", client, clients, err)
+	}
+	actual := NewSnapshot()
+	for _, name := range clients {
+		actual.Clients[name], err = store.DumpClient(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	actual.Users = expected.Users
+	if inconsistencies := CompareSnapshots("memory", expected, actual); len(inconsistencies) != 0 {
+		t.Errorf("Expected the clients to add up to the whole dump, got %v", inconsistencies)
+	}
+}
+
+func TestCompareSnapshots(t *testing.T) {
+	expected, _ := snapshotStore().Dump()
+	actual, _ := snapshotStore().Dump()
+
+	state := actual.Client(client)
+	state.Subjects[subject][2] = 3
+	delete(state.SubjectConfigs, subject)
+	state.GlobalConfig = CompatibilityBackward
+	delete(actual.Users, "token")
+
+	counts := kinds(CompareSnapshots("cassandra", expected, actual))
+	if counts[InconsistencyVersion] != 1 {
+		t.Errorf("Expected 1 version mismatch, got %d", counts[InconsistencyVersion])
+	}
+	// version 2 points to a missing id 3, and id 2 is not used anymore
+	if counts[InconsistencyOrphan] != 2 {
+		t.Errorf("Expected 2 orphaned ids, got %d", counts[InconsistencyOrphan])
+	}
+	if counts[InconsistencyConfig] != 2 {
+		t.Errorf("Expected 2 config mismatches, got %d", counts[InconsistencyConfig])
+	}
+	if counts[InconsistencyUser] != 1 {
+		t.Errorf("Expected 1 user mismatch, got %d", counts[InconsistencyUser])
+	}
+}
+
+func TestCompareSnapshotsExtraState(t *testing.T) {
+	expected, _ := snapshotStore().Dump()
+	store := snapshotStore()
+	store.AddSchema(client, "extra", 9, testSchema)
+	actual, _ := store.Dump()
+
+	inconsistencies := CompareSnapshots("memory", expected, actual)
+	counts := kinds(inconsistencies)
+	if counts[InconsistencyVersion] != 1 || counts[InconsistencySchema] != 1 {
+		t.Errorf("Expected the extra version and schema to be reported, got %v", inconsistencies)
+	}
+}
@@ -5,19 +5,55 @@ type Schema struct {
 	ID      int64  `json:"id"`
 	Version int    `json:"version"`
 	Schema  string `json:"schema"`
+	// SchemaType is left empty by stores, Schema holds the stored form of the schema, see DecodeSchema
+	SchemaType string `json:"schemaType,omitempty"`
 }
 
 const (
-	CompatibilityNone     = "NONE"
-	CompatibilityFull     = "FULL"
-	CompatibilityForward  = "FORWARD"
-	CompatibilityBackward = "BACKWARD"
+	CompatibilityNone      = "NONE"
+	CompatibilityFull      = "FULL"
+	CompatibilityForward   = "FORWARD"
+	CompatibilityBackward  = "BACKWARD"
+	CompatibilityKeyStable = "KEY_STABLE"
 )
 
 type CompatibilityConfig struct {
 	Compatibility string `json:"compatibility"`
 }
 
+// Validation levels. Strict validation rejects schemas the Avro specification doesn't allow although go-avro parses them,
+// lenient validation accepts everything go-avro parses.
+const (
+	ValidationStrict  = "STRICT"
+	ValidationLenient = "LENIENT"
+)
+
+type ValidationConfig struct {
+	Validation string `json:"validation"`
+}
+
+// Settings hold the options of a client other than compatibility. A setting with an empty subject applies to the whole client.
+const (
+	SettingValidation = "validation"
+	SettingRules      = "rules"
+	// SettingReaders is followed by a slash and the consumer, every consumer of a subject has a setting of its own
+	SettingReaders = "readers"
+)
+
+type SettingKey struct {
+	Subject string
+	Name    string
+}
+
+func (sk SettingKey) String() string {
+	if sk.Subject == "" {
+		return sk.Name
+	}
+	return sk.Name + " of subject " + sk.Subject
+}
+
+type Settings map[SettingKey]string
+
 type Storage interface {
 	StorageStateReader
 	StorageStateWriter
@@ -35,6 +71,7 @@ type StorageWriter interface {
 
 	UpdateGlobalConfig(string, CompatibilityConfig) error
 	UpdateSubjectConfig(string, string, CompatibilityConfig) error
+	UpdateSetting(client string, subject string, name string, value string) error
 
 	CreateUser(string, string, bool) (string, error)
 }
@@ -57,6 +94,8 @@ type StorageStateReader interface {
 
 	GetGlobalConfig(string) (string, error)
 	GetSubjectConfig(string, string) (string, bool, error)
+	GetSetting(client string, subject string, name string) (string, bool, error)
+	GetSettings(client string, subject string) (map[string]string, error)
 
 	UserByName(string) (*User, bool)
 	UserByToken(string) (*User, bool)
@@ -66,6 +105,7 @@ type StorageStateWriter interface {
 	AddSchema(string, string, int64, string) error
 	SetGlobalConfig(string, string) error
 	SetSubjectConfig(string, string, string) error
+	SetSetting(client string, subject string, name string, value string) error
 	AddUser(string, string, bool) error
 }
 
@@ -1,40 +1,53 @@
 package storage
 
+import (
+	"time"
+
+	"github.com/yanzay/log"
+)
+
+const applyTimeout = 5 * time.Second
+
+// StorageMultiwriter writes to the schema log only. The log is authoritative: the derived store
+// (Cassandra) is populated by a LogFollower, so both stores see the same records in the same order
+// and converge even if a write to the derived store fails.
 type StorageMultiwriter struct {
-	kafkaWriter     StorageWriter
-	cassandraWriter StorageStateWriter
+	kafkaWriter  StorageWriter
+	follower     *LogFollower
+	applyTimeout time.Duration
 }
 
-func NewStorageMultiwriter(kafkaStorage StorageWriter, cassandraStorage StorageStateWriter) *StorageMultiwriter {
+func NewStorageMultiwriter(kafkaStorage StorageWriter, follower *LogFollower) *StorageMultiwriter {
 	return &StorageMultiwriter{
-		kafkaWriter:     kafkaStorage,
-		cassandraWriter: cassandraStorage,
+		kafkaWriter:  kafkaStorage,
+		follower:     follower,
+		applyTimeout: applyTimeout,
 	}
 }
 
+// StoreSchema succeeds as soon as the schema is in the log. It waits for the follower to apply
+// the schema so reads from the derived store see it, but a slow follower doesn't fail the write.
 func (sm *StorageMultiwriter) StoreSchema(client string, subject string, schema string) (int64, error) {
 	id, err := sm.kafkaWriter.StoreSchema(client, subject, schema)
 	if err != nil {
 		return -1, err
 	}
-	err = sm.cassandraWriter.AddSchema(client, subject, id, schema)
-	return id, err
+	if !sm.follower.WaitApplied(client, 0, id-1, sm.applyTimeout) {
+		log.Warningf("Schema %d for subject %s is not applied to the backend yet, it will be applied from the log", id, subject)
+	}
+	return id, nil
 }
 
 func (sm *StorageMultiwriter) UpdateGlobalConfig(client string, config CompatibilityConfig) error {
-	err := sm.kafkaWriter.UpdateGlobalConfig(client, config)
-	if err != nil {
-		return err
-	}
-	return sm.cassandraWriter.SetGlobalConfig(client, config.Compatibility)
+	return sm.kafkaWriter.UpdateGlobalConfig(client, config)
 }
 
 func (sm *StorageMultiwriter) UpdateSubjectConfig(client string, subject string, config CompatibilityConfig) error {
-	err := sm.kafkaWriter.UpdateSubjectConfig(client, subject, config)
-	if err != nil {
-		return err
-	}
-	return sm.cassandraWriter.SetSubjectConfig(client, subject, config.Compatibility)
+	return sm.kafkaWriter.UpdateSubjectConfig(client, subject, config)
+}
+
+func (sm *StorageMultiwriter) UpdateSetting(client string, subject string, name string, value string) error {
+	return sm.kafkaWriter.UpdateSetting(client, subject, name, value)
 }
 
 func (sm *StorageMultiwriter) CreateUser(name string, token string, admin bool) (string, error) {
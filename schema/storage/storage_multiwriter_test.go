@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeLogWriter returns the next offset as a schema id and feeds written schemas to a follower.
+type fakeLogWriter struct {
+	MockStorageWriter
+	offset   int64
+	err      error
+	follower *LogFollower
+}
+
+func (fw *fakeLogWriter) StoreSchema(client string, subject string, schema string) (int64, error) {
+	if fw.err != nil {
+		return -1, fw.err
+	}
+	record := schemaRecord(fw.offset, schema)
+	fw.offset++
+	if fw.follower != nil {
+		go fw.follower.Apply(record)
+	}
+	return record.Offset + 1, nil
+}
+
+func (fw *fakeLogWriter) UpdateGlobalConfig(string, CompatibilityConfig) error {
+	return fw.err
+}
+
+func (fw *fakeLogWriter) UpdateSubjectConfig(string, string, CompatibilityConfig) error {
+	return fw.err
+}
+
+func TestStorageMultiwriterStoreSchema(t *testing.T) {
+	state := NewInMemoryStorage()
+	follower := newTestFollower(state, newMemoryCheckpoints())
+	writer := NewStorageMultiwriter(&fakeLogWriter{offset: 41, follower: follower}, follower)
+
+	id, err := writer.StoreSchema(client, subject, testSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 42 {
+		t.Errorf("Expected id 42 from the log writer, got %d", id)
+	}
+	schema, found, err := state.GetSchemaByID(client, id)
+	if err != nil || !found || schema != testSchema {
+		t.Errorf("Schema should be applied by the follower before StoreSchema returns: %s, %t, %v", schema, found, err)
+	}
+}
+
+func TestStorageMultiwriterSlowFollower(t *testing.T) {
+	state := NewInMemoryStorage()
+	follower := newTestFollower(state, newMemoryCheckpoints())
+	writer := NewStorageMultiwriter(&fakeLogWriter{}, follower)
+	writer.applyTimeout = 10 * time.Millisecond
+
+	id, err := writer.StoreSchema(client, subject, testSchema)
+	if err != nil {
+		t.Errorf("The log is authoritative, a lagging follower should not fail the write: %s", err)
+	}
+	if id != 1 {
+		t.Errorf("Expected id 1, got %d", id)
+	}
+}
+
+func TestStorageMultiwriterLogFailure(t *testing.T) {
+	state := NewInMemoryStorage()
+	follower := newTestFollower(state, newMemoryCheckpoints())
+	writer := NewStorageMultiwriter(&fakeLogWriter{err: errors.New("broker down"), follower: follower}, follower)
+
+	if _, err := writer.StoreSchema(client, subject, testSchema); err == nil {
+		t.Error("Expected log error")
+	}
+	if err := writer.UpdateGlobalConfig(client, CompatibilityConfig{Compatibility: CompatibilityFull}); err == nil {
+		t.Error("Expected log error")
+	}
+	if err := writer.UpdateSubjectConfig(client, subject, CompatibilityConfig{Compatibility: CompatibilityFull}); err == nil {
+		t.Error("Expected log error")
+	}
+	if _, err := state.GetSubjects(client); err == nil {
+		t.Error("Nothing should be written when the log write fails")
+	}
+}
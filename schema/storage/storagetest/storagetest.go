@@ -0,0 +1,344 @@
+// Package storagetest provides a conformance suite that every storage.StorageStater implementation should pass.
+//
+// A backend's test file only has to hand the suite a way to get an empty store:
+//
+//	func TestConformance(t *testing.T) {
+//		storagetest.Run(t, func() storage.StorageStater {
+//			return storage.NewInMemoryStorage()
+//		})
+//	}
+//
+// Storage has no delete operations yet, so the suite doesn't cover them.
+package storagetest
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/goavro/wednesday/schema/storage"
+)
+
+// Factory returns a new empty storage. It is called once per test case.
+type Factory func() storage.StorageStater
+
+type testCase struct {
+	name string
+	run  func(*testing.T, storage.StorageStater)
+}
+
+var testCases = []testCase{
+	{"VersionNumbering", testVersionNumbering},
+	{"Dedup", testDedup},
+	{"LatestSchema", testLatestSchema},
+	{"SchemaByID", testSchemaByID},
+	{"MissingEntries", testMissingEntries},
+	{"GlobalConfig", testGlobalConfig},
+	{"SubjectConfig", testSubjectConfig},
+	{"Settings", testSettings},
+	{"Users", testUsers},
+	{"ConcurrentAddSchema", testConcurrentAddSchema},
+}
+
+const (
+	client  = "conformance"
+	subject = "subject"
+)
+
+var schemas = []string{
+	`{"type": "string"}`,
+	`{"type": "int"}`,
+	`{"type": "long"}`,
+}
+
+// Run runs every conformance test case against fresh storages produced by factory.
+func Run(t *testing.T, factory Factory) {
+	for _, tc := range testCases {
+		t.Logf("storagetest: running %s", tc.name)
+		tc.run(t, factory())
+	}
+}
+
+func testVersionNumbering(t *testing.T, store storage.StorageStater) {
+	for i, schema := range schemas {
+		mustAddSchema(t, store, subject, int64(10*(i+1)), schema)
+	}
+	mustAddSchema(t, store, "other", 40, schemas[0])
+
+	versions, found, err := store.GetVersions(client, subject)
+	if err != nil || !found {
+		t.Fatalf("GetVersions: found %t, error %v", found, err)
+	}
+	assertVersions(t, versions, 1, 2, 3)
+
+	for i, expected := range schemas {
+		schema, found, err := store.GetSchema(client, subject, i+1)
+		if err != nil || !found {
+			t.Errorf("GetSchema version %d: found %t, error %v", i+1, found, err)
+			continue
+		}
+		if schema != expected {
+			t.Errorf("GetSchema version %d: expected %s, got %s", i+1, expected, schema)
+		}
+	}
+
+	versions, _, err = store.GetVersions(client, "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertVersions(t, versions, 1)
+}
+
+func testDedup(t *testing.T, store storage.StorageStater) {
+	mustAddSchema(t, store, subject, 1, schemas[0])
+	mustAddSchema(t, store, subject, 1, schemas[0])
+	mustAddSchema(t, store, subject, 2, schemas[1])
+
+	versions, _, err := store.GetVersions(client, subject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertVersions(t, versions, 1, 2)
+
+	mustAddSchema(t, store, "other", 3, schemas[2])
+	subjects, err := store.GetSubjects(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(subjects)
+	if fmt.Sprint(subjects) != fmt.Sprint([]string{"other", subject}) {
+		t.Errorf("GetSubjects: expected every subject exactly once, got %v", subjects)
+	}
+}
+
+func testLatestSchema(t *testing.T, store storage.StorageStater) {
+	mustAddSchema(t, store, subject, 5, schemas[0])
+	mustAddSchema(t, store, subject, 7, schemas[1])
+
+	latest, found, err := store.GetLatestSchema(client, subject)
+	if err != nil || !found {
+		t.Fatalf("GetLatestSchema: found %t, error %v", found, err)
+	}
+	expected := storage.Schema{Subject: subject, ID: 7, Version: 2, Schema: schemas[1]}
+	if *latest != expected {
+		t.Errorf("GetLatestSchema: expected %v, got %v", expected, *latest)
+	}
+
+	_, found, err = store.GetLatestSchema(client, "missing")
+	if err != nil || found {
+		t.Errorf("GetLatestSchema for missing subject: found %t, error %v", found, err)
+	}
+}
+
+func testSchemaByID(t *testing.T, store storage.StorageStater) {
+	mustAddSchema(t, store, subject, 3, schemas[0])
+
+	schema, found, err := store.GetSchemaByID(client, 3)
+	if err != nil || !found || schema != schemas[0] {
+		t.Errorf("GetSchemaByID: expected %s, got %s (found %t, error %v)", schemas[0], schema, found, err)
+	}
+	_, found, err = store.GetSchemaByID(client, 4)
+	if err != nil || found {
+		t.Errorf("GetSchemaByID for missing id: found %t, error %v", found, err)
+	}
+
+	if id := store.GetID(client, schemas[0]); id != 3 {
+		t.Errorf("GetID: expected 3, got %d", id)
+	}
+	if id := store.GetID(client, schemas[1]); id != -1 {
+		t.Errorf("GetID for unknown schema: expected -1, got %d", id)
+	}
+}
+
+func testMissingEntries(t *testing.T, store storage.StorageStater) {
+	// an unknown client may be reported as an error, but never as found
+	if _, found, _ := store.GetSchema("unknown", subject, 1); found {
+		t.Error("GetSchema for unknown client: expected not found")
+	}
+	if _, found, _ := store.GetVersions("unknown", subject); found {
+		t.Error("GetVersions for unknown client: expected not found")
+	}
+
+	mustAddSchema(t, store, subject, 1, schemas[0])
+
+	_, found, err := store.GetSchema(client, subject, 2)
+	if err != nil || found {
+		t.Errorf("GetSchema for missing version: found %t, error %v", found, err)
+	}
+	_, found, err = store.GetSchema(client, "missing", 1)
+	if err != nil || found {
+		t.Errorf("GetSchema for missing subject: found %t, error %v", found, err)
+	}
+	_, found, err = store.GetVersions(client, "missing")
+	if err != nil || found {
+		t.Errorf("GetVersions for missing subject: found %t, error %v", found, err)
+	}
+}
+
+func testGlobalConfig(t *testing.T, store storage.StorageStater) {
+	for _, level := range []string{storage.CompatibilityFull, storage.CompatibilityNone} {
+		if err := store.SetGlobalConfig(client, level); err != nil {
+			t.Fatal(err)
+		}
+		stored, err := store.GetGlobalConfig(client)
+		if err != nil || stored != level {
+			t.Errorf("GetGlobalConfig: expected %s, got %s (error %v)", level, stored, err)
+		}
+	}
+}
+
+func testSubjectConfig(t *testing.T, store storage.StorageStater) {
+	if err := store.SetGlobalConfig(client, storage.CompatibilityNone); err != nil {
+		t.Fatal(err)
+	}
+	for _, level := range []string{storage.CompatibilityForward, storage.CompatibilityBackward} {
+		if err := store.SetSubjectConfig(client, subject, level); err != nil {
+			t.Fatal(err)
+		}
+		stored, found, err := store.GetSubjectConfig(client, subject)
+		if err != nil || !found || stored != level {
+			t.Errorf("GetSubjectConfig: expected %s, got %s (found %t, error %v)", level, stored, found, err)
+		}
+	}
+
+	_, found, err := store.GetSubjectConfig(client, "missing")
+	if err != nil || found {
+		t.Errorf("GetSubjectConfig for missing subject: found %t, error %v", found, err)
+	}
+
+	global, err := store.GetGlobalConfig(client)
+	if err != nil || global != storage.CompatibilityNone {
+		t.Errorf("subject config should not change global config, got %s (error %v)", global, err)
+	}
+}
+
+func testSettings(t *testing.T, store storage.StorageStater) {
+	for _, value := range []string{"strict", "lenient"} {
+		if err := store.SetSetting(client, "", storage.SettingValidation, value); err != nil {
+			t.Fatal(err)
+		}
+		stored, found, err := store.GetSetting(client, "", storage.SettingValidation)
+		if err != nil || !found || stored != value {
+			t.Errorf("GetSetting: expected %s, got %s (found %t, error %v)", value, stored, found, err)
+		}
+	}
+	if err := store.SetSetting(client, subject, storage.SettingValidation, "strict"); err != nil {
+		t.Fatal(err)
+	}
+	stored, _, _ := store.GetSetting(client, "", storage.SettingValidation)
+	if stored != "lenient" {
+		t.Errorf("subject setting should not change the client setting, got %s", stored)
+	}
+
+	if err := store.SetSetting(client, subject, storage.SettingRules, "{}"); err != nil {
+		t.Fatal(err)
+	}
+	settings, err := store.GetSettings(client, subject)
+	if err != nil || len(settings) != 2 || settings[storage.SettingValidation] != "strict" || settings[storage.SettingRules] != "{}" {
+		t.Errorf("GetSettings: expected the validation and rules settings of the subject, got %v (error %v)", settings, err)
+	}
+	settings, err = store.GetSettings("missing", "")
+	if err != nil || len(settings) != 0 {
+		t.Errorf("GetSettings for missing client: got %v, error %v", settings, err)
+	}
+
+	_, found, err := store.GetSetting(client, "missing", storage.SettingValidation)
+	if err != nil || found {
+		t.Errorf("GetSetting for missing subject: found %t, error %v", found, err)
+	}
+	_, found, err = store.GetSetting("missing", "", storage.SettingValidation)
+	if err != nil || found {
+		t.Errorf("GetSetting for missing client: found %t, error %v", found, err)
+	}
+}
+
+func testUsers(t *testing.T, store storage.StorageStater) {
+	if !store.Empty() {
+		t.Error("Empty: expected new storage to be empty")
+	}
+	if err := store.AddUser("admin", "admin-token", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AddUser("user", "user-token", false); err != nil {
+		t.Fatal(err)
+	}
+	if store.Empty() {
+		t.Error("Empty: expected storage with users to be non-empty")
+	}
+
+	user, found := store.UserByName("user")
+	if !found || user.Token != "user-token" || user.Admin {
+		t.Errorf("UserByName: got %v (found %t)", user, found)
+	}
+	user, found = store.UserByToken("admin-token")
+	if !found || user.Name != "admin" || !user.Admin {
+		t.Errorf("UserByToken: got %v (found %t)", user, found)
+	}
+	if _, found := store.UserByName("nobody"); found {
+		t.Error("UserByName for unknown user: expected not found")
+	}
+	if _, found := store.UserByToken("nobody-token"); found {
+		t.Error("UserByToken for unknown token: expected not found")
+	}
+}
+
+func testConcurrentAddSchema(t *testing.T, store storage.StorageStater) {
+	const writers = 20
+	errs := make(chan error, writers)
+	wg := &sync.WaitGroup{}
+	for i := 0; i < writers; i++ {
+		wg.Add(2)
+		go func(id int64) {
+			defer wg.Done()
+			errs <- store.AddSchema(client, subject, id, fmt.Sprintf(`{"type": "fixed", "name": "f%d", "size": 1}`, id))
+		}(int64(i + 1))
+		go func() {
+			defer wg.Done()
+			store.GetLatestSchema(client, subject)
+			store.GetSubjects(client)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	versions, _, err := store.GetVersions(client, subject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := make([]int, writers)
+	for i := range expected {
+		expected[i] = i + 1
+	}
+	assertVersions(t, versions, expected...)
+
+	ids := make(map[int64]int)
+	for _, version := range versions {
+		schema, _, err := store.GetSchema(client, subject, version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id := store.GetID(client, schema)
+		if other, ok := ids[id]; ok {
+			t.Errorf("id %d is used by versions %d and %d", id, other, version)
+		}
+		ids[id] = version
+	}
+}
+
+func mustAddSchema(t *testing.T, store storage.StorageStater, subject string, id int64, schema string) {
+	if err := store.AddSchema(client, subject, id, schema); err != nil {
+		t.Fatalf("AddSchema(%s, %d): %s", subject, id, err)
+	}
+}
+
+func assertVersions(t *testing.T, actual []int, expected ...int) {
+	if fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Errorf("expected versions %v in ascending order, got %v", expected, actual)
+	}
+}
@@ -83,3 +83,14 @@ func NewFullCompatibility() *FullCompatibility {
 func (fc *FullCompatibility) Validate(toValidate avro.Schema, existing avro.Schema) error {
 	return fc.validator.Validate(toValidate, []avro.Schema{existing})
 }
+
+// KeyStableCompatibility accepts a schema that can read the existing one and encodes every value of the existing
+// schema to the same bytes. Kafka partitions and compacts by the key bytes, so key schemas have to keep them.
+type KeyStableCompatibility struct{}
+
+func (ksc *KeyStableCompatibility) Validate(toValidate avro.Schema, existing avro.Schema) error {
+	if incompatibilities := check(existing, toValidate).fixing(false); len(incompatibilities) > 0 {
+		return incompatibilities
+	}
+	return checkStable(existing, toValidate).err()
+}
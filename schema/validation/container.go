@@ -0,0 +1,164 @@
+/* Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package validation
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/elodina/go-avro"
+)
+
+// containerHeaderSchema is the header of an object container file. go-avro reads it too, but keeps it to itself.
+var containerHeaderSchema = avro.MustParseSchema(`{"type": "record", "name": "org.apache.avro.file.Header", "fields": [
+	{"name": "magic", "type": {"type": "fixed", "name": "Magic", "size": 4}},
+	{"name": "meta", "type": {"type": "map", "values": "bytes"}},
+	{"name": "sync", "type": {"type": "fixed", "name": "Sync", "size": 16}}
+]}`)
+
+var containerMagic = []byte{'O', 'b', 'j', 1}
+
+// NullCodec is the codec of container files whose blocks aren't compressed.
+const NullCodec = "null"
+
+type containerHeader struct {
+	Magic []byte            `avro:"magic"`
+	Meta  map[string][]byte `avro:"meta"`
+	Sync  []byte            `avro:"sync"`
+}
+
+// containerSyncSize is the size of the marker following every block.
+const containerSyncSize = 16
+
+// ContainerFile is an Avro object container file. Schema is the writer schema embedded in its header, exactly
+// as written: go-avro's data file reader parses it and drops what it doesn't support, like aliases.
+type ContainerFile struct {
+	Schema   string
+	Codec    string
+	Records  int64
+	filename string
+}
+
+// OpenContainerFile reads the header of an object container file.
+func OpenContainerFile(filename string) (*ContainerFile, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < len(containerMagic) || !bytes.Equal(data[:len(containerMagic)], containerMagic) {
+		return nil, avro.NotAvroFile
+	}
+	reader := avro.NewSpecificDatumReader()
+	reader.SetSchema(containerHeaderSchema)
+	header := &containerHeader{}
+	decoder := avro.NewBinaryDecoder(data)
+	err = reader.Read(header, decoder)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid container file header: %s", err)
+	}
+	records, err := countRecords(decoder, int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	container := &ContainerFile{Schema: string(header.Meta["avro.schema"]), Codec: string(header.Meta["avro.codec"]),
+		Records: records, filename: filename}
+	if container.Codec == "" {
+		container.Codec = NullCodec
+	}
+	if _, err := ParseSchema(container.Schema); err != nil {
+		return nil, fmt.Errorf("Invalid container file schema: %s", err)
+	}
+	return container, nil
+}
+
+// countRecords adds up the record counts of the blocks following the header.
+func countRecords(decoder *avro.BinaryDecoder, size int64) (int64, error) {
+	var records int64
+	for decoder.Tell() < size {
+		count, err := decoder.ReadLong()
+		if err != nil {
+			return 0, fmt.Errorf("Invalid container file block: %s", err)
+		}
+		blockSize, err := decoder.ReadLong()
+		if err != nil {
+			return 0, fmt.Errorf("Invalid container file block: %s", err)
+		}
+		end := decoder.Tell() + blockSize + containerSyncSize
+		if count < 0 || blockSize < 0 || end > size {
+			return 0, fmt.Errorf("Invalid container file block at %d", decoder.Tell())
+		}
+		records += count
+		decoder.Seek(end)
+	}
+	return records, nil
+}
+
+// Sample reads up to n records with the reader schema through go-avro's data file reader, resolving them with
+// the writer schema of the file. It returns how many records were read and why the next one couldn't be.
+// go-avro doesn't decompress blocks, so only files with the null codec can be sampled.
+func (cf *ContainerFile) Sample(reader avro.Schema, n int) (int, error) {
+	if cf.Codec != NullCodec {
+		return 0, fmt.Errorf("Records compressed with the %s codec can't be read", cf.Codec)
+	}
+	writer, err := ParseSchema(cf.Schema)
+	if err != nil {
+		return 0, err
+	}
+	file, err := avro.NewDataFileReader(cf.filename, &resolvingDatumReader{writer: writer, reader: reader})
+	if err != nil {
+		return 0, err
+	}
+	// go-avro reads past the empty block that ends the file, so only as many records as there are are read
+	if int64(n) > cf.Records {
+		n = int(cf.Records)
+	}
+	for i := 0; i < n; i++ {
+		var datum interface{}
+		ok, err := file.Next(&datum)
+		if err != nil {
+			return i, fmt.Errorf("Record %d: %s", i, err)
+		}
+		if !ok {
+			return i, nil
+		}
+	}
+	return n, nil
+}
+
+// resolvingDatumReader lets go-avro's data file reader read records the way ReadDatum does.
+type resolvingDatumReader struct {
+	writer avro.Schema
+	reader avro.Schema
+}
+
+// SetSchema ignores the writer schema go-avro parsed from the header, the one parsed with ParseSchema keeps aliases.
+func (rdr *resolvingDatumReader) SetSchema(avro.Schema) {}
+
+func (rdr *resolvingDatumReader) Read(v interface{}, dec avro.Decoder) error {
+	decoder, ok := dec.(*avro.BinaryDecoder)
+	if !ok {
+		return fmt.Errorf("Unsupported decoder %T", dec)
+	}
+	d := &datumReader{decoder: decoder, size: -1}
+	datum, err := d.read(rdr.writer, rdr.reader)
+	if err != nil {
+		return err
+	}
+	*v.(*interface{}) = datum
+	return nil
+}
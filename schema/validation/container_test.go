@@ -0,0 +1,84 @@
+/* Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package validation
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/elodina/go-avro"
+	"gopkg.in/stretchr/testify.v1/assert"
+	"gopkg.in/stretchr/testify.v1/require"
+)
+
+const containerSchema = `{"type": "record", "name": "Event", "fields": [{"name": "id", "type": "long"}, {"name": "kind", "type": "string"}]}`
+
+func writeContainerFile(t *testing.T, records int) string {
+	file, err := ioutil.TempFile("", "container")
+	require.NoError(t, err)
+	defer file.Close()
+	schema, err := avro.ParseSchema(containerSchema)
+	require.NoError(t, err)
+	writer, err := avro.NewDataFileWriter(file, schema, avro.NewGenericDatumWriter())
+	require.NoError(t, err)
+	for i := 0; i < records; i++ {
+		record := avro.NewGenericRecord(schema)
+		record.Set("id", int64(i))
+		record.Set("kind", "click")
+		require.NoError(t, writer.Write(record))
+	}
+	require.NoError(t, writer.Close())
+	return file.Name()
+}
+
+func TestContainerFile(t *testing.T) {
+	filename := writeContainerFile(t, 3)
+	defer os.Remove(filename)
+
+	container, err := OpenContainerFile(filename)
+	require.NoError(t, err)
+	assert.Equal(t, NullCodec, container.Codec)
+	assert.Equal(t, int64(3), container.Records)
+	schema, err := ParseSchema(container.Schema)
+	require.NoError(t, err)
+	assert.Equal(t, "Event", schema.GetName())
+
+	read, err := container.Sample(schema, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, read)
+	read, err = container.Sample(schema, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 3, read)
+
+	reader, err := ParseSchema(`{"type": "record", "name": "Event", "fields": [{"name": "id", "type": "int"}]}`)
+	require.NoError(t, err)
+	read, err = container.Sample(reader, 10)
+	assert.Equal(t, 0, read)
+	require.Error(t, err)
+	assert.Equal(t, "Record 0: /fields/id: Found long, expecting int", err.Error())
+}
+
+func TestContainerFileInvalid(t *testing.T) {
+	file, err := ioutil.TempFile("", "container")
+	require.NoError(t, err)
+	defer os.Remove(file.Name())
+	file.WriteString(`{"type": "string"}`)
+	file.Close()
+
+	_, err = OpenContainerFile(file.Name())
+	assert.Equal(t, avro.NotAvroFile, err)
+}
@@ -0,0 +1,453 @@
+/* Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/elodina/go-avro"
+)
+
+// maxDatumItems caps the array items and map entries of a datum. Items that take no bytes, like nulls,
+// can't be bounded by the size of the data, so a few bytes could otherwise declare billions of them.
+const maxDatumItems = 1 << 20
+
+// DatumError is data that can't be read, found at Path in the reader schema.
+type DatumError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (de *DatumError) Error() string {
+	return fmt.Sprintf("%s: %s", de.Path, de.Message)
+}
+
+// Record is a record datum with its fields in the order of the reader schema.
+type Record []*RecordField
+
+// RecordField is a field of a record datum.
+type RecordField struct {
+	Name  string
+	Value interface{}
+}
+
+// MarshalJSON serializes the record as a JSON object, keeping the field order.
+func (r Record) MarshalJSON() ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	buffer.WriteString("{")
+	for i, field := range r {
+		if i > 0 {
+			buffer.WriteString(",")
+		}
+		name, err := json.Marshal(field.Name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(field.Value)
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(name)
+		buffer.WriteString(":")
+		buffer.Write(value)
+	}
+	buffer.WriteString("}")
+	return buffer.Bytes(), nil
+}
+
+// ReadDatum decodes Avro binary data written with the writer schema as the reader schema, resolving the two
+// the way the Avro specification does: values are promoted, writer fields the reader doesn't have are skipped
+// and reader fields that aren't written take their defaults. The datum is returned in the Avro JSON encoding,
+// ready to marshal: records are Records, unions other than null are single entry maps keyed by the branch name,
+// and bytes and fixed are strings of code points 0-255.
+func ReadDatum(writer avro.Schema, reader avro.Schema, data []byte) (interface{}, error) {
+	d := &datumReader{decoder: avro.NewBinaryDecoder(data), size: int64(len(data))}
+	datum, err := d.read(writer, reader)
+	if err != nil {
+		return nil, err
+	}
+	if left := int64(len(data)) - d.decoder.Tell(); left > 0 {
+		return nil, d.errorf("%d bytes left after the datum", left)
+	}
+	return datum, nil
+}
+
+type datumReader struct {
+	decoder *avro.BinaryDecoder
+	path    []string
+	// size is the length of the data, -1 if unknown, and items the array items and map entries read so far
+	size  int64
+	items int64
+}
+
+func (d *datumReader) errorf(format string, args ...interface{}) error {
+	return &DatumError{Path: "/" + strings.Join(d.path, "/"), Message: fmt.Sprintf(format, args...)}
+}
+
+// block checks the item count of an array or map block read from the data. A block can't hold more items
+// than there are bytes left, unless its items are encoded with no bytes at all.
+func (d *datumReader) block(count int64, items avro.Schema) error {
+	if left := d.size - d.decoder.Tell(); d.size >= 0 && count > left && (items == nil || !encodedEmpty(items, nil)) {
+		return d.errorf("Block of %d items is longer than the %d bytes left", count, left)
+	}
+	d.items += count
+	if d.items > maxDatumItems {
+		return d.errorf("Datum has more than %d array items and map entries", maxDatumItems)
+	}
+	return nil
+}
+
+func (d *datumReader) readAt(writer avro.Schema, reader avro.Schema, segment string) (interface{}, error) {
+	d.path = append(d.path, segment)
+	datum, err := d.read(writer, reader)
+	d.path = d.path[:len(d.path)-1]
+	return datum, err
+}
+
+func (d *datumReader) read(writer avro.Schema, reader avro.Schema) (interface{}, error) {
+	writer = actual(writer)
+	reader = actual(reader)
+	if writer.Type() == avro.Union {
+		branches := writer.(*avro.UnionSchema).Types
+		index, err := d.decoder.ReadLong()
+		if err != nil {
+			return nil, d.errorf("Can't read union branch: %s", err)
+		}
+		if index < 0 || index >= int64(len(branches)) {
+			return nil, d.errorf("Union branch %d does not exist, the writer union has %d", index, len(branches))
+		}
+		return d.read(branches[index], reader)
+	}
+	if reader.Type() == avro.Union {
+		union := reader.(*avro.UnionSchema)
+		index := readerBranch(writer, union)
+		if index < 0 {
+			return nil, d.errorf("No branch of the reader union reads %s", avro.GetFullName(writer))
+		}
+		datum, err := d.readAt(writer, union.Types[index], strconv.Itoa(index))
+		if err != nil {
+			return nil, err
+		}
+		return unionDatum(actual(union.Types[index]), datum), nil
+	}
+	if writer.Type() != reader.Type() {
+		if !promotable(writer.Type(), reader.Type()) {
+			return nil, d.errorf("Found %s, expecting %s", avro.GetFullName(writer), avro.GetFullName(reader))
+		}
+		return d.promote(writer, reader)
+	}
+
+	var datum interface{}
+	var err error
+	switch writer.Type() {
+	case avro.Null:
+		return nil, nil
+	case avro.Boolean:
+		datum, err = d.decoder.ReadBoolean()
+	case avro.Int:
+		datum, err = d.decoder.ReadInt()
+	case avro.Long:
+		datum, err = d.decoder.ReadLong()
+	case avro.Float:
+		datum, err = d.decoder.ReadFloat()
+	case avro.Double:
+		datum, err = d.decoder.ReadDouble()
+	case avro.String:
+		datum, err = d.decoder.ReadString()
+	case avro.Bytes:
+		var value []byte
+		value, err = d.decoder.ReadBytes()
+		datum = bytesDatum(value)
+	case avro.Fixed:
+		return d.readFixed(writer.(*avro.FixedSchema), reader.(*avro.FixedSchema))
+	case avro.Enum:
+		return d.readEnum(writer.(*avro.EnumSchema), reader.(*avro.EnumSchema))
+	case avro.Array:
+		return d.readArray(writer.(*avro.ArraySchema), reader.(*avro.ArraySchema))
+	case avro.Map:
+		return d.readMap(writer.(*avro.MapSchema), reader.(*avro.MapSchema))
+	case avro.Record:
+		return d.readRecord(writer.(*avro.RecordSchema), reader.(*avro.RecordSchema))
+	default:
+		return nil, d.errorf("Unknown schema type: %d", writer.Type())
+	}
+	if err != nil {
+		return nil, d.errorf("Can't read %s: %s", avro.GetFullName(writer), err)
+	}
+	return datum, nil
+}
+
+// promote reads a value of the writer type as the reader type it can be promoted to.
+func (d *datumReader) promote(writer avro.Schema, reader avro.Schema) (interface{}, error) {
+	var value interface{}
+	var err error
+	switch writer.Type() {
+	case avro.Int:
+		var i int32
+		i, err = d.decoder.ReadInt()
+		value = int64(i)
+	case avro.Long:
+		value, err = d.decoder.ReadLong()
+	case avro.Float:
+		var f float32
+		f, err = d.decoder.ReadFloat()
+		value = float64(f)
+	case avro.String:
+		var s string
+		s, err = d.decoder.ReadString()
+		value = []byte(s)
+	case avro.Bytes:
+		var b []byte
+		b, err = d.decoder.ReadBytes()
+		value = string(b)
+	}
+	if err != nil {
+		return nil, d.errorf("Can't read %s: %s", avro.GetFullName(writer), err)
+	}
+
+	switch reader.Type() {
+	case avro.Long:
+		return value.(int64), nil
+	case avro.Float:
+		return float32(value.(int64)), nil
+	case avro.Double:
+		if f, ok := value.(float64); ok {
+			return f, nil
+		}
+		return float64(value.(int64)), nil
+	case avro.Bytes:
+		return bytesDatum(value.([]byte)), nil
+	}
+	return value, nil
+}
+
+func (d *datumReader) readFixed(writer *avro.FixedSchema, reader *avro.FixedSchema) (interface{}, error) {
+	if !namesMatch(writer, reader) {
+		return nil, d.errorf("Different Fixed type names: writer %s, reader %s", writer.GetName(), reader.GetName())
+	}
+	if writer.Size != reader.Size {
+		return nil, d.errorf("Different Fixed type sizes: writer %d, reader %d", writer.Size, reader.Size)
+	}
+	value := make([]byte, writer.Size)
+	err := d.decoder.ReadFixed(value)
+	if err != nil {
+		return nil, d.errorf("Can't read %s: %s", writer.GetName(), err)
+	}
+	return bytesDatum(value), nil
+}
+
+// readEnum reads a writer symbol, or the reader enum default if the reader doesn't have it.
+func (d *datumReader) readEnum(writer *avro.EnumSchema, reader *avro.EnumSchema) (interface{}, error) {
+	if !namesMatch(writer, reader) {
+		return nil, d.errorf("Different Enum type names: writer %s, reader %s", writer.GetName(), reader.GetName())
+	}
+	index, err := d.decoder.ReadEnum()
+	if err != nil {
+		return nil, d.errorf("Can't read %s: %s", writer.GetName(), err)
+	}
+	if index < 0 || int(index) >= len(writer.Symbols) {
+		return nil, d.errorf("Enum symbol %d does not exist, the writer enum has %d", index, len(writer.Symbols))
+	}
+	symbol := writer.Symbols[index]
+	for _, readerSymbol := range reader.Symbols {
+		if readerSymbol == symbol {
+			return symbol, nil
+		}
+	}
+	if defaultSymbol, ok := reader.Prop("default"); ok {
+		return defaultSymbol, nil
+	}
+	return nil, d.errorf("Enum symbol %s does not exist for reader schema", symbol)
+}
+
+func (d *datumReader) readArray(writer *avro.ArraySchema, reader *avro.ArraySchema) (interface{}, error) {
+	items := make([]interface{}, 0)
+	count, err := d.decoder.ReadArrayStart()
+	for ; err == nil && count > 0; count, err = d.decoder.ArrayNext() {
+		if err := d.block(count, writer.Items); err != nil {
+			return nil, err
+		}
+		for i := int64(0); i < count; i++ {
+			item, err := d.readAt(writer.Items, reader.Items, strconv.Itoa(len(items)))
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+	}
+	if err != nil {
+		return nil, d.errorf("Can't read array block: %s", err)
+	}
+	return items, nil
+}
+
+func (d *datumReader) readMap(writer *avro.MapSchema, reader *avro.MapSchema) (interface{}, error) {
+	values := make(map[string]interface{})
+	count, err := d.decoder.ReadMapStart()
+	for ; err == nil && count > 0; count, err = d.decoder.MapNext() {
+		// every entry has a key, which takes at least a byte
+		if err := d.block(count, nil); err != nil {
+			return nil, err
+		}
+		for i := int64(0); i < count; i++ {
+			key, err := d.decoder.ReadString()
+			if err != nil {
+				return nil, d.errorf("Can't read map key: %s", err)
+			}
+			value, err := d.readAt(writer.Values, reader.Values, key)
+			if err != nil {
+				return nil, err
+			}
+			values[key] = value
+		}
+	}
+	if err != nil {
+		return nil, d.errorf("Can't read map block: %s", err)
+	}
+	return values, nil
+}
+
+// readRecord reads the writer fields in order, skipping those the reader doesn't have,
+// and fills in the defaults of the reader fields that aren't written.
+func (d *datumReader) readRecord(writer *avro.RecordSchema, reader *avro.RecordSchema) (interface{}, error) {
+	if !namesMatch(writer, reader) {
+		return nil, d.errorf("Different Record type names: writer %s, reader %s", writer.GetName(), reader.GetName())
+	}
+	writerFields := make(map[string]*avro.SchemaField)
+	for _, field := range writer.Fields {
+		writerFields[field.Name] = field
+	}
+	readBy := make(map[*avro.SchemaField]*avro.SchemaField)
+	for _, readerField := range reader.Fields {
+		if writerField := lookupWriterField(writerFields, readerField); writerField != nil {
+			readBy[writerField] = readerField
+		}
+	}
+
+	values := make(map[string]interface{})
+	for _, writerField := range writer.Fields {
+		readerField, ok := readBy[writerField]
+		if !ok {
+			readerField = writerField
+		}
+		value, err := d.readAt(writerField.Type, readerField.Type, "fields/"+readerField.Name)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			values[readerField.Name] = value
+		}
+	}
+
+	record := make(Record, 0, len(reader.Fields))
+	for _, readerField := range reader.Fields {
+		value, ok := values[readerField.Name]
+		if !ok {
+			if !hasDefault(readerField) {
+				return nil, d.errorf("Field %s is not written and does not have default value", readerField.Name)
+			}
+			value = defaultDatum(readerField.Type, fieldDefault(readerField))
+		}
+		record = append(record, &RecordField{Name: readerField.Name, Value: value})
+	}
+	return record, nil
+}
+
+// encodedEmpty tells if every value of a schema is encoded with no bytes: nulls, empty fixed types
+// and records of such fields.
+func encodedEmpty(schema avro.Schema, visited map[*avro.RecordSchema]bool) bool {
+	switch typed := actual(schema).(type) {
+	case *avro.NullSchema:
+		return true
+	case *avro.FixedSchema:
+		return typed.Size == 0
+	case *avro.RecordSchema:
+		if visited[typed] {
+			return true
+		}
+		if visited == nil {
+			visited = make(map[*avro.RecordSchema]bool)
+		}
+		visited[typed] = true
+		for _, field := range typed.Fields {
+			if !encodedEmpty(field.Type, visited) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// defaultDatum turns a JSON default value into a datum of its schema. A union default is the first branch's.
+func defaultDatum(schema avro.Schema, value interface{}) interface{} {
+	schema = actual(schema)
+	switch schema.Type() {
+	case avro.Union:
+		branch := actual(schema.(*avro.UnionSchema).Types[0])
+		if branch.Type() == avro.Null {
+			return nil
+		}
+		return unionDatum(branch, defaultDatum(branch, value))
+	case avro.Array:
+		list, _ := value.([]interface{})
+		items := make([]interface{}, len(list))
+		for i, item := range list {
+			items[i] = defaultDatum(schema.(*avro.ArraySchema).Items, item)
+		}
+		return items
+	case avro.Map:
+		object, _ := value.(map[string]interface{})
+		values := make(map[string]interface{})
+		for key, item := range object {
+			values[key] = defaultDatum(schema.(*avro.MapSchema).Values, item)
+		}
+		return values
+	case avro.Record:
+		object, _ := value.(map[string]interface{})
+		record := make(Record, 0)
+		for _, field := range schema.(*avro.RecordSchema).Fields {
+			fieldValue, ok := object[field.Name]
+			if !ok {
+				fieldValue = fieldDefault(field)
+			}
+			record = append(record, &RecordField{Name: field.Name, Value: defaultDatum(field.Type, fieldValue)})
+		}
+		return record
+	}
+	return value
+}
+
+// unionDatum wraps a datum read with a union branch, null aside, in a map keyed by the branch name.
+func unionDatum(branch avro.Schema, datum interface{}) interface{} {
+	if branch.Type() == avro.Null {
+		return nil
+	}
+	return map[string]interface{}{avro.GetFullName(branch): datum}
+}
+
+// bytesDatum encodes bytes as a string of code points 0-255, the way the Avro JSON encoding does.
+func bytesDatum(value []byte) string {
+	runes := make([]rune, len(value))
+	for i, b := range value {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
@@ -0,0 +1,353 @@
+/* Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/elodina/go-avro"
+)
+
+// DatumErrors lists every problem found in a datum.
+type DatumErrors []*DatumError
+
+func (de DatumErrors) Error() string {
+	messages := make([]string, len(de))
+	for i, err := range de {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// ParseJSONDatum converts a datum in the Avro JSON encoding into the generic values go-avro writes:
+// records are *avro.GenericRecords, enums their symbols, ints int32s, floats float32s, bytes and fixed []bytes,
+// and unions the value of their branch. Every field that doesn't match its schema is reported, at its path in the datum.
+func ParseJSONDatum(schema avro.Schema, data []byte) (interface{}, DatumErrors) {
+	value, errors := decodeJSON(data)
+	if errors != nil {
+		return nil, errors
+	}
+	parser := &datumParser{}
+	datum := parser.parse(schema, value, false)
+	return datum, parser.errors
+}
+
+// ValidateDatum checks a datum in the Avro JSON encoding against a schema. Once every field matches,
+// the datum also has to pass go-avro's Schema.Validate and be written by its generic datum writer.
+func ValidateDatum(schema avro.Schema, data []byte) DatumErrors {
+	datum, errors := ParseJSONDatum(schema, data)
+	if len(errors) > 0 {
+		return errors
+	}
+	if !schema.Validate(reflect.ValueOf(datum)) {
+		return DatumErrors{{Path: "/", Message: "Datum does not validate against the schema"}}
+	}
+	writer := avro.NewGenericDatumWriter()
+	writer.SetSchema(schema)
+	if err := writer.Write(datum, avro.NewBinaryEncoder(&bytes.Buffer{})); err != nil {
+		return DatumErrors{{Path: "/", Message: fmt.Sprintf("Datum can't be written: %s", err)}}
+	}
+	return nil
+}
+
+// WriteDatum encodes a datum in the Avro JSON encoding as Avro binary data. go-avro's generic datum writer
+// guesses union branches from the value, which can't tell a string from an enum symbol or two records apart,
+// so the datum is written with its binary encoder as it is parsed, with the branch the datum names.
+func WriteDatum(schema avro.Schema, data []byte) ([]byte, DatumErrors) {
+	value, errors := decodeJSON(data)
+	if errors != nil {
+		return nil, errors
+	}
+	buffer := &bytes.Buffer{}
+	parser := &datumParser{encoder: avro.NewBinaryEncoder(buffer)}
+	parser.parse(schema, value, false)
+	if len(parser.errors) > 0 {
+		return nil, parser.errors
+	}
+	return buffer.Bytes(), nil
+}
+
+// decodeJSON decodes a single JSON value, keeping numbers as json.Numbers so ints and longs can be range checked.
+func decodeJSON(data []byte) (interface{}, DatumErrors) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, DatumErrors{{Path: "/", Message: fmt.Sprintf("Invalid JSON: %s", err)}}
+	}
+	if decoder.More() {
+		return nil, DatumErrors{{Path: "/", Message: "Invalid JSON: more than one value"}}
+	}
+	return value, nil
+}
+
+// datumParser writes what it parses with the encoder, if it has one.
+type datumParser struct {
+	path    []string
+	errors  DatumErrors
+	encoder *avro.BinaryEncoder
+}
+
+func (p *datumParser) errorf(format string, args ...interface{}) {
+	p.errors = append(p.errors, &DatumError{Path: "/" + strings.Join(p.path, "/"), Message: fmt.Sprintf(format, args...)})
+}
+
+func (p *datumParser) write(write func(*avro.BinaryEncoder)) {
+	if p.encoder != nil && len(p.errors) == 0 {
+		write(p.encoder)
+	}
+}
+
+func (p *datumParser) parseAt(schema avro.Schema, value interface{}, isDefault bool, segment string) interface{} {
+	p.path = append(p.path, segment)
+	datum := p.parse(schema, value, isDefault)
+	p.path = p.path[:len(p.path)-1]
+	return datum
+}
+
+// parse converts a JSON value of a schema. Defaults are plain JSON: a union default is a value of the first
+// branch rather than an object keyed by the branch name.
+func (p *datumParser) parse(schema avro.Schema, value interface{}, isDefault bool) interface{} {
+	schema = actual(schema)
+	switch schema.Type() {
+	case avro.Null:
+		if value != nil {
+			p.errorf("Expected null, found %s", fragment(value))
+		}
+		return nil
+	case avro.Boolean:
+		if b, ok := value.(bool); ok {
+			p.write(func(e *avro.BinaryEncoder) { e.WriteBoolean(b) })
+			return b
+		}
+	case avro.Int:
+		if i, ok := jsonInteger(value, 32); ok {
+			p.write(func(e *avro.BinaryEncoder) { e.WriteInt(int32(i)) })
+			return int32(i)
+		}
+	case avro.Long:
+		if i, ok := jsonInteger(value, 64); ok {
+			p.write(func(e *avro.BinaryEncoder) { e.WriteLong(i) })
+			return i
+		}
+	case avro.Float:
+		if f, ok := jsonFloat(value, 32); ok {
+			p.write(func(e *avro.BinaryEncoder) { e.WriteFloat(float32(f)) })
+			return float32(f)
+		}
+	case avro.Double:
+		if f, ok := jsonFloat(value, 64); ok {
+			p.write(func(e *avro.BinaryEncoder) { e.WriteDouble(f) })
+			return f
+		}
+	case avro.String:
+		if s, ok := value.(string); ok {
+			p.write(func(e *avro.BinaryEncoder) { e.WriteString(s) })
+			return s
+		}
+	case avro.Bytes:
+		if b, ok := jsonBytes(value); ok {
+			p.write(func(e *avro.BinaryEncoder) { e.WriteBytes(b) })
+			return b
+		}
+	case avro.Fixed:
+		size := schema.(*avro.FixedSchema).Size
+		if b, ok := jsonBytes(value); ok {
+			if len(b) != size {
+				p.errorf("Expected %d bytes for %s, found %d", size, avro.GetFullName(schema), len(b))
+			}
+			p.write(func(e *avro.BinaryEncoder) { e.WriteRaw(b) })
+			return b
+		}
+	case avro.Enum:
+		if s, ok := value.(string); ok {
+			for index, symbol := range schema.(*avro.EnumSchema).Symbols {
+				if symbol == s {
+					p.write(func(e *avro.BinaryEncoder) { e.WriteInt(int32(index)) })
+					return s
+				}
+			}
+			p.errorf("%s is not a symbol of %s", s, avro.GetFullName(schema))
+			return s
+		}
+	case avro.Array:
+		if list, ok := value.([]interface{}); ok {
+			if len(list) > 0 {
+				p.write(func(e *avro.BinaryEncoder) { e.WriteArrayStart(int64(len(list))) })
+			}
+			items := make([]interface{}, len(list))
+			for i, item := range list {
+				items[i] = p.parseAt(schema.(*avro.ArraySchema).Items, item, isDefault, strconv.Itoa(i))
+			}
+			p.write(func(e *avro.BinaryEncoder) { e.WriteArrayNext(0) })
+			return items
+		}
+	case avro.Map:
+		if object, ok := value.(map[string]interface{}); ok {
+			keys := make([]string, 0, len(object))
+			for key := range object {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			if len(keys) > 0 {
+				p.write(func(e *avro.BinaryEncoder) { e.WriteMapStart(int64(len(keys))) })
+			}
+			values := make(map[string]interface{})
+			for _, key := range keys {
+				p.write(func(e *avro.BinaryEncoder) { e.WriteString(key) })
+				values[key] = p.parseAt(schema.(*avro.MapSchema).Values, object[key], isDefault, key)
+			}
+			p.write(func(e *avro.BinaryEncoder) { e.WriteMapNext(0) })
+			return values
+		}
+	case avro.Record:
+		if object, ok := value.(map[string]interface{}); ok {
+			return p.parseRecord(schema.(*avro.RecordSchema), object, isDefault)
+		}
+	case avro.Union:
+		return p.parseUnion(schema.(*avro.UnionSchema), value, isDefault)
+	default:
+		p.errorf("Unknown schema type: %d", schema.Type())
+		return nil
+	}
+	p.errorf("Expected %s, found %s", avro.GetFullName(schema), fragment(value))
+	return nil
+}
+
+// parseRecord converts every field of a record, missing fields take their defaults.
+func (p *datumParser) parseRecord(schema *avro.RecordSchema, object map[string]interface{}, isDefault bool) interface{} {
+	record := avro.NewGenericRecord(schema)
+	known := make(map[string]bool)
+	for _, field := range schema.Fields {
+		known[field.Name] = true
+		value, ok := object[field.Name]
+		if !ok {
+			if !hasDefault(field) {
+				p.path = append(p.path, field.Name)
+				p.errorf("Field %s is missing and does not have default value", field.Name)
+				p.path = p.path[:len(p.path)-1]
+				continue
+			}
+			record.Set(field.Name, p.parseAt(field.Type, fieldDefault(field), true, field.Name))
+			continue
+		}
+		record.Set(field.Name, p.parseAt(field.Type, value, isDefault, field.Name))
+	}
+	unknown := make([]string, 0)
+	for name := range object {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		p.path = append(p.path, name)
+		p.errorf("Field %s is not in %s", name, avro.GetFullName(schema))
+		p.path = p.path[:len(p.path)-1]
+	}
+	return record
+}
+
+// parseUnion converts null, or an object with a single entry keyed by the name of the branch its value has.
+func (p *datumParser) parseUnion(schema *avro.UnionSchema, value interface{}, isDefault bool) interface{} {
+	if isDefault {
+		p.write(func(e *avro.BinaryEncoder) { e.WriteLong(0) })
+		return p.parse(schema.Types[0], value, true)
+	}
+	if value == nil {
+		for index, branch := range schema.Types {
+			if branch.Type() == avro.Null {
+				p.write(func(e *avro.BinaryEncoder) { e.WriteLong(int64(index)) })
+				return nil
+			}
+		}
+		p.errorf("Expected one of %s, found null", unionBranchNames(schema))
+		return nil
+	}
+	object, ok := value.(map[string]interface{})
+	if !ok || len(object) != 1 {
+		p.errorf("Expected an object with a single key, one of %s, found %s", unionBranchNames(schema), fragment(value))
+		return nil
+	}
+	for name, branchValue := range object {
+		for index, branch := range schema.Types {
+			if avro.GetFullName(actual(branch)) == name {
+				p.write(func(e *avro.BinaryEncoder) { e.WriteLong(int64(index)) })
+				return p.parseAt(branch, branchValue, false, name)
+			}
+		}
+		p.errorf("%s is not a branch of the union, expected one of %s", name, unionBranchNames(schema))
+	}
+	return nil
+}
+
+func unionBranchNames(schema *avro.UnionSchema) string {
+	names := make([]string, len(schema.Types))
+	for i, branch := range schema.Types {
+		names[i] = avro.GetFullName(actual(branch))
+	}
+	return strings.Join(names, ", ")
+}
+
+func jsonInteger(value interface{}, bits int) (int64, bool) {
+	switch number := value.(type) {
+	case json.Number:
+		i, err := strconv.ParseInt(number.String(), 10, bits)
+		return i, err == nil
+	case float64:
+		// defaults are parsed without json.Number
+		if number != math.Trunc(number) {
+			return 0, false
+		}
+		i, err := strconv.ParseInt(strconv.FormatFloat(number, 'f', -1, 64), 10, bits)
+		return i, err == nil
+	}
+	return 0, false
+}
+
+func jsonFloat(value interface{}, bits int) (float64, bool) {
+	switch number := value.(type) {
+	case json.Number:
+		f, err := strconv.ParseFloat(number.String(), bits)
+		return f, err == nil
+	case float64:
+		return number, true
+	}
+	return 0, false
+}
+
+// jsonBytes decodes a string of code points 0-255, the Avro JSON encoding of bytes and fixed.
+func jsonBytes(value interface{}) ([]byte, bool) {
+	s, ok := value.(string)
+	if !ok {
+		return nil, false
+	}
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r > 255 {
+			return nil, false
+		}
+		b = append(b, byte(r))
+	}
+	return b, true
+}
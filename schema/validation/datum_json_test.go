@@ -0,0 +1,103 @@
+/* Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package validation
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+	"gopkg.in/stretchr/testify.v1/require"
+)
+
+const datumSchema = `{"type": "record", "name": "Order", "namespace": "com.acme", "fields": [
+	{"name": "id", "type": "long"},
+	{"name": "quantity", "type": "int"},
+	{"name": "price", "type": "double"},
+	{"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["NEW", "PAID"]}},
+	{"name": "hash", "type": {"type": "fixed", "name": "Hash", "size": 2}},
+	{"name": "note", "type": ["null", "string"], "default": null},
+	{"name": "tags", "type": {"type": "map", "values": "string"}, "default": {}},
+	{"name": "lines", "type": {"type": "array", "items": {"type": "record", "name": "Line", "fields": [
+		{"name": "sku", "type": "string"},
+		{"name": "discount", "type": ["float", "null"], "default": 0}
+	]}}},
+	{"name": "next", "type": ["null", "Order"], "default": null}
+]}`
+
+func TestValidateDatum(t *testing.T) {
+	schema, err := ParseSchema(datumSchema)
+	require.NoError(t, err)
+
+	errors := ValidateDatum(schema, []byte(`{"id": 1, "quantity": 2, "price": 9.5, "status": "PAID", "hash": "ÿ\u0000",
+		"note": {"string": "fragile"}, "lines": [{"sku": "A-1"}, {"sku": "B-2", "discount": {"float": 0.5}}],
+		"next": {"com.acme.Order": {"id": 2, "quantity": 1, "price": 1, "status": "NEW", "hash": "ab", "lines": []}}}`))
+	assert.Empty(t, errors)
+}
+
+func TestValidateDatumErrors(t *testing.T) {
+	schema, err := ParseSchema(datumSchema)
+	require.NoError(t, err)
+
+	errors := ValidateDatum(schema, []byte(`{"id": "1", "quantity": 3000000000, "price": 1, "status": "LOST", "hash": "abc",
+		"note": "fragile", "lines": [{"sku": "A-1", "discount": {"double": 0.5}}], "color": "red"}`))
+	messages := make([]string, len(errors))
+	for i, err := range errors {
+		messages[i] = err.Error()
+	}
+	assert.Equal(t, []string{
+		`/id: Expected long, found "1"`,
+		`/quantity: Expected int, found 3000000000`,
+		`/status: LOST is not a symbol of com.acme.Status`,
+		`/hash: Expected 2 bytes for com.acme.Hash, found 3`,
+		`/note: Expected an object with a single key, one of null, string, found "fragile"`,
+		`/lines/0/discount: double is not a branch of the union, expected one of float, null`,
+		`/color: Field color is not in com.acme.Order`,
+	}, messages)
+
+	errors = ValidateDatum(schema, []byte(`{"id": 1}`))
+	require.Len(t, errors, 5)
+	assert.Equal(t, "/quantity: Field quantity is missing and does not have default value", errors[0].Error())
+
+	errors = ValidateDatum(schema, []byte(`{"id": 1`))
+	require.Len(t, errors, 1)
+	assert.Equal(t, "/", errors[0].Path)
+}
+
+func TestWriteDatum(t *testing.T) {
+	schema, err := ParseSchema(datumSchema)
+	require.NoError(t, err)
+
+	datum := `{"id":1,"quantity":2,"price":9.5,"status":"PAID","hash":"ÿ\u0000","note":{"string":"fragile"},"tags":{"a":"1","b":"2"},` +
+		`"lines":[{"sku":"A-1","discount":{"float":0}},{"sku":"B-2","discount":null}],` +
+		`"next":{"com.acme.Order":{"id":2,"quantity":1,"price":1,"status":"NEW","hash":"ab","note":null,"tags":{},"lines":[],"next":null}}}`
+	data, errors := WriteDatum(schema, []byte(datum))
+	require.Empty(t, errors)
+	read, err := ReadDatum(schema, schema, data)
+	require.NoError(t, err)
+	encoded, err := json.Marshal(read)
+	require.NoError(t, err)
+	assert.Equal(t, datum, string(encoded))
+
+	union, err := ParseSchema(`[{"type": "enum", "name": "Color", "symbols": ["RED"]}, "string"]`)
+	require.NoError(t, err)
+	data, errors = WriteDatum(union, []byte(`{"string": "blue"}`))
+	require.Empty(t, errors)
+	assert.Equal(t, []byte{2, 8, 'b', 'l', 'u', 'e'}, data)
+
+	_, errors = WriteDatum(schema, []byte(`{"id": 1}`))
+	assert.Len(t, errors, 5)
+}
@@ -0,0 +1,144 @@
+/* Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/elodina/go-avro"
+	"gopkg.in/stretchr/testify.v1/assert"
+	"gopkg.in/stretchr/testify.v1/require"
+)
+
+const datumWriterSchema = `{"type": "record", "name": "User", "fields": [
+	{"name": "id", "type": "int"},
+	{"name": "email", "type": "string"},
+	{"name": "tags", "type": {"type": "array", "items": "string"}},
+	{"name": "suit", "type": {"type": "enum", "name": "Suit", "symbols": ["HEARTS", "SPADES"]}},
+	{"name": "nick", "type": ["null", "string"]},
+	{"name": "login", "type": "string"}
+]}`
+
+func encodeUser(suit int32) []byte {
+	buffer := &bytes.Buffer{}
+	encoder := avro.NewBinaryEncoder(buffer)
+	encoder.WriteInt(7)
+	encoder.WriteString("jo@acme.com")
+	encoder.WriteArrayStart(2)
+	encoder.WriteString("a")
+	encoder.WriteString("b")
+	encoder.WriteArrayNext(0)
+	encoder.WriteInt(suit)
+	encoder.WriteLong(1)
+	encoder.WriteString("jo")
+	encoder.WriteString("jdoe")
+	return buffer.Bytes()
+}
+
+func readUser(t *testing.T, rawReader string, data []byte) (string, error) {
+	writer, err := ParseSchema(datumWriterSchema)
+	require.NoError(t, err)
+	reader, err := ParseSchema(rawReader)
+	require.NoError(t, err)
+	datum, err := ReadDatum(writer, reader, data)
+	if err != nil {
+		return "", err
+	}
+	encoded, err := json.Marshal(datum)
+	require.NoError(t, err)
+	return string(encoded), nil
+}
+
+func TestReadDatum(t *testing.T) {
+	datum, err := readUser(t, datumWriterSchema, encodeUser(1))
+	require.NoError(t, err)
+	assert.Equal(t, `{"id":7,"email":"jo@acme.com","tags":["a","b"],"suit":"SPADES","nick":{"string":"jo"},"login":"jdoe"}`, datum)
+
+	datum, err = readUser(t, `{"type": "record", "name": "Account", "aliases": ["User"], "fields": [
+		{"name": "id", "type": ["null", "double"]},
+		{"name": "name", "type": "bytes", "aliases": ["login"]},
+		{"name": "suit", "type": {"type": "enum", "name": "Suit", "symbols": ["SPADES"]}},
+		{"name": "nick", "type": "string"},
+		{"name": "age", "type": ["int", "null"], "default": 30},
+		{"name": "address", "type": {"type": "record", "name": "Address", "fields": [
+			{"name": "city", "type": "string"}, {"name": "zip", "type": ["null", "string"], "default": null}
+		]}, "default": {"city": "Paris"}}
+	]}`, encodeUser(1))
+	require.NoError(t, err)
+	assert.Equal(t, `{"id":{"double":7},"name":"jdoe","suit":"SPADES","nick":"jo","age":{"int":30},"address":{"city":"Paris","zip":null}}`, datum)
+}
+
+func TestReadDatumErrors(t *testing.T) {
+	_, err := readUser(t, `{"type": "record", "name": "User", "fields": [
+		{"name": "suit", "type": {"type": "enum", "name": "Suit", "symbols": ["HEARTS"]}}
+	]}`, encodeUser(1))
+	require.Error(t, err)
+	assert.Equal(t, "/fields/suit: Enum symbol SPADES does not exist for reader schema", err.Error())
+
+	_, err = readUser(t, `{"type": "record", "name": "User", "fields": [{"name": "age", "type": "int"}]}`, encodeUser(0))
+	require.Error(t, err)
+	assert.Equal(t, "/: Field age is not written and does not have default value", err.Error())
+
+	_, err = readUser(t, datumWriterSchema, encodeUser(0)[:5])
+	assert.Error(t, err)
+
+	_, err = readUser(t, datumWriterSchema, append(encodeUser(0), 0))
+	require.Error(t, err)
+	assert.Equal(t, "/: 1 bytes left after the datum", err.Error())
+}
+
+func TestReadDatumBlockCounts(t *testing.T) {
+	read := func(rawSchema string, count int64) error {
+		schema, err := ParseSchema(rawSchema)
+		require.NoError(t, err)
+		buffer := &bytes.Buffer{}
+		avro.NewBinaryEncoder(buffer).WriteLong(count)
+		_, err = ReadDatum(schema, schema, buffer.Bytes())
+		return err
+	}
+
+	err := read(`{"type": "array", "items": "int"}`, 1<<24)
+	require.Error(t, err)
+	assert.Equal(t, "/: Block of 16777216 items is longer than the 0 bytes left", err.Error())
+
+	err = read(`{"type": "map", "values": "null"}`, 3)
+	require.Error(t, err)
+	assert.Equal(t, "/: Block of 3 items is longer than the 0 bytes left", err.Error())
+
+	err = read(`{"type": "array", "items": "null"}`, 1<<24)
+	require.Error(t, err)
+	assert.Equal(t, "/: Datum has more than 1048576 array items and map entries", err.Error())
+
+	err = read(`{"type": "array", "items": {"type": "record", "name": "Empty", "fields": []}}`, 1<<24)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "more than 1048576")
+}
+
+func TestSplitWireFormat(t *testing.T) {
+	id, data, err := SplitWireFormat([]byte{0, 0, 0, 1, 2, 42})
+	require.NoError(t, err)
+	assert.Equal(t, int64(258), id)
+	assert.Equal(t, []byte{42}, data)
+
+	assert.Equal(t, []byte{0, 0, 0, 1, 2, 42}, WireFormat(258, []byte{42}))
+
+	_, _, err = SplitWireFormat([]byte{1, 0, 0, 1, 2, 42})
+	assert.Equal(t, ErrNotWireFormat, err)
+	_, _, err = SplitWireFormat([]byte{0, 0})
+	assert.Equal(t, ErrNotWireFormat, err)
+}
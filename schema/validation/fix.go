@@ -0,0 +1,110 @@
+/* Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/elodina/go-avro"
+)
+
+// Fix actions. Field actions apply to the field at the path, the others to the schema at the path.
+const (
+	FixAddDefault        = "ADD_DEFAULT"
+	FixMakeNullable      = "MAKE_NULLABLE"
+	FixAddAlias          = "ADD_ALIAS"
+	FixAddField          = "ADD_FIELD"
+	FixAddEnumSymbol     = "ADD_ENUM_SYMBOL"
+	FixRemoveEnumSymbol  = "REMOVE_ENUM_SYMBOL"
+	FixRename            = "RENAME"
+	FixSetSize           = "SET_SIZE"
+	FixReplaceType       = "REPLACE_TYPE"
+	FixAddUnionBranch    = "ADD_UNION_BRANCH"
+	FixRemoveUnionBranch = "REMOVE_UNION_BRANCH"
+)
+
+// Fix is a change to the schema being checked that resolves an incompatibility. Path points into that schema
+// the way Incompatibility.Path does, Value is the JSON the action needs: a default, an alias, a symbol, a schema.
+// ADD_ALIAS applies to a field if the path ends at a field and to a named type otherwise.
+type Fix struct {
+	Action      string          `json:"action"`
+	Path        string          `json:"path"`
+	Value       json.RawMessage `json:"value,omitempty"`
+	Description string          `json:"description"`
+
+	// writer tells if the fix is to the writer schema rather than the reader one
+	writer bool
+}
+
+func newFix(action string, path string, value interface{}, writer bool, format string, args ...interface{}) *Fix {
+	fix := &Fix{
+		Action:      action,
+		Path:        path,
+		Description: fmt.Sprintf(format, args...),
+		writer:      writer,
+	}
+	if value != nil || action == FixAddDefault {
+		fix.Value = json.RawMessage(fragment(value))
+	}
+	return fix
+}
+
+// fixing keeps only the fixes to the writer schema, or to the reader schema, of every incompatibility:
+// the fixes to the schema that is checked against a registered one.
+func (i Incompatibilities) fixing(writer bool) Incompatibilities {
+	for _, incompatibility := range i {
+		fixes := make([]*Fix, 0, len(incompatibility.Fixes))
+		for _, fix := range incompatibility.Fixes {
+			if fix.writer == writer {
+				fixes = append(fixes, fix)
+			}
+		}
+		incompatibility.Fixes = fixes
+	}
+	return i
+}
+
+// zeroValue returns a default value for a schema, if it has an obvious one.
+func zeroValue(schema avro.Schema) (interface{}, bool) {
+	switch typed := actual(schema).(type) {
+	case *avro.EnumSchema:
+		if len(typed.Symbols) > 0 {
+			return typed.Symbols[0], true
+		}
+		return nil, false
+	case *avro.UnionSchema:
+		if len(typed.Types) > 0 {
+			return zeroValue(typed.Types[0])
+		}
+		return nil, false
+	}
+	switch schema.Type() {
+	case avro.Null:
+		return nil, true
+	case avro.Boolean:
+		return false, true
+	case avro.Int, avro.Long, avro.Float, avro.Double:
+		return 0, true
+	case avro.String, avro.Bytes:
+		return "", true
+	case avro.Array:
+		return []interface{}{}, true
+	case avro.Map:
+		return map[string]interface{}{}, true
+	}
+	return nil, false
+}
@@ -0,0 +1,137 @@
+/* Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package validation
+
+import (
+	"testing"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+	"gopkg.in/stretchr/testify.v1/require"
+)
+
+func fixActions(fixes []*Fix) []string {
+	actions := make([]string, len(fixes))
+	for i, fix := range fixes {
+		actions[i] = fix.Action + " " + fix.Path + " " + string(fix.Value)
+	}
+	return actions
+}
+
+// patchUntilCompatible applies the first fix of every incompatibility until the checker passes.
+func patchUntilCompatible(t *testing.T, checker CompatibilityChecker, existing string, toValidate string) string {
+	existingSchema, err := ParseSchema(existing)
+	require.NoError(t, err)
+	for round := 0; round < 3; round++ {
+		schema, err := ParseSchema(toValidate)
+		require.NoError(t, err, toValidate)
+		err = checker.Validate(schema, existingSchema)
+		if err == nil {
+			return toValidate
+		}
+		fixes := make([]*Fix, 0)
+		for _, incompatibility := range err.(Incompatibilities) {
+			require.NotEmpty(t, incompatibility.Fixes, incompatibility.Message)
+			fixes = append(fixes, incompatibility.Fixes[0])
+		}
+		toValidate, err = Patch(toValidate, fixes)
+		require.NoError(t, err)
+	}
+	t.Fatalf("Patched schema is still incompatible: %s", toValidate)
+	return ""
+}
+
+const fixWriter = `{"type": "record", "name": "Person", "namespace": "com.acme", "fields": [
+     {"name": "name", "type": "string"},
+     {"name": "age", "type": "int"},
+     {"name": "suit", "type": {"type": "enum", "name": "Suit", "symbols": ["SPADES", "HEARTS"]}},
+     {"name": "hash", "type": {"type": "fixed", "name": "Hash", "size": 4}},
+     {"name": "home", "type": {"type": "record", "name": "Address", "fields": [{"name": "street", "type": "string"}]}},
+     {"name": "work", "type": ["null", "Address"]},
+     {"name": "score", "type": ["int", "boolean"]}
+ ]}`
+
+const fixReader = `{"type": "record", "name": "Person", "namespace": "com.acme", "fields": [
+     {"name": "full_name", "type": "string"},
+     {"name": "age", "type": "string"},
+     {"name": "suit", "type": {"type": "enum", "name": "Suit", "symbols": ["SPADES"]}},
+     {"name": "hash", "type": {"type": "fixed", "name": "Hash", "size": 8}},
+     {"name": "home", "type": {"type": "record", "name": "Location", "fields": [{"name": "street", "type": "string"}]}},
+     {"name": "work", "type": ["null", "Location"]},
+     {"name": "score", "type": ["int"]},
+     {"name": "email", "type": "string"}
+ ]}`
+
+func TestReaderFixes(t *testing.T) {
+	reader, err := ParseSchema(fixReader)
+	require.NoError(t, err)
+	writer, err := ParseSchema(fixWriter)
+	require.NoError(t, err)
+	found := check(writer, reader).fixing(false)
+	require.Len(t, found, 8)
+
+	assert.Equal(t, []string{
+		`ADD_ALIAS /fields/full_name "name"`,
+		`MAKE_NULLABLE /fields/full_name `,
+		`ADD_DEFAULT /fields/full_name ""`,
+	}, fixActions(found[0].Fixes))
+	assert.Equal(t, []string{`REPLACE_TYPE /fields/age/type "int"`}, fixActions(found[1].Fixes))
+	assert.Equal(t, []string{`ADD_ENUM_SYMBOL /fields/suit/type "HEARTS"`}, fixActions(found[2].Fixes))
+	assert.Equal(t, []string{`SET_SIZE /fields/hash/type 4`}, fixActions(found[3].Fixes))
+	assert.Equal(t, []string{`ADD_ALIAS /fields/home/type "com.acme.Address"`}, fixActions(found[4].Fixes))
+	assert.Equal(t, `ADD_ALIAS /fields/work/type/1 "com.acme.Address"`, fixActions(found[5].Fixes)[0])
+	assert.Regexp(t, `^ADD_UNION_BRANCH /fields/work/type {"type":"record"`, fixActions(found[5].Fixes)[1])
+	assert.Equal(t, []string{`ADD_UNION_BRANCH /fields/score/type "boolean"`}, fixActions(found[6].Fixes))
+	assert.Equal(t, []string{`MAKE_NULLABLE /fields/email `, `ADD_DEFAULT /fields/email ""`}, fixActions(found[7].Fixes))
+}
+
+func TestWriterFixes(t *testing.T) {
+	reader, err := ParseSchema(fixReader)
+	require.NoError(t, err)
+	writer, err := ParseSchema(fixWriter)
+	require.NoError(t, err)
+	found := check(writer, reader).fixing(true)
+
+	assert.Equal(t, []string{`ADD_FIELD /fields {"name":"full_name","type":"string"}`}, fixActions(found[0].Fixes))
+	assert.Equal(t, []string{`REPLACE_TYPE /fields/age/type "string"`}, fixActions(found[1].Fixes))
+	assert.Equal(t, []string{`REMOVE_ENUM_SYMBOL /fields/suit/type "HEARTS"`}, fixActions(found[2].Fixes))
+	assert.Equal(t, []string{`RENAME /fields/work/type/1 "com.acme.Location"`, `REMOVE_UNION_BRANCH /fields/work/type/1 `}, fixActions(found[5].Fixes))
+	assert.Equal(t, []string{`REMOVE_UNION_BRANCH /fields/score/type/1 `}, fixActions(found[6].Fixes))
+}
+
+func TestPatchBackward(t *testing.T) {
+	patched := patchUntilCompatible(t, NewBackwardCompatibility(), fixWriter, fixReader)
+	names, err := NamedTypes(patched)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"com.acme.Person", "com.acme.Suit", "com.acme.Hash", "com.acme.Location"}, names)
+	assert.Contains(t, patched, `"aliases":["name"]`)
+}
+
+func TestPatchForward(t *testing.T) {
+	patchUntilCompatible(t, NewForwardCompatibility(), fixReader, fixWriter)
+}
+
+func TestPatchFull(t *testing.T) {
+	patchUntilCompatible(t, NewFullCompatibility(),
+		`{"type": "record", "name": "Person", "fields": [{"name": "name", "type": "string"}]}`,
+		`{"type": "record", "name": "Person", "fields": [{"name": "name", "type": "string"}, {"name": "age", "type": "int"}]}`)
+}
+
+func TestPatchErrors(t *testing.T) {
+	_, err := Patch(fixReader, []*Fix{{Action: FixAddDefault, Path: "/fields/missing", Value: []byte("0")}})
+	assert.Error(t, err)
+	_, err = Patch(fixReader, []*Fix{{Action: FixAddEnumSymbol, Path: "/fields/age/type", Value: []byte(`"A"`)}})
+	assert.Error(t, err)
+}
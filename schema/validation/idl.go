@@ -0,0 +1,645 @@
+/* Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	idlEOF = iota
+	idlIdentifier
+	idlAnnotation
+	idlString
+	idlNumber
+	idlSymbol
+)
+
+// idlLogicalTypes are the IDL shorthands for logical types, with the type they annotate.
+var idlLogicalTypes = map[string][2]string{
+	"date":               {"int", Date},
+	"time_ms":            {"int", TimeMillis},
+	"timestamp_ms":       {"long", TimestampMillis},
+	"local_timestamp_ms": {"long", LocalTimestampMillis},
+	"uuid":               {"string", UUID},
+}
+
+type idlToken struct {
+	kind   int
+	text   string
+	offset int
+	// doc is the doc comment right before the token
+	doc    string
+	quoted bool
+}
+
+// typeRef is a reference to a named type, resolved to its full name once the whole protocol is parsed.
+type typeRef struct {
+	name      string
+	namespace string
+	offset    int
+	full      string
+}
+
+type idlProperty struct {
+	name   string
+	value  interface{}
+	offset int
+}
+
+// ParseIDL parses an Avro IDL protocol. Errors are *SchemaError with the line and column they were found at.
+// Imports are not supported, the protocol has to define every type it uses.
+func ParseIDL(source string) (protocol *Protocol, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			schemaError, ok := r.(*SchemaError)
+			if !ok {
+				panic(r)
+			}
+			protocol, err = nil, schemaError
+		}
+	}()
+	parser := &idlParser{source: source}
+	parser.advance()
+	protocol = parser.protocolDeclaration()
+	parser.resolve()
+	return protocol, nil
+}
+
+// idlParser is a recursive descent parser of IDL, failing with a panic the parse recovers from.
+type idlParser struct {
+	source   string
+	pos      int
+	tok      idlToken
+	protocol *Protocol
+	refs     []*typeRef
+}
+
+func (p *idlParser) fail(offset int, format string, args ...interface{}) {
+	line, column := lineColumn(p.source, offset)
+	panic(&SchemaError{Line: line, Column: column, Message: fmt.Sprintf(format, args...)})
+}
+
+func (p *idlParser) unexpected(expected string) {
+	found := strconv.Quote(p.tok.text)
+	if p.tok.kind == idlEOF {
+		found = "end of input"
+	} else if p.tok.kind == idlAnnotation {
+		found = strconv.Quote("@" + p.tok.text)
+	}
+	p.fail(p.tok.offset, "Expected %s, found %s", expected, found)
+}
+
+// advance moves to the next token, returning the current one.
+func (p *idlParser) advance() idlToken {
+	current := p.tok
+	p.tok = p.scan()
+	return current
+}
+
+// is tells whether the current token is a symbol or an unquoted keyword.
+func (p *idlParser) is(text string) bool {
+	return (p.tok.kind == idlSymbol || p.tok.kind == idlIdentifier && !p.tok.quoted) && p.tok.text == text
+}
+
+func (p *idlParser) expect(text string) idlToken {
+	if !p.is(text) {
+		p.unexpected(strconv.Quote(text))
+	}
+	return p.advance()
+}
+
+func (p *idlParser) identifier() idlToken {
+	if p.tok.kind != idlIdentifier {
+		p.unexpected("a name")
+	}
+	return p.advance()
+}
+
+func (p *idlParser) number() json.Number {
+	if p.tok.kind != idlNumber {
+		p.unexpected("a number")
+	}
+	if _, err := strconv.Atoi(p.tok.text); err != nil {
+		p.unexpected("an integer")
+	}
+	return json.Number(p.advance().text)
+}
+
+func (p *idlParser) scan() idlToken {
+	doc := ""
+	for {
+		for p.pos < len(p.source) && strings.IndexByte(" \t\r\n\f", p.source[p.pos]) >= 0 {
+			p.pos++
+		}
+		rest := p.source[p.pos:]
+		if strings.HasPrefix(rest, "//") {
+			end := strings.IndexByte(rest, '\n')
+			if end < 0 {
+				end = len(rest)
+			}
+			p.pos += end
+			continue
+		}
+		if strings.HasPrefix(rest, "/*") {
+			end := strings.Index(rest[2:], "*/")
+			if end < 0 {
+				p.fail(p.pos, "Unterminated comment")
+			}
+			comment := rest[:end+4]
+			if strings.HasPrefix(comment, "/**") && comment != "/**/" {
+				doc = docComment(comment)
+			}
+			p.pos += len(comment)
+			continue
+		}
+		break
+	}
+
+	token := idlToken{offset: p.pos, doc: doc}
+	if p.pos >= len(p.source) {
+		token.kind = idlEOF
+		return token
+	}
+	switch c := p.source[p.pos]; {
+	case c == '@':
+		p.pos++
+		for p.pos < len(p.source) && (identifierPart(p.source[p.pos]) || strings.IndexByte(".-", p.source[p.pos]) >= 0) {
+			p.pos++
+		}
+		token.kind, token.text = idlAnnotation, p.source[token.offset+1:p.pos]
+		if token.text == "" {
+			p.fail(token.offset, "Expected a property name after @")
+		}
+	case c == '`':
+		end := strings.IndexByte(p.source[p.pos+1:], '`')
+		if end < 0 {
+			p.fail(p.pos, "Unterminated quoted name")
+		}
+		token.kind, token.text, token.quoted = idlIdentifier, p.source[p.pos+1:p.pos+1+end], true
+		p.pos += end + 2
+	case identifierStart(c):
+		for p.pos < len(p.source) && (identifierPart(p.source[p.pos]) ||
+			p.source[p.pos] == '.' && p.pos+1 < len(p.source) && identifierStart(p.source[p.pos+1])) {
+			p.pos++
+		}
+		token.kind, token.text = idlIdentifier, p.source[token.offset:p.pos]
+	case c == '"':
+		for p.pos++; p.pos < len(p.source) && p.source[p.pos] != '"'; p.pos++ {
+			if p.source[p.pos] == '\\' {
+				p.pos++
+			} else if p.source[p.pos] == '\n' {
+				break
+			}
+		}
+		if p.pos >= len(p.source) || p.source[p.pos] != '"' {
+			p.fail(token.offset, "Unterminated string")
+		}
+		p.pos++
+		token.kind, token.text = idlString, p.source[token.offset:p.pos]
+	case c == '-' || c >= '0' && c <= '9':
+		for p.pos++; p.pos < len(p.source) && (identifierPart(p.source[p.pos]) || strings.IndexByte(".+-", p.source[p.pos]) >= 0); p.pos++ {
+		}
+		token.kind, token.text = idlNumber, p.source[token.offset:p.pos]
+	case strings.IndexByte("{}()[]<>,;=?:", c) >= 0:
+		p.pos++
+		token.kind, token.text = idlSymbol, string(c)
+	default:
+		p.fail(p.pos, "Unexpected character %q", c)
+	}
+	return token
+}
+
+func identifierStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func identifierPart(c byte) bool {
+	return identifierStart(c) || c >= '0' && c <= '9'
+}
+
+// docComment returns the text of a /** */ comment without the leading asterisks of its lines.
+func docComment(comment string) string {
+	lines := strings.Split(comment[3:len(comment)-2], "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "*"))
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// json parses the JSON value starting at the current token.
+func (p *idlParser) json() interface{} {
+	start := p.tok.offset
+	end := jsonEnd(p.source, start)
+	decoder := json.NewDecoder(strings.NewReader(p.source[start:end]))
+	decoder.UseNumber()
+	value, err := decodeOrdered(decoder)
+	if err != nil {
+		offset := start
+		if syntaxError, ok := err.(*json.SyntaxError); ok && syntaxError.Offset > 0 {
+			offset += int(syntaxError.Offset) - 1
+		}
+		p.fail(offset, "Invalid JSON value: %s", err)
+	}
+	p.pos = end
+	p.tok = p.scan()
+	return value
+}
+
+// jsonEnd returns where the JSON value starting at start ends, the decoder finds what is wrong with it.
+func jsonEnd(source string, start int) int {
+	depth := 0
+	for i := start; i < len(source); i++ {
+		switch c := source[i]; c {
+		case '"':
+			for i++; i < len(source) && source[i] != '"'; i++ {
+				if source[i] == '\\' {
+					i++
+				}
+			}
+			if depth == 0 {
+				if i >= len(source) {
+					return len(source)
+				}
+				return i + 1
+			}
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+			if depth <= 0 {
+				return i + 1
+			}
+		default:
+			if depth == 0 && !identifierPart(c) && strings.IndexByte(".+-", c) < 0 {
+				return i
+			}
+		}
+	}
+	return len(source)
+}
+
+// properties parses the @name(value) annotations before a declaration.
+func (p *idlParser) properties() []*idlProperty {
+	properties := make([]*idlProperty, 0)
+	for p.tok.kind == idlAnnotation {
+		token := p.advance()
+		p.expect("(")
+		value := p.json()
+		p.expect(")")
+		properties = append(properties, &idlProperty{token.text, value, token.offset})
+	}
+	return properties
+}
+
+func (p *idlParser) stringProperty(property *idlProperty) string {
+	value, ok := property.value.(string)
+	if !ok {
+		p.fail(property.offset, "@%s must be a string", property.name)
+	}
+	return value
+}
+
+func (p *idlParser) aliasesProperty(property *idlProperty) []interface{} {
+	aliases, ok := property.value.([]interface{})
+	for _, alias := range aliases {
+		if _, isString := alias.(string); !isString {
+			ok = false
+		}
+	}
+	if !ok {
+		p.fail(property.offset, "@%s must be a list of names", property.name)
+	}
+	return aliases
+}
+
+func (p *idlParser) protocolDeclaration() *Protocol {
+	properties := p.properties()
+	p.expect("protocol")
+	name := p.identifier()
+	namespace := ""
+	for _, property := range properties {
+		if property.name == "namespace" {
+			namespace = p.stringProperty(property)
+		}
+	}
+	p.protocol = newProtocol(name.text, namespace)
+	p.expect("{")
+	for !p.is("}") {
+		p.declaration()
+	}
+	p.advance()
+	if p.tok.kind != idlEOF {
+		p.unexpected("end of input")
+	}
+	return p.protocol
+}
+
+func (p *idlParser) declaration() {
+	doc := p.tok.doc
+	properties := p.properties()
+	switch {
+	case p.is("import"):
+		p.fail(p.tok.offset, "Imports are not supported, the protocol has to define every type it uses")
+	case p.is("record"), p.is("error"):
+		p.record(properties, doc)
+	case p.is("enum"):
+		p.enum(properties, doc)
+	case p.is("fixed"):
+		p.fixed(properties, doc)
+	default:
+		p.message()
+	}
+}
+
+// named parses the keyword and the name of a named type and returns the start of its definition and its full name.
+func (p *idlParser) named(properties []*idlProperty, doc string) (Record, string) {
+	kind := p.advance().text
+	name := p.identifier()
+	namespace := p.protocol.Namespace
+	definition := Record{{"type", kind}, {"name", name.text}}
+	extra := make(Record, 0)
+	for _, property := range properties {
+		switch property.name {
+		case "namespace":
+			namespace = p.stringProperty(property)
+		case aliasesProperty:
+			extra = append(extra, &RecordField{aliasesProperty, p.aliasesProperty(property)})
+		default:
+			extra = append(extra, &RecordField{property.name, property.value})
+		}
+	}
+	full := fullName(name.text, namespace)
+	if _, ok := p.protocol.definitions[full]; ok {
+		p.fail(name.offset, "Type %s is defined more than once", full)
+	}
+	if full != name.text {
+		definition = append(definition, &RecordField{"namespace", namespaceOf(full)})
+	}
+	if doc != "" {
+		definition = append(definition, &RecordField{"doc", doc})
+	}
+	return append(definition, extra...), full
+}
+
+func (p *idlParser) record(properties []*idlProperty, doc string) {
+	definition, full := p.named(properties, doc)
+	p.protocol.define(full, nil)
+	p.expect("{")
+	fields := make([]interface{}, 0)
+	for !p.is("}") {
+		fields = append(fields, p.fields(namespaceOf(full))...)
+	}
+	p.advance()
+	p.protocol.definitions[full] = append(definition, &RecordField{"fields", fields})
+}
+
+func (p *idlParser) enum(properties []*idlProperty, doc string) {
+	definition, full := p.named(properties, doc)
+	p.expect("{")
+	symbols := make([]string, 0)
+	for !p.is("}") {
+		if len(symbols) > 0 {
+			p.expect(",")
+		}
+		symbols = append(symbols, p.identifier().text)
+	}
+	p.advance()
+	definition = append(definition, &RecordField{"symbols", symbols})
+	if p.is("=") {
+		p.advance()
+		symbol := p.identifier()
+		if !contains(symbols, symbol.text) {
+			p.fail(symbol.offset, "Default %s is not a symbol of %s", symbol.text, full)
+		}
+		definition = append(definition, &RecordField{"default", symbol.text})
+	}
+	if p.is(";") {
+		p.advance()
+	}
+	p.protocol.define(full, definition)
+}
+
+func (p *idlParser) fixed(properties []*idlProperty, doc string) {
+	definition, full := p.named(properties, doc)
+	p.expect("(")
+	size := p.number()
+	p.expect(")")
+	p.expect(";")
+	p.protocol.define(full, append(definition, &RecordField{"size", size}))
+}
+
+// fields parses a field declaration, which can declare several fields of the same type.
+// Properties before the type annotate the type, except the field order.
+func (p *idlParser) fields(namespace string) []interface{} {
+	doc := p.tok.doc
+	var order interface{}
+	typeProperties := make([]*idlProperty, 0)
+	for _, property := range p.properties() {
+		if property.name == "order" {
+			order = p.stringProperty(property)
+		} else {
+			typeProperties = append(typeProperties, property)
+		}
+	}
+	fieldType, optional := p.fieldType(namespace)
+	fieldType = p.annotate(fieldType, typeProperties)
+	fields := []interface{}{p.variable(fieldType, optional, doc, order)}
+	for p.is(",") {
+		p.advance()
+		fields = append(fields, p.variable(fieldType, optional, "", order))
+	}
+	p.expect(";")
+	return fields
+}
+
+// variable parses a field name with its properties and default. An optional type becomes a union with null,
+// null coming second if the default isn't null.
+func (p *idlParser) variable(fieldType interface{}, optional bool, doc string, order interface{}) Record {
+	if doc == "" {
+		doc = p.tok.doc
+	}
+	properties := p.properties()
+	name := p.identifier()
+	field := Record{{"name", name.text}, {"type", fieldType}}
+	if doc != "" {
+		field = append(field, &RecordField{"doc", doc})
+	}
+	var defaultValue interface{}
+	if p.is("=") {
+		p.advance()
+		offset := p.tok.offset
+		defaultValue = p.json()
+		if message := integerDefault(fieldType, defaultValue); message != "" {
+			p.fail(offset, "%s", message)
+		}
+		field = append(field, &RecordField{"default", defaultValue})
+	}
+	if optional {
+		if defaultValue == nil {
+			field[1].Value = []interface{}{"null", fieldType}
+		} else {
+			field[1].Value = []interface{}{fieldType, "null"}
+		}
+	}
+	for _, property := range properties {
+		switch property.name {
+		case "order":
+			order = p.stringProperty(property)
+		case aliasesProperty:
+			field = append(field, &RecordField{aliasesProperty, p.aliasesProperty(property)})
+		default:
+			field = append(field, &RecordField{property.name, property.value})
+		}
+	}
+	if order != nil {
+		field = append(field, &RecordField{"order", order})
+	}
+	return field
+}
+
+// annotate sets properties on a primitive, an array or a map. Named types can only be annotated where defined.
+func (p *idlParser) annotate(schema interface{}, properties []*idlProperty) interface{} {
+	if len(properties) == 0 {
+		return schema
+	}
+	var annotated Record
+	switch typed := schema.(type) {
+	case string:
+		annotated = Record{{"type", typed}}
+	case Record:
+		annotated = append(annotated, typed...)
+	default:
+		p.fail(properties[0].offset, "Properties can only be set on primitives, arrays and maps or where a type is defined")
+	}
+	for _, property := range properties {
+		annotated = append(annotated, &RecordField{property.name, property.value})
+	}
+	return annotated
+}
+
+// fieldType parses a type and the ? making it optional.
+func (p *idlParser) fieldType(namespace string) (interface{}, bool) {
+	schema := p.schema(namespace)
+	if p.is("?") {
+		p.advance()
+		return schema, true
+	}
+	return schema, false
+}
+
+func (p *idlParser) schema(namespace string) interface{} {
+	if p.tok.kind != idlIdentifier {
+		p.unexpected("a type")
+	}
+	if !p.tok.quoted {
+		switch p.tok.text {
+		case "union":
+			p.advance()
+			p.expect("{")
+			branches := []interface{}{p.schema(namespace)}
+			for p.is(",") {
+				p.advance()
+				branches = append(branches, p.schema(namespace))
+			}
+			p.expect("}")
+			return branches
+		case "array":
+			p.advance()
+			p.expect("<")
+			items := p.schema(namespace)
+			p.expect(">")
+			return Record{{"type", "array"}, {"items", items}}
+		case "map":
+			p.advance()
+			p.expect("<")
+			values := p.schema(namespace)
+			p.expect(">")
+			return Record{{"type", "map"}, {"values", values}}
+		case Decimal:
+			p.advance()
+			p.expect("(")
+			precision := p.number()
+			p.expect(",")
+			scale := p.number()
+			p.expect(")")
+			return Record{{"type", "bytes"}, {logicalTypeProperty, Decimal}, {precisionProperty, precision}, {scaleProperty, scale}}
+		}
+		if logical, ok := idlLogicalTypes[p.tok.text]; ok {
+			p.advance()
+			return Record{{"type", logical[0]}, {logicalTypeProperty, logical[1]}}
+		}
+		if primitiveTypes[p.tok.text] {
+			return p.advance().text
+		}
+	}
+	return p.reference(namespace)
+}
+
+func (p *idlParser) reference(namespace string) *typeRef {
+	name := p.identifier()
+	ref := &typeRef{name: name.text, namespace: namespace, offset: name.offset}
+	p.refs = append(p.refs, ref)
+	return ref
+}
+
+// message parses a protocol message, only to check it. Its types must be defined like those of fields.
+func (p *idlParser) message() {
+	namespace := p.protocol.Namespace
+	if p.is("void") {
+		p.advance()
+	} else {
+		p.fieldType(namespace)
+	}
+	p.identifier()
+	p.expect("(")
+	for !p.is(")") {
+		if p.tok.kind == idlEOF || p.is(";") {
+			p.unexpected(`")"`)
+		}
+		p.properties()
+		p.fieldType(namespace)
+		p.variable(nil, false, "", nil)
+		if !p.is(")") {
+			p.expect(",")
+		}
+	}
+	p.advance()
+	if p.is("oneway") {
+		p.advance()
+	} else if p.is("throws") {
+		p.advance()
+		p.reference(namespace)
+		for p.is(",") {
+			p.advance()
+			p.reference(namespace)
+		}
+	}
+	p.expect(";")
+}
+
+// resolve sets the full names of the types referred to, once every type is defined.
+func (p *idlParser) resolve() {
+	for _, ref := range p.refs {
+		ref.full = p.protocol.resolve(ref.name, ref.namespace)
+		if ref.full == "" {
+			p.fail(ref.offset, "Undefined type %s", ref.name)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+/* Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package validation
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+	"gopkg.in/stretchr/testify.v1/require"
+)
+
+const peopleIDL = `/** People and their logins. */
+@namespace("com.acme")
+protocol People {
+  /** A hash. */
+  fixed MD5(16);
+
+  @namespace("com.acme.auth")
+  record Login {
+    string name;
+    com.acme.MD5 hash;
+  }
+
+  enum Role { ADMIN, USER } = USER;
+
+  error NotFound { string message; }
+
+  record Person {
+    /** The id */
+    long id;
+    string? email = null;
+    @order("descending") int @aliases(["years"]) age = 0, height = 170;
+    union { null, com.acme.auth.Login } login = null;
+    array<Role> roles = [];
+    map<com.acme.auth.Login> logins;
+    decimal(9,2) balance;
+    timestamp_ms created;
+    @logicalType("timestamp-micros") long updated;
+    Person? manager;
+  }
+
+  Person get(long id) throws NotFound;
+  void ping() oneway;
+}`
+
+func TestParseIDL(t *testing.T) {
+	protocol, err := ParseIDL(peopleIDL)
+	require.NoError(t, err)
+	assert.Equal(t, "People", protocol.Name)
+	assert.Equal(t, "com.acme", protocol.Namespace)
+	assert.Equal(t, []string{"com.acme.MD5", "com.acme.auth.Login", "com.acme.Role", "com.acme.NotFound", "com.acme.Person"}, protocol.Types)
+
+	_, err = protocol.Schema("")
+	assert.Error(t, err, "two records to choose from")
+
+	schema, err := protocol.Schema("Person")
+	require.NoError(t, err)
+	assert.Empty(t, CheckSchema(schema))
+
+	var person map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(schema), &person))
+	assert.Equal(t, "com.acme", person["namespace"])
+	fields := person["fields"].([]interface{})
+	require.Len(t, fields, 11)
+	assert.Equal(t, "The id", fields[0].(map[string]interface{})["doc"])
+	assert.Equal(t, []interface{}{"null", "string"}, fields[1].(map[string]interface{})["type"])
+	assert.Equal(t, map[string]interface{}{"name": "height", "type": "int", "default": 170.0, "order": "descending"}, fields[3])
+	assert.Equal(t, []interface{}{"years"}, fields[2].(map[string]interface{})["aliases"])
+
+	login := fields[4].(map[string]interface{})["type"].([]interface{})[1].(map[string]interface{})
+	assert.Equal(t, "com.acme.auth", login["namespace"])
+	hash := login["fields"].([]interface{})[1].(map[string]interface{})["type"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"type": "fixed", "name": "MD5", "namespace": "com.acme", "doc": "A hash.", "size": 16.0}, hash)
+	assert.Equal(t, map[string]interface{}{"type": "map", "values": "com.acme.auth.Login"}, fields[6].(map[string]interface{})["type"])
+	assert.Equal(t, map[string]interface{}{"type": "bytes", "logicalType": "decimal", "precision": 9.0, "scale": 2.0},
+		fields[7].(map[string]interface{})["type"])
+	assert.Equal(t, map[string]interface{}{"type": "long", "logicalType": "timestamp-micros"}, fields[9].(map[string]interface{})["type"])
+	assert.Equal(t, []interface{}{"null", "com.acme.Person"}, fields[10].(map[string]interface{})["type"])
+
+	schema, err = protocol.Schema("com.acme.NotFound")
+	require.NoError(t, err)
+	assert.Contains(t, schema, `"type": "record"`)
+}
+
+func TestParseIDLErrors(t *testing.T) {
+	cases := []struct {
+		source  string
+		line    int
+		column  int
+		message string
+	}{
+		{"protocol P {\n  record A { B b; }\n}", 2, 14, "Undefined type B"},
+		{"protocol P {\n  record A { int a }\n}", 2, 20, `Expected ";", found "}"`},
+		{"protocol P {\n  record A { int a = {\"x\": }; }\n}", 2, 28, "Invalid JSON value"},
+		{"protocol P {\n  /* open", 2, 3, "Unterminated comment"},
+		{"protocol P {\n  import idl \"other.avdl\";\n}", 2, 3, "Imports are not supported, the protocol has to define every type it uses"},
+		{"protocol P {\n  record A {}\n  record A {}\n}", 3, 10, "Type A is defined more than once"},
+		{"protocol P {\n  enum E { A } = B;\n}", 2, 18, "Default B is not a symbol of E"},
+		{"protocol P {\n  record A { int a = 99999999999; }\n}", 2, 22, "Default 99999999999 is not a valid int"},
+		{"protocol P {\n  record A { union { long, null } a = 1.5; }\n}", 2, 39, "Default 1.5 is not a valid long"},
+		{"protocol P {\n  record A { P.A? a; }", 2, 23, "Expected a type, found end of input"},
+		{"record A {}", 1, 1, `Expected "protocol", found "record"`},
+	}
+	for _, c := range cases {
+		_, err := ParseIDL(c.source)
+		require.Error(t, err, c.source)
+		schemaError, ok := err.(*SchemaError)
+		require.True(t, ok, c.source)
+		assert.Equal(t, c.line, schemaError.Line, c.source)
+		assert.Equal(t, c.column, schemaError.Column, c.source)
+		assert.Contains(t, schemaError.Message, c.message, c.source)
+	}
+}
@@ -0,0 +1,86 @@
+/* Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Kinds of incompatibility, named the way the Java schema registry reports them. Java ignores logical types
+// and has no KEY_STABLE level, LOGICAL_TYPE_MISMATCH and ENCODING_CHANGE are our own.
+const (
+	NameMismatch                   = "NAME_MISMATCH"
+	FixedSizeMismatch              = "FIXED_SIZE_MISMATCH"
+	MissingEnumSymbols             = "MISSING_ENUM_SYMBOLS"
+	ReaderFieldMissingDefaultValue = "READER_FIELD_MISSING_DEFAULT_VALUE"
+	TypeMismatch                   = "TYPE_MISMATCH"
+	MissingUnionBranch             = "MISSING_UNION_BRANCH"
+	LogicalTypeMismatch            = "LOGICAL_TYPE_MISMATCH"
+	EncodingChange                 = "ENCODING_CHANGE"
+)
+
+// Incompatibility is a single reason data written with one schema can't be read with another.
+// Path points into the reader schema, e.g. /fields/address/type/fields/zip, and Reader and Writer
+// are the JSON of the reader and writer schemas at that point. Fixes suggest changes to the schema
+// being checked, the first one being the most likely.
+type Incompatibility struct {
+	Type    string `json:"type"`
+	Path    string `json:"path"`
+	Message string `json:"message"`
+	Reader  string `json:"reader"`
+	Writer  string `json:"writer"`
+	Fixes   []*Fix `json:"fixes,omitempty"`
+}
+
+// Incompatibilities is the error validation returns, it holds every incompatibility found and not just the first one.
+type Incompatibilities []*Incompatibility
+
+func (i Incompatibilities) Error() string {
+	messages := make([]string, len(i))
+	for index, incompatibility := range i {
+		messages[index] = fmt.Sprintf("%s: %s", incompatibility.Path, incompatibility.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (i Incompatibilities) err() error {
+	if len(i) == 0 {
+		return nil
+	}
+	return i
+}
+
+// fragment returns the compact JSON of a schema or a record field.
+func fragment(definition interface{}) string {
+	bytes, err := json.Marshal(definition)
+	if err != nil {
+		return fmt.Sprintf("%s", definition)
+	}
+	return string(bytes)
+}
+
+// joinPath appends a path found relative to a record to the location the record is at.
+func joinPath(location string, path string) string {
+	if location == "/" {
+		return path
+	}
+	if path == "/" {
+		return location
+	}
+	return location + path
+}
@@ -0,0 +1,123 @@
+/* Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package validation
+
+import (
+	"testing"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+	"gopkg.in/stretchr/testify.v1/require"
+)
+
+func incompatibilities(t *testing.T, writer string, reader string) Incompatibilities {
+	err := canRead(t, writer, reader)
+	require.NotNil(t, err)
+	found, ok := err.(Incompatibilities)
+	require.True(t, ok)
+	return found
+}
+
+func TestIncompatibilityPaths(t *testing.T) {
+	writer := `{"type": "record", "name": "Person", "fields": [
+     {"name": "age", "type": "int"},
+     {"name": "address", "type": {"type": "record", "name": "Address", "fields": [
+         {"name": "street", "type": "string"}
+     ]}},
+     {"name": "tags", "type": {"type": "array", "items": "long"}},
+     {"name": "suit", "type": {"type": "enum", "name": "Suit", "symbols": ["SPADES", "HEARTS", "CLUBS"]}}
+ ]}`
+	reader := `{"type": "record", "name": "Person", "fields": [
+     {"name": "age", "type": "string"},
+     {"name": "address", "type": {"type": "record", "name": "Address", "fields": [
+         {"name": "street", "type": "string"},
+         {"name": "zip", "type": "string"}
+     ]}},
+     {"name": "tags", "type": {"type": "array", "items": "int"}},
+     {"name": "suit", "type": {"type": "enum", "name": "Suit", "symbols": ["SPADES"]}}
+ ]}`
+
+	found := incompatibilities(t, writer, reader)
+	require.Len(t, found, 5)
+
+	assert.Equal(t, TypeMismatch, found[0].Type)
+	assert.Equal(t, "/fields/age/type", found[0].Path)
+	assert.Equal(t, "Found int, expecting string", found[0].Message)
+	assert.Equal(t, `"string"`, found[0].Reader)
+	assert.Equal(t, `"int"`, found[0].Writer)
+
+	assert.Equal(t, ReaderFieldMissingDefaultValue, found[1].Type)
+	assert.Equal(t, "/fields/address/type/fields/zip", found[1].Path)
+	assert.Equal(t, `{"name":"zip","type":"string"}`, found[1].Reader)
+
+	assert.Equal(t, TypeMismatch, found[2].Type)
+	assert.Equal(t, "/fields/tags/type/items", found[2].Path)
+
+	assert.Equal(t, MissingEnumSymbols, found[3].Type)
+	assert.Equal(t, "/fields/suit/type/symbols", found[3].Path)
+	assert.Equal(t, MissingEnumSymbols, found[4].Type)
+	assert.Regexp(t, "CLUBS", found[4].Message)
+}
+
+func TestIncompatibilityUnionPaths(t *testing.T) {
+	found := incompatibilities(t, `{"type": "map", "values": ["null", "int", "boolean"]}`, `{"type": "map", "values": ["string", "long"]}`)
+	require.Len(t, found, 2)
+	assert.Equal(t, MissingUnionBranch, found[0].Type)
+	assert.Equal(t, "/values", found[0].Path)
+	assert.Equal(t, `"null"`, found[0].Writer)
+	assert.Equal(t, MissingUnionBranch, found[1].Type)
+	assert.Equal(t, `"boolean"`, found[1].Writer)
+
+	found = incompatibilities(t, `{"type": "record", "name": "A", "fields": [{"name": "f", "type": {"type": "fixed", "name": "F", "size": 4}}]}`,
+		`{"type": "record", "name": "A", "fields": [{"name": "f", "type": ["null", {"type": "fixed", "name": "G", "size": 8}]}]}`)
+	require.Len(t, found, 1)
+	assert.Equal(t, "/fields/f/type", found[0].Path)
+
+	found = incompatibilities(t, `["null", {"type": "array", "items": "string"}]`, `["null", {"type": "array", "items": "int"}]`)
+	require.Len(t, found, 1)
+	assert.Equal(t, "/1/items", found[0].Path)
+}
+
+func TestIncompatibilityRecursivePaths(t *testing.T) {
+	writer := `{"type": "record", "name": "Node", "fields": [
+     {"name": "value", "type": "string"},
+     {"name": "left", "type": ["null", "Node"]},
+     {"name": "right", "type": ["null", "Node"]}
+ ]}`
+	reader := `{"type": "record", "name": "Node", "fields": [
+     {"name": "value", "type": "int"},
+     {"name": "left", "type": ["null", "Node"]},
+     {"name": "right", "type": ["null", "Node"]}
+ ]}`
+
+	found := incompatibilities(t, writer, reader)
+	require.Len(t, found, 1)
+	assert.Equal(t, "/fields/value/type", found[0].Path)
+}
+
+func TestIncompatibilitiesMutualRead(t *testing.T) {
+	writer, err := ParseSchema(`{"type": "record", "name": "A", "fields": [{"name": "a", "type": "long"}]}`)
+	require.Equal(t, nil, err)
+	reader, err := ParseSchema(`{"type": "record", "name": "A", "fields": [{"name": "a", "type": "int"}, {"name": "b", "type": "int"}]}`)
+	require.Equal(t, nil, err)
+
+	err = NewFullCompatibility().Validate(reader, writer)
+	require.NotNil(t, err)
+	found := err.(Incompatibilities)
+	require.Len(t, found, 2)
+	assert.Equal(t, TypeMismatch, found[0].Type)
+	assert.Equal(t, ReaderFieldMissingDefaultValue, found[1].Type)
+	assert.Equal(t, "/fields/a/type: Found long, expecting int; /fields/b: Introduced field b does not have default value which is required.", err.Error())
+}
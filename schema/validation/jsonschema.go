@@ -0,0 +1,372 @@
+/* Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var jsonSchemaTypes = map[string]bool{
+	"null": true, "boolean": true, "object": true, "array": true, "number": true, "integer": true, "string": true,
+}
+
+// JSONSchema is a parsed JSON Schema, draft-07. Only the keywords that restrict which documents are valid
+// are kept, annotations like title, default or format are not. const is kept as an enum of one value.
+type JSONSchema struct {
+	// Bool is set for the true and false schemas
+	Bool  *bool
+	Types []string
+	Enum  []interface{}
+
+	Properties           map[string]*JSONSchema
+	Required             []string
+	AdditionalProperties *JSONSchema
+	MinProperties        *int
+	MaxProperties        *int
+
+	// Items is set if every item has the same schema, TupleItems if each position has its own
+	Items           *JSONSchema
+	TupleItems      []*JSONSchema
+	AdditionalItems *JSONSchema
+	MinItems        *int
+	MaxItems        *int
+	UniqueItems     bool
+
+	Minimum          *float64
+	Maximum          *float64
+	ExclusiveMinimum *float64
+	ExclusiveMaximum *float64
+	MultipleOf       *float64
+
+	MinLength *int
+	MaxLength *int
+	Pattern   string
+
+	AllOf []*JSONSchema
+	AnyOf []*JSONSchema
+	OneOf []*JSONSchema
+	Not   *JSONSchema
+
+	// Ref is the $ref of the schema, which replaces every other keyword
+	Ref    string
+	target *JSONSchema
+	raw    interface{}
+}
+
+// ParseJSONSchema parses a JSON Schema. It fails only if the document is not JSON, is not a schema or has a $ref
+// that can't be resolved, keywords with invalid values are ignored and reported by CheckJSONSchema.
+// Only references within the document are supported.
+func ParseJSONSchema(rawSchema string) (*JSONSchema, error) {
+	schema, _, err := parseJSONSchema(rawSchema)
+	return schema, err
+}
+
+// CheckJSONSchema reports every keyword of a JSON Schema with an invalid value.
+func CheckJSONSchema(rawSchema string) SchemaErrors {
+	_, errors, err := parseJSONSchema(rawSchema)
+	if schemaError, ok := err.(*SchemaError); ok {
+		return SchemaErrors{schemaError}
+	}
+	return errors
+}
+
+func parseJSONSchema(rawSchema string) (*JSONSchema, SchemaErrors, error) {
+	var root interface{}
+	if err := json.Unmarshal([]byte(rawSchema), &root); err != nil {
+		return nil, nil, jsonError(rawSchema, err)
+	}
+	switch root.(type) {
+	case bool, map[string]interface{}:
+	default:
+		return nil, nil, &SchemaError{Path: "/", Message: "A JSON Schema must be an object or a boolean"}
+	}
+	parser := &jsonSchemaParser{root: root, parsed: make(map[string]*JSONSchema)}
+	schema := parser.parse(root, "")
+	for i := 0; i < len(parser.refs); i++ {
+		ref := parser.refs[i]
+		if !strings.HasPrefix(ref.Ref, "#") {
+			return nil, nil, &SchemaError{Path: jsonPath(ref.path + "/$ref"), Message: fmt.Sprintf("Reference %s is not within the schema, only those are supported", ref.Ref)}
+		}
+		pointer := ref.Ref[1:]
+		raw, ok := resolvePointer(root, pointer)
+		if !ok {
+			return nil, nil, &SchemaError{Path: jsonPath(ref.path + "/$ref"), Message: fmt.Sprintf("Reference %s can't be resolved", ref.Ref)}
+		}
+		ref.target = parser.parse(raw, pointer)
+	}
+	return schema, parser.errors, nil
+}
+
+type jsonSchemaRef struct {
+	*JSONSchema
+	path string
+}
+
+type jsonSchemaParser struct {
+	root interface{}
+	// parsed holds the schemas by their JSON pointer, references to a schema get the same one
+	parsed map[string]*JSONSchema
+	refs   []*jsonSchemaRef
+	errors SchemaErrors
+}
+
+func (p *jsonSchemaParser) report(path string, format string, args ...interface{}) {
+	p.errors = append(p.errors, &SchemaError{Path: jsonPath(path), Message: fmt.Sprintf(format, args...)})
+}
+
+func (p *jsonSchemaParser) parse(raw interface{}, path string) *JSONSchema {
+	if schema, ok := p.parsed[path]; ok {
+		return schema
+	}
+	schema := &JSONSchema{raw: raw}
+	p.parsed[path] = schema
+	definition, ok := raw.(map[string]interface{})
+	if !ok {
+		value, isBool := raw.(bool)
+		if !isBool {
+			p.report(path, "A schema must be an object or a boolean")
+			value = true
+		}
+		schema.Bool = &value
+		return schema
+	}
+	for _, keyword := range []string{"definitions", "$defs"} {
+		definitions, _ := definition[keyword].(map[string]interface{})
+		for _, name := range sortedKeys(definitions) {
+			p.parse(definitions[name], path+"/"+keyword+"/"+escapePointer(name))
+		}
+	}
+	if ref, ok := definition["$ref"]; ok {
+		if schema.Ref, ok = ref.(string); ok {
+			p.refs = append(p.refs, &jsonSchemaRef{schema, path})
+			return schema
+		}
+		p.report(path+"/$ref", "$ref must be a string")
+	}
+
+	schema.Types = p.types(definition["type"], path+"/type")
+	if enum, ok := definition["enum"]; ok {
+		if schema.Enum, ok = enum.([]interface{}); !ok || len(schema.Enum) == 0 {
+			p.report(path+"/enum", "enum must be a list of values")
+		}
+	}
+	if value, ok := definition["const"]; ok {
+		schema.Enum = []interface{}{value}
+	}
+
+	if properties, ok := definition["properties"]; ok {
+		propertyMap, isMap := properties.(map[string]interface{})
+		if !isMap {
+			p.report(path+"/properties", "properties must be an object")
+		}
+		schema.Properties = make(map[string]*JSONSchema)
+		for _, name := range sortedKeys(propertyMap) {
+			schema.Properties[name] = p.parse(propertyMap[name], path+"/properties/"+escapePointer(name))
+		}
+	}
+	if required, ok := definition["required"]; ok {
+		schema.Required = stringList(required)
+		if list, _ := required.([]interface{}); list == nil || len(list) != len(schema.Required) {
+			p.report(path+"/required", "required must be a list of property names")
+		}
+	}
+	schema.AdditionalProperties = p.subschema(definition, "additionalProperties", path)
+	schema.MinProperties = p.count(definition, "minProperties", path)
+	schema.MaxProperties = p.count(definition, "maxProperties", path)
+
+	switch items := definition["items"].(type) {
+	case nil:
+	case []interface{}:
+		schema.TupleItems = make([]*JSONSchema, len(items))
+		for i, item := range items {
+			schema.TupleItems[i] = p.parse(item, fmt.Sprintf("%s/items/%d", path, i))
+		}
+	default:
+		schema.Items = p.parse(items, path+"/items")
+	}
+	schema.AdditionalItems = p.subschema(definition, "additionalItems", path)
+	schema.MinItems = p.count(definition, "minItems", path)
+	schema.MaxItems = p.count(definition, "maxItems", path)
+	if unique, ok := definition["uniqueItems"]; ok {
+		if schema.UniqueItems, ok = unique.(bool); !ok {
+			p.report(path+"/uniqueItems", "uniqueItems must be a boolean")
+		}
+	}
+
+	schema.Minimum = p.number(definition, "minimum", path)
+	schema.Maximum = p.number(definition, "maximum", path)
+	// draft-04 exclusive bounds are booleans making minimum and maximum exclusive
+	if exclusive, ok := definition["exclusiveMinimum"].(bool); ok {
+		if exclusive {
+			schema.Minimum, schema.ExclusiveMinimum = nil, schema.Minimum
+		}
+	} else {
+		schema.ExclusiveMinimum = p.number(definition, "exclusiveMinimum", path)
+	}
+	if exclusive, ok := definition["exclusiveMaximum"].(bool); ok {
+		if exclusive {
+			schema.Maximum, schema.ExclusiveMaximum = nil, schema.Maximum
+		}
+	} else {
+		schema.ExclusiveMaximum = p.number(definition, "exclusiveMaximum", path)
+	}
+	schema.MultipleOf = p.number(definition, "multipleOf", path)
+	if schema.MultipleOf != nil && *schema.MultipleOf <= 0 {
+		p.report(path+"/multipleOf", "multipleOf must be greater than 0")
+		schema.MultipleOf = nil
+	}
+
+	schema.MinLength = p.count(definition, "minLength", path)
+	schema.MaxLength = p.count(definition, "maxLength", path)
+	if pattern, ok := definition["pattern"]; ok {
+		var isString bool
+		schema.Pattern, isString = pattern.(string)
+		if _, err := regexp.Compile(schema.Pattern); !isString || err != nil {
+			p.report(path+"/pattern", "pattern must be a regular expression")
+		}
+	}
+
+	schema.AllOf = p.subschemas(definition, "allOf", path)
+	schema.AnyOf = p.subschemas(definition, "anyOf", path)
+	schema.OneOf = p.subschemas(definition, "oneOf", path)
+	schema.Not = p.subschema(definition, "not", path)
+	return schema
+}
+
+func (p *jsonSchemaParser) types(raw interface{}, path string) []string {
+	if raw == nil {
+		return nil
+	}
+	types := stringList(raw)
+	if name, ok := raw.(string); ok {
+		types = []string{name}
+	} else if list, _ := raw.([]interface{}); list == nil || len(list) != len(types) {
+		p.report(path, "type must be a type name or a list of them")
+	}
+	for _, name := range types {
+		if !jsonSchemaTypes[name] {
+			p.report(path, "Unknown type %q", name)
+		}
+	}
+	return types
+}
+
+func (p *jsonSchemaParser) subschema(definition map[string]interface{}, keyword string, path string) *JSONSchema {
+	raw, ok := definition[keyword]
+	if !ok {
+		return nil
+	}
+	return p.parse(raw, path+"/"+keyword)
+}
+
+func (p *jsonSchemaParser) subschemas(definition map[string]interface{}, keyword string, path string) []*JSONSchema {
+	raw, ok := definition[keyword]
+	if !ok {
+		return nil
+	}
+	list, _ := raw.([]interface{})
+	if len(list) == 0 {
+		p.report(path+"/"+keyword, "%s must be a list of schemas", keyword)
+		return nil
+	}
+	schemas := make([]*JSONSchema, len(list))
+	for i, item := range list {
+		schemas[i] = p.parse(item, fmt.Sprintf("%s/%s/%d", path, keyword, i))
+	}
+	return schemas
+}
+
+func (p *jsonSchemaParser) number(definition map[string]interface{}, keyword string, path string) *float64 {
+	raw, ok := definition[keyword]
+	if !ok {
+		return nil
+	}
+	value, ok := raw.(float64)
+	if !ok {
+		p.report(path+"/"+keyword, "%s must be a number", keyword)
+		return nil
+	}
+	return &value
+}
+
+func (p *jsonSchemaParser) count(definition map[string]interface{}, keyword string, path string) *int {
+	value := p.number(definition, keyword, path)
+	if value == nil {
+		return nil
+	}
+	if *value < 0 || *value != math.Trunc(*value) {
+		p.report(path+"/"+keyword, "%s must be a non-negative integer", keyword)
+		return nil
+	}
+	count := int(*value)
+	return &count
+}
+
+// resolvePointer returns the value a JSON pointer points to in a document.
+func resolvePointer(document interface{}, pointer string) (interface{}, bool) {
+	if pointer == "" {
+		return document, true
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, false
+	}
+	current := document
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+		switch typed := current.(type) {
+		case map[string]interface{}:
+			value, ok := typed[token]
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case []interface{}:
+			var index int
+			if _, err := fmt.Sscanf(token, "%d", &index); err != nil || index < 0 || index >= len(typed) {
+				return nil, false
+			}
+			current = typed[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func escapePointer(token string) string {
+	return strings.Replace(strings.Replace(token, "~", "~0", -1), "/", "~1", -1)
+}
+
+func jsonPath(pointer string) string {
+	if pointer == "" {
+		return "/"
+	}
+	return pointer
+}
+
+func sortedKeys(definition map[string]interface{}) []string {
+	keys := make([]string, 0, len(definition))
+	for key := range definition {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,463 @@
+/* Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package validation
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Kinds of JSON Schema incompatibility, named the way the Java schema registry reports them.
+const (
+	TypeNarrowed                          = "TYPE_NARROWED"
+	EnumArrayNarrowed                     = "ENUM_ARRAY_NARROWED"
+	RequiredAttributeAdded                = "REQUIRED_ATTRIBUTE_ADDED"
+	PropertyRemovedFromClosedContentModel = "PROPERTY_REMOVED_FROM_CLOSED_CONTENT_MODEL"
+	AdditionalPropertiesNarrowed          = "ADDITIONAL_PROPERTIES_NARROWED"
+	MinPropertiesIncreased                = "MIN_PROPERTIES_INCREASED"
+	MaxPropertiesDecreased                = "MAX_PROPERTIES_DECREASED"
+	AdditionalItemsNarrowed               = "ADDITIONAL_ITEMS_NARROWED"
+	MinItemsIncreased                     = "MIN_ITEMS_INCREASED"
+	MaxItemsDecreased                     = "MAX_ITEMS_DECREASED"
+	UniqueItemsAdded                      = "UNIQUE_ITEMS_ADDED"
+	MinimumIncreased                      = "MINIMUM_INCREASED"
+	MaximumDecreased                      = "MAXIMUM_DECREASED"
+	MultipleOfChanged                     = "MULTIPLE_OF_CHANGED"
+	MinLengthIncreased                    = "MIN_LENGTH_INCREASED"
+	MaxLengthDecreased                    = "MAX_LENGTH_DECREASED"
+	PatternChanged                        = "PATTERN_CHANGED"
+	CombinedTypeSubschemasChanged         = "COMBINED_TYPE_SUBSCHEMAS_CHANGED"
+	NotTypeExtended                       = "NOT_TYPE_EXTENDED"
+	// LevelNotApplicable is our own, Java doesn't have compatibility levels that only apply to Avro
+	LevelNotApplicable = "COMPATIBILITY_LEVEL_NOT_APPLICABLE"
+)
+
+// maxRefDepth is how many references in a row are followed before a chain of them is taken for a cycle.
+const maxRefDepth = 32
+
+var anyJSONSchema = func() *JSONSchema {
+	valid := true
+	return &JSONSchema{Bool: &valid, raw: true}
+}()
+
+// JSONCompatibilityChecker checks a JSON Schema against an existing one, as CompatibilityChecker does Avro schemas.
+type JSONCompatibilityChecker interface {
+	Validate(toValidate *JSONSchema, existing *JSONSchema) error
+}
+
+// JSONCompatibility checks the documents valid with one schema are valid with the other. Backward checks
+// the new schema accepts every document the existing one does, so consumers can upgrade first, Forward checks
+// the reverse and both make FULL. With neither set every schema is compatible.
+type JSONCompatibility struct {
+	Backward bool
+	Forward  bool
+}
+
+func (jc *JSONCompatibility) Validate(toValidate *JSONSchema, existing *JSONSchema) error {
+	incompatibilities := make(Incompatibilities, 0)
+	if jc.Backward {
+		incompatibilities = append(incompatibilities, checkJSON(toValidate, existing)...)
+	}
+	if jc.Forward {
+		incompatibilities = append(incompatibilities, checkJSON(existing, toValidate)...)
+	}
+	return incompatibilities.err()
+}
+
+// JSONLevelNotApplicable rejects every schema at a compatibility level that only applies to Avro schemas,
+// like KEY_STABLE which is about the fields of record keys.
+type JSONLevelNotApplicable struct {
+	Level string
+}
+
+func (jc *JSONLevelNotApplicable) Validate(toValidate *JSONSchema, existing *JSONSchema) error {
+	return Incompatibilities{{
+		Type:    LevelNotApplicable,
+		Path:    jsonPath(""),
+		Message: fmt.Sprintf("Compatibility level %s doesn't apply to JSON Schemas, set another level for the subject", jc.Level),
+		Reader:  fragment(toValidate.raw),
+		Writer:  fragment(existing.raw),
+	}}
+}
+
+// checkJSON returns why documents valid with the writer schema are not valid with the reader schema. The check is
+// structural and errs on the safe side: subschemas of anyOf and oneOf are compared without the keywords next to them.
+func checkJSON(reader *JSONSchema, writer *JSONSchema) Incompatibilities {
+	checker := &jsonChecker{incompatibilities: make(Incompatibilities, 0), visited: make(map[[2]*JSONSchema]bool)}
+	checker.check(reader, writer, "")
+	return checker.incompatibilities
+}
+
+type jsonChecker struct {
+	incompatibilities Incompatibilities
+	// visited holds the pairs of schemas already checked, or being checked further up a recursive schema
+	visited map[[2]*JSONSchema]bool
+}
+
+func (c *jsonChecker) report(kind string, path string, reader *JSONSchema, writer *JSONSchema, format string, args ...interface{}) {
+	c.incompatibilities = append(c.incompatibilities, &Incompatibility{
+		Type:    kind,
+		Path:    jsonPath(path),
+		Message: fmt.Sprintf(format, args...),
+		Reader:  fragment(reader.raw),
+		Writer:  fragment(writer.raw),
+	})
+}
+
+// accepts tells whether the reader accepts every document the writer does without reporting why not.
+func (c *jsonChecker) accepts(reader *JSONSchema, writer *JSONSchema) bool {
+	sub := &jsonChecker{visited: make(map[[2]*JSONSchema]bool, len(c.visited))}
+	for key := range c.visited {
+		sub.visited[key] = true
+	}
+	sub.check(reader, writer, "")
+	return len(sub.incompatibilities) == 0
+}
+
+func (c *jsonChecker) check(reader *JSONSchema, writer *JSONSchema, path string) {
+	reader, writer = reader.resolved(), writer.resolved()
+	key := [2]*JSONSchema{reader, writer}
+	if c.visited[key] || writer.never() {
+		return
+	}
+	c.visited[key] = true
+	if reader.never() {
+		c.report(TypeNarrowed, path, reader, writer, "No document is valid any more")
+		return
+	}
+	if reader.Bool != nil {
+		return
+	}
+
+	// a document of the writer is valid with every subschema of its allOf, one of them being accepted is enough
+	for _, branch := range writer.AllOf {
+		if c.accepts(reader, branch) {
+			return
+		}
+	}
+	if len(writer.AnyOf) > 0 || len(writer.OneOf) > 0 {
+		for _, branch := range append(append([]*JSONSchema{}, writer.AnyOf...), writer.OneOf...) {
+			c.check(reader, branch, path)
+		}
+		return
+	}
+
+	for i, branch := range reader.AllOf {
+		c.check(branch, writer, fmt.Sprintf("%s/allOf/%d", path, i))
+	}
+	c.checkAlternatives(reader, reader.AnyOf, writer, path, "anyOf")
+	c.checkAlternatives(reader, reader.OneOf, writer, path, "oneOf")
+	if reader.Not != nil && (writer.Not == nil || fragment(reader.Not.raw) != fragment(writer.Not.raw)) {
+		c.report(NotTypeExtended, path+"/not", reader, writer, "not excludes documents that were valid")
+	}
+
+	c.checkTypes(reader, writer, path)
+	if writer.allows("number") || writer.allows("integer") {
+		c.checkNumbers(reader, writer, path)
+	}
+	if writer.allows("string") {
+		if minIncreased(reader.MinLength, writer.MinLength) {
+			c.report(MinLengthIncreased, path+"/minLength", reader, writer, "Strings must have at least %d characters", *reader.MinLength)
+		}
+		if maxDecreased(reader.MaxLength, writer.MaxLength) {
+			c.report(MaxLengthDecreased, path+"/maxLength", reader, writer, "Strings can have at most %d characters", *reader.MaxLength)
+		}
+		if reader.Pattern != "" && reader.Pattern != writer.Pattern {
+			c.report(PatternChanged, path+"/pattern", reader, writer, "Strings must match %s", reader.Pattern)
+		}
+	}
+	if writer.allows("array") {
+		c.checkArrays(reader, writer, path)
+	}
+	if writer.allows("object") {
+		c.checkObjects(reader, writer, path)
+	}
+}
+
+func (c *jsonChecker) checkAlternatives(reader *JSONSchema, alternatives []*JSONSchema, writer *JSONSchema, path string, keyword string) {
+	if len(alternatives) == 0 {
+		return
+	}
+	for _, alternative := range alternatives {
+		if c.accepts(alternative, writer) {
+			return
+		}
+	}
+	c.report(CombinedTypeSubschemasChanged, path+"/"+keyword, reader, writer, "No subschema of %s accepts every document that was valid", keyword)
+}
+
+func (c *jsonChecker) checkTypes(reader *JSONSchema, writer *JSONSchema, path string) {
+	if reader.Enum != nil {
+		if writer.Enum == nil {
+			c.report(EnumArrayNarrowed, path, reader, writer, "Only %s are valid", fragment(reader.Enum))
+		}
+		for _, value := range writer.Enum {
+			if !containsValue(reader.Enum, value) {
+				c.report(EnumArrayNarrowed, path, reader, writer, "%s is not valid any more", fragment(value))
+			}
+		}
+	}
+	if reader.Types == nil {
+		return
+	}
+	writerTypes := writer.Types
+	if writerTypes == nil && writer.Enum != nil {
+		for _, value := range writer.Enum {
+			writerTypes = append(writerTypes, jsonType(value))
+		}
+	}
+	if writerTypes == nil {
+		c.report(TypeNarrowed, path+"/type", reader, writer, "Only %s documents are valid", strings.Join(reader.Types, ", "))
+		return
+	}
+	for _, name := range writerTypes {
+		if !reader.allows(name) {
+			c.report(TypeNarrowed, path+"/type", reader, writer, "%s is not valid any more", name)
+		}
+	}
+}
+
+func (c *jsonChecker) checkNumbers(reader *JSONSchema, writer *JSONSchema, path string) {
+	readerBound, readerExclusive := reader.lower()
+	writerBound, writerExclusive := writer.lower()
+	if boundNarrowed(readerBound, readerExclusive, writerBound, writerExclusive, 1) {
+		c.report(MinimumIncreased, path+"/minimum", reader, writer, "Numbers must be at least %v", *readerBound)
+	}
+	readerBound, readerExclusive = reader.upper()
+	writerBound, writerExclusive = writer.upper()
+	if boundNarrowed(readerBound, readerExclusive, writerBound, writerExclusive, -1) {
+		c.report(MaximumDecreased, path+"/maximum", reader, writer, "Numbers must be at most %v", *readerBound)
+	}
+	if reader.MultipleOf != nil {
+		ratio := 0.5
+		if writer.MultipleOf != nil {
+			ratio = *writer.MultipleOf / *reader.MultipleOf
+		}
+		if math.Abs(ratio-math.Floor(ratio+0.5)) > 1e-9 {
+			c.report(MultipleOfChanged, path+"/multipleOf", reader, writer, "Numbers must be multiples of %v", *reader.MultipleOf)
+		}
+	}
+}
+
+func (c *jsonChecker) checkArrays(reader *JSONSchema, writer *JSONSchema, path string) {
+	if minIncreased(reader.MinItems, writer.MinItems) {
+		c.report(MinItemsIncreased, path+"/minItems", reader, writer, "Arrays must have at least %d items", *reader.MinItems)
+	}
+	if maxDecreased(reader.MaxItems, writer.MaxItems) {
+		c.report(MaxItemsDecreased, path+"/maxItems", reader, writer, "Arrays can have at most %d items", *reader.MaxItems)
+	}
+	if reader.UniqueItems && !writer.UniqueItems {
+		c.report(UniqueItemsAdded, path+"/uniqueItems", reader, writer, "Array items must be unique")
+	}
+	if reader.Items == nil && reader.TupleItems == nil {
+		return
+	}
+	positions := len(reader.TupleItems)
+	if len(writer.TupleItems) > positions {
+		positions = len(writer.TupleItems)
+	}
+	// the last position stands for every item after the tuples of both schemas
+	for i := 0; i <= positions; i++ {
+		if writer.MaxItems != nil && i >= *writer.MaxItems {
+			return
+		}
+		itemPath := fmt.Sprintf("%s/items/%d", path, i)
+		if reader.Items != nil {
+			itemPath = path + "/items"
+		} else if i >= len(reader.TupleItems) {
+			itemPath = path + "/additionalItems"
+		}
+		readerItem, writerItem := reader.item(i), writer.item(i)
+		if readerItem.resolved().never() && !writerItem.resolved().never() {
+			c.report(AdditionalItemsNarrowed, itemPath, reader, writer, "Arrays can't have an item at position %d any more", i)
+			continue
+		}
+		c.check(readerItem, writerItem, itemPath)
+	}
+}
+
+func (c *jsonChecker) checkObjects(reader *JSONSchema, writer *JSONSchema, path string) {
+	for _, name := range reader.Required {
+		if !contains(writer.Required, name) {
+			c.report(RequiredAttributeAdded, path+"/required", reader, writer, "Property %s is required", name)
+		}
+	}
+	if minIncreased(reader.MinProperties, writer.MinProperties) {
+		c.report(MinPropertiesIncreased, path+"/minProperties", reader, writer, "Objects must have at least %d properties", *reader.MinProperties)
+	}
+	if maxDecreased(reader.MaxProperties, writer.MaxProperties) {
+		c.report(MaxPropertiesDecreased, path+"/maxProperties", reader, writer, "Objects can have at most %d properties", *reader.MaxProperties)
+	}
+
+	names := make([]string, 0, len(reader.Properties)+len(writer.Properties))
+	for name := range reader.Properties {
+		names = append(names, name)
+	}
+	for name := range writer.Properties {
+		if _, ok := reader.Properties[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		writerProperty := writer.property(name)
+		if writerProperty.resolved().never() {
+			continue
+		}
+		propertyPath := path + "/properties/" + escapePointer(name)
+		if _, ok := reader.Properties[name]; !ok {
+			propertyPath = path + "/additionalProperties"
+		}
+		readerProperty := reader.property(name)
+		if readerProperty.resolved().never() {
+			c.report(PropertyRemovedFromClosedContentModel, propertyPath, reader, writer, "Property %s is not valid any more", name)
+			continue
+		}
+		c.check(readerProperty, writerProperty, propertyPath)
+	}
+
+	readerRest, writerRest := reader.rest(), writer.rest()
+	if reader.AdditionalProperties != nil && readerRest.resolved().never() && !writerRest.resolved().never() {
+		c.report(AdditionalPropertiesNarrowed, path+"/additionalProperties", reader, writer, "Properties the schema doesn't declare are not valid any more")
+	} else if reader.AdditionalProperties != nil {
+		c.check(readerRest, writerRest, path+"/additionalProperties")
+	}
+}
+
+// resolved follows the references of a schema, a cycle of references accepts anything.
+func (s *JSONSchema) resolved() *JSONSchema {
+	for i := 0; s.Ref != "" && s.target != nil && i < maxRefDepth; i++ {
+		s = s.target
+	}
+	if s.Ref != "" {
+		return anyJSONSchema
+	}
+	return s
+}
+
+// never tells whether the schema is false, no document is valid with it.
+func (s *JSONSchema) never() bool {
+	return s.Bool != nil && !*s.Bool
+}
+
+// allows tells whether values of a JSON type can be valid. Integers are numbers.
+func (s *JSONSchema) allows(name string) bool {
+	if s.Types == nil {
+		return true
+	}
+	for _, allowed := range s.Types {
+		if allowed == name || allowed == "number" && name == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+// property returns the schema of a property, which is the schema of the properties not declared if it isn't.
+func (s *JSONSchema) property(name string) *JSONSchema {
+	if property, ok := s.Properties[name]; ok {
+		return property
+	}
+	return s.rest()
+}
+
+// rest returns the schema of the properties the schema doesn't declare.
+func (s *JSONSchema) rest() *JSONSchema {
+	if s.AdditionalProperties != nil {
+		return s.AdditionalProperties
+	}
+	return anyJSONSchema
+}
+
+// item returns the schema of the item at a position of an array.
+func (s *JSONSchema) item(position int) *JSONSchema {
+	switch {
+	case s.Items != nil:
+		return s.Items
+	case position < len(s.TupleItems):
+		return s.TupleItems[position]
+	case s.TupleItems != nil && s.AdditionalItems != nil:
+		return s.AdditionalItems
+	}
+	return anyJSONSchema
+}
+
+func (s *JSONSchema) lower() (*float64, bool) {
+	if s.ExclusiveMinimum != nil && (s.Minimum == nil || *s.ExclusiveMinimum >= *s.Minimum) {
+		return s.ExclusiveMinimum, true
+	}
+	return s.Minimum, false
+}
+
+func (s *JSONSchema) upper() (*float64, bool) {
+	if s.ExclusiveMaximum != nil && (s.Maximum == nil || *s.ExclusiveMaximum <= *s.Maximum) {
+		return s.ExclusiveMaximum, true
+	}
+	return s.Maximum, false
+}
+
+// boundNarrowed tells whether the reader bound excludes numbers the writer bound doesn't,
+// sign is 1 for lower bounds and -1 for upper bounds.
+func boundNarrowed(reader *float64, readerExclusive bool, writer *float64, writerExclusive bool, sign float64) bool {
+	if reader == nil {
+		return false
+	}
+	if writer == nil {
+		return true
+	}
+	readerValue, writerValue := *reader*sign, *writer*sign
+	return readerValue > writerValue || readerValue == writerValue && readerExclusive && !writerExclusive
+}
+
+func minIncreased(reader *int, writer *int) bool {
+	return reader != nil && *reader > 0 && (writer == nil || *writer < *reader)
+}
+
+func maxDecreased(reader *int, writer *int) bool {
+	return reader != nil && (writer == nil || *writer > *reader)
+}
+
+func containsValue(values []interface{}, value interface{}) bool {
+	for _, candidate := range values {
+		if reflect.DeepEqual(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType returns the JSON Schema type of a decoded JSON value.
+func jsonType(value interface{}) string {
+	switch typed := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if typed == math.Trunc(typed) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	}
+	return "object"
+}
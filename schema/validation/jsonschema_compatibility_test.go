@@ -0,0 +1,116 @@
+/* Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package validation
+
+import (
+	"testing"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+	"gopkg.in/stretchr/testify.v1/require"
+)
+
+func checkJSONSchemas(t *testing.T, reader string, writer string) Incompatibilities {
+	readerSchema, err := ParseJSONSchema(reader)
+	require.NoError(t, err)
+	writerSchema, err := ParseJSONSchema(writer)
+	require.NoError(t, err)
+	return checkJSON(readerSchema, writerSchema)
+}
+
+func TestJSONSchemaCompatible(t *testing.T) {
+	cases := []struct{ reader, writer string }{
+		{personJSONSchema, personJSONSchema},
+		{`true`, `{"type": "string"}`},
+		{`{"type": "string"}`, `false`},
+		{`{"type": "number"}`, `{"type": "integer"}`},
+		{`{"type": ["string", "null"]}`, `{"type": "string"}`},
+		{`{"enum": ["A", "B", "C"]}`, `{"enum": ["A", "B"]}`},
+		{`{"type": "string"}`, `{"enum": ["A", "B"]}`},
+		{`{"type": "integer", "minimum": 0}`, `{"type": "integer", "exclusiveMinimum": 0}`},
+		{`{"type": "integer", "maximum": 10}`, `{"type": "integer", "minimum": 0, "maximum": 5}`},
+		{`{"multipleOf": 2}`, `{"multipleOf": 4}`},
+		{`{"type": "string", "maxLength": 10}`, `{"type": "string", "maxLength": 5, "minLength": 1}`},
+		{`{"type": "object", "properties": {"a": {"type": "string"}}}`,
+			`{"type": "object", "properties": {"a": {"type": "string"}, "b": {"type": "integer"}}, "required": ["a"]}`},
+		{`{"type": "object", "properties": {"a": {"type": "string"}}, "additionalProperties": false}`,
+			`{"type": "object", "properties": {"a": {"type": "string"}}, "additionalProperties": false}`},
+		{`{"type": "array", "items": {"type": ["string", "integer"]}}`, `{"type": "array", "items": [{"type": "string"}, {"type": "integer"}], "additionalItems": false}`},
+		{`{"anyOf": [{"type": "string"}, {"type": "integer"}]}`, `{"oneOf": [{"type": "integer"}, {"type": "string", "maxLength": 3}]}`},
+		{`{"type": "object", "properties": {"child": {"$ref": "#"}}}`, `{"type": "object", "properties": {"child": {"$ref": "#"}}}`},
+		{`{"allOf": [{"type": "object"}, {"required": ["a"]}]}`, `{"type": "object", "required": ["a", "b"]}`},
+	}
+	for _, c := range cases {
+		assert.Empty(t, checkJSONSchemas(t, c.reader, c.writer), "%s reading %s", c.reader, c.writer)
+	}
+}
+
+func TestJSONSchemaIncompatible(t *testing.T) {
+	cases := []struct{ reader, writer, kind, path string }{
+		{`false`, `true`, TypeNarrowed, "/"},
+		{`{"type": "string"}`, `{}`, TypeNarrowed, "/type"},
+		{`{"type": "integer"}`, `{"type": "number"}`, TypeNarrowed, "/type"},
+		{`{"enum": ["A"]}`, `{"enum": ["A", "B"]}`, EnumArrayNarrowed, "/"},
+		{`{"minimum": 1}`, `{"minimum": 0}`, MinimumIncreased, "/minimum"},
+		{`{"exclusiveMaximum": 5}`, `{"maximum": 5}`, MaximumDecreased, "/maximum"},
+		{`{"multipleOf": 4}`, `{"multipleOf": 2}`, MultipleOfChanged, "/multipleOf"},
+		{`{"minLength": 2}`, `{"type": "string"}`, MinLengthIncreased, "/minLength"},
+		{`{"maxLength": 2}`, `{"maxLength": 3}`, MaxLengthDecreased, "/maxLength"},
+		{`{"pattern": "^a"}`, `{"pattern": "^b"}`, PatternChanged, "/pattern"},
+		{`{"type": "object", "required": ["a"]}`, `{"type": "object"}`, RequiredAttributeAdded, "/required"},
+		{`{"properties": {"a": {"type": "string"}}, "additionalProperties": false}`,
+			`{"properties": {"a": {"type": "string"}, "b": {"type": "string"}}, "additionalProperties": false}`, PropertyRemovedFromClosedContentModel, "/additionalProperties"},
+		{`{"properties": {"a": {"type": "string"}}, "additionalProperties": false}`,
+			`{"properties": {"a": {"type": "string"}}}`, AdditionalPropertiesNarrowed, "/additionalProperties"},
+		{`{"properties": {"a": {"type": "integer"}}}`, `{"properties": {"a": {"type": "string"}}}`, TypeNarrowed, "/properties/a/type"},
+		{`{"minProperties": 1}`, `{}`, MinPropertiesIncreased, "/minProperties"},
+		{`{"maxProperties": 1}`, `{"maxProperties": 2}`, MaxPropertiesDecreased, "/maxProperties"},
+		{`{"items": {"type": "string"}}`, `{"items": {"type": ["string", "null"]}}`, TypeNarrowed, "/items/type"},
+		{`{"items": [{"type": "string"}], "additionalItems": false}`, `{"items": [{"type": "string"}]}`, AdditionalItemsNarrowed, "/additionalItems"},
+		{`{"minItems": 1}`, `{}`, MinItemsIncreased, "/minItems"},
+		{`{"maxItems": 1}`, `{}`, MaxItemsDecreased, "/maxItems"},
+		{`{"uniqueItems": true}`, `{}`, UniqueItemsAdded, "/uniqueItems"},
+		{`{"oneOf": [{"type": "string"}, {"type": "null"}]}`, `{"type": ["string", "integer"]}`, CombinedTypeSubschemasChanged, "/oneOf"},
+		{`{"not": {"type": "null"}}`, `{}`, NotTypeExtended, "/not"},
+		{`{"type": "object", "properties": {"child": {"$ref": "#"}, "a": {"type": "string"}}}`,
+			`{"type": "object", "properties": {"child": {"$ref": "#"}, "a": {"type": ["string", "null"]}}}`, TypeNarrowed, "/properties/a/type"},
+	}
+	for _, c := range cases {
+		incompatibilities := checkJSONSchemas(t, c.reader, c.writer)
+		if assert.Len(t, incompatibilities, 1, "%s reading %s", c.reader, c.writer) {
+			assert.Equal(t, c.kind, incompatibilities[0].Type, c.reader)
+			assert.Equal(t, c.path, incompatibilities[0].Path, c.reader)
+		}
+	}
+}
+
+func TestJSONCompatibility(t *testing.T) {
+	old, err := ParseJSONSchema(`{"type": "object", "properties": {"a": {"type": "string"}}, "required": ["a"]}`)
+	require.NoError(t, err)
+	optional, err := ParseJSONSchema(`{"type": "object", "properties": {"a": {"type": "string"}}}`)
+	require.NoError(t, err)
+
+	assert.NoError(t, (&JSONCompatibility{Backward: true}).Validate(optional, old))
+	assert.Error(t, (&JSONCompatibility{Forward: true}).Validate(optional, old))
+	assert.Error(t, (&JSONCompatibility{Backward: true, Forward: true}).Validate(optional, old))
+	assert.NoError(t, new(JSONCompatibility).Validate(optional, old))
+
+	err = (&JSONLevelNotApplicable{Level: "KEY_STABLE"}).Validate(old, old)
+	require.Error(t, err)
+	incompatibilities := err.(Incompatibilities)
+	require.Len(t, incompatibilities, 1)
+	assert.Equal(t, LevelNotApplicable, incompatibilities[0].Type)
+	assert.Contains(t, incompatibilities[0].Message, "KEY_STABLE")
+}
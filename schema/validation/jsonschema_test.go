@@ -0,0 +1,90 @@
+/* Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package validation
+
+import (
+	"testing"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+	"gopkg.in/stretchr/testify.v1/require"
+)
+
+const personJSONSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "Person",
+  "type": "object",
+  "properties": {
+    "id": {"type": "integer", "minimum": 1},
+    "email": {"type": ["string", "null"], "format": "email", "maxLength": 200},
+    "role": {"enum": ["ADMIN", "USER"]},
+    "tags": {"type": "array", "items": {"type": "string"}, "uniqueItems": true},
+    "manager": {"$ref": "#"},
+    "address": {"$ref": "#/definitions/address"}
+  },
+  "required": ["id"],
+  "definitions": {
+    "address": {"type": "object", "properties": {"street": {"type": "string"}}, "additionalProperties": false}
+  }
+}`
+
+func TestParseJSONSchema(t *testing.T) {
+	schema, err := ParseJSONSchema(personJSONSchema)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"object"}, schema.Types)
+	assert.Equal(t, []string{"id"}, schema.Required)
+	require.Len(t, schema.Properties, 6)
+	assert.Equal(t, 1.0, *schema.Properties["id"].Minimum)
+	assert.Equal(t, 200, *schema.Properties["email"].MaxLength)
+	assert.Equal(t, []interface{}{"ADMIN", "USER"}, schema.Properties["role"].Enum)
+	assert.True(t, schema.Properties["tags"].UniqueItems)
+	assert.Equal(t, []string{"string"}, schema.Properties["tags"].Items.Types)
+	assert.Equal(t, schema, schema.Properties["manager"].resolved())
+	address := schema.Properties["address"].resolved()
+	assert.True(t, address.AdditionalProperties.never())
+	assert.Empty(t, CheckJSONSchema(personJSONSchema))
+
+	schema, err = ParseJSONSchema(`false`)
+	require.NoError(t, err)
+	assert.True(t, schema.never())
+
+	schema, err = ParseJSONSchema(`{"type": "number", "minimum": 0, "exclusiveMinimum": true, "const": 3}`)
+	require.NoError(t, err)
+	assert.Nil(t, schema.Minimum)
+	assert.Equal(t, 0.0, *schema.ExclusiveMinimum)
+	assert.Equal(t, []interface{}{3.0}, schema.Enum)
+}
+
+func TestParseJSONSchemaErrors(t *testing.T) {
+	_, err := ParseJSONSchema(`"string"`)
+	assert.Error(t, err)
+	_, err = ParseJSONSchema(`{"properties": {"a": {"$ref": "#/definitions/missing"}}}`)
+	require.Error(t, err)
+	assert.Equal(t, "/properties/a/$ref", err.(*SchemaError).Path)
+	_, err = ParseJSONSchema(`{"$ref": "http://example.com/person.json"}`)
+	assert.Error(t, err)
+
+	_, err = ParseJSONSchema("{\n  \"type\": \"object\",\n  \"properties\": {]\n}")
+	require.Error(t, err)
+	assert.Equal(t, 3, err.(*SchemaError).Line)
+
+	errors := CheckJSONSchema(`{"type": "text", "required": "id", "minLength": -1, "pattern": "(",
+      "properties": {"a": 1}, "anyOf": []}`)
+	paths := make([]string, len(errors))
+	for i, schemaError := range errors {
+		paths[i] = schemaError.Path
+	}
+	assert.Equal(t, []string{"/type", "/properties/a", "/required", "/minLength", "/pattern", "/anyOf"}, paths)
+}
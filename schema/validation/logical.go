@@ -0,0 +1,275 @@
+/* Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/elodina/go-avro"
+)
+
+// Logical types of the Avro specification.
+const (
+	Decimal              = "decimal"
+	UUID                 = "uuid"
+	Date                 = "date"
+	TimeMillis           = "time-millis"
+	TimeMicros           = "time-micros"
+	TimestampMillis      = "timestamp-millis"
+	TimestampMicros      = "timestamp-micros"
+	LocalTimestampMillis = "local-timestamp-millis"
+	LocalTimestampMicros = "local-timestamp-micros"
+	Duration             = "duration"
+)
+
+const (
+	logicalTypeProperty = "logicalType"
+	precisionProperty   = "precision"
+	scaleProperty       = "scale"
+	durationSize        = 12
+)
+
+// logicalTypes lists the types every logical type can annotate.
+var logicalTypes = map[string][]int{
+	Decimal:              {avro.Bytes, avro.Fixed},
+	UUID:                 {avro.String},
+	Date:                 {avro.Int},
+	TimeMillis:           {avro.Int},
+	TimeMicros:           {avro.Long},
+	TimestampMillis:      {avro.Long},
+	TimestampMicros:      {avro.Long},
+	LocalTimestampMillis: {avro.Long},
+	LocalTimestampMicros: {avro.Long},
+	Duration:             {avro.Fixed},
+}
+
+// LogicalType is the logical type a schema annotates its underlying type with.
+// Precision and Scale are only set for decimals.
+type LogicalType struct {
+	Name      string
+	Precision int
+	Scale     int
+}
+
+func (lt *LogicalType) String() string {
+	if lt == nil {
+		return "no logical type"
+	}
+	if lt.Name == Decimal {
+		return fmt.Sprintf("%s(%d,%d)", lt.Name, lt.Precision, lt.Scale)
+	}
+	return lt.Name
+}
+
+// readableAs tells if values of this logical type can be read as the reader one: the logical types are the same,
+// and a decimal is read with the same scale and at least its precision.
+func (lt *LogicalType) readableAs(reader *LogicalType) bool {
+	if lt == nil || reader == nil {
+		return lt == reader
+	}
+	if lt.Name == Decimal && reader.Name == Decimal {
+		return lt.Scale == reader.Scale && lt.Precision <= reader.Precision
+	}
+	return lt.Name == reader.Name
+}
+
+// LogicalSchema is a primitive schema annotated with a logical type. go-avro drops all properties of primitives,
+// so ParseSchema keeps them here. Everything else is the primitive's.
+type LogicalSchema struct {
+	avro.Schema
+	Properties map[string]interface{}
+}
+
+func newLogicalSchema(schema avro.Schema, definition map[string]interface{}) *LogicalSchema {
+	properties := make(map[string]interface{})
+	for name, value := range definition {
+		if name != "type" {
+			properties[name] = value
+		}
+	}
+	return &LogicalSchema{Schema: schema, Properties: properties}
+}
+
+// Prop gets a custom property of the schema, logicalType among them.
+func (s *LogicalSchema) Prop(key string) (interface{}, bool) {
+	prop, ok := s.Properties[key]
+	return prop, ok
+}
+
+func (s *LogicalSchema) String() string {
+	bytes, err := json.MarshalIndent(s, "", "    ")
+	if err != nil {
+		return err.Error()
+	}
+	return string(bytes)
+}
+
+// MarshalJSON serializes the schema as JSON along with its properties.
+func (s *LogicalSchema) MarshalJSON() ([]byte, error) {
+	definition := map[string]interface{}{"type": s.Schema.GetName()}
+	for name, value := range s.Properties {
+		definition[name] = value
+	}
+	return json.Marshal(definition)
+}
+
+func primitive(schema avro.Schema) bool {
+	switch schema.Type() {
+	case avro.Null, avro.Boolean, avro.Int, avro.Long, avro.Float, avro.Double, avro.String, avro.Bytes:
+		return true
+	}
+	return false
+}
+
+// parseLogicalType returns the logical type of a schema, or an error if the schema can't have it. Schemas
+// without a logical type, or with one the Avro specification doesn't define, have none.
+func parseLogicalType(schema avro.Schema) (*LogicalType, error) {
+	prop, ok := schema.Prop(logicalTypeProperty)
+	if !ok {
+		return nil, nil
+	}
+	name, ok := prop.(string)
+	if !ok {
+		return nil, fmt.Errorf("Logical type must be a string, got %v", prop)
+	}
+	underlying, ok := logicalTypes[name]
+	if !ok {
+		return nil, nil
+	}
+	if !typeIn(schema.Type(), underlying) {
+		return nil, fmt.Errorf("Logical type %s can't annotate %s", name, typeName(schema))
+	}
+
+	logical := &LogicalType{Name: name}
+	switch name {
+	case Decimal:
+		precision, ok := intProp(schema, precisionProperty)
+		if !ok || precision <= 0 {
+			return nil, fmt.Errorf("Decimal precision must be a positive integer")
+		}
+		scale := 0
+		if _, ok := schema.Prop(scaleProperty); ok {
+			scale, ok = intProp(schema, scaleProperty)
+			if !ok || scale < 0 {
+				return nil, fmt.Errorf("Decimal scale must be a non-negative integer")
+			}
+		}
+		if scale > precision {
+			return nil, fmt.Errorf("Decimal scale %d is greater than precision %d", scale, precision)
+		}
+		if fixed, ok := schema.(*avro.FixedSchema); ok {
+			if max := maxPrecision(fixed.Size); precision > max {
+				return nil, fmt.Errorf("Decimal precision %d needs more than %d bytes, which hold at most %d digits", precision, fixed.Size, max)
+			}
+		}
+		logical.Precision, logical.Scale = precision, scale
+	case Duration:
+		if size := schema.(*avro.FixedSchema).Size; size != durationSize {
+			return nil, fmt.Errorf("Duration must be a fixed of size %d, got %d", durationSize, size)
+		}
+	}
+	return logical, nil
+}
+
+// logicalType returns the logical type of a schema. Invalid logical types are ignored as the Avro specification requires,
+// the schema is then read as its underlying type.
+func logicalType(schema avro.Schema) *LogicalType {
+	logical, err := parseLogicalType(schema)
+	if err != nil {
+		return nil
+	}
+	return logical
+}
+
+// maxPrecision is the number of decimal digits a two's complement fixed of the given size can hold.
+func maxPrecision(size int) int {
+	if size <= 0 {
+		return 0
+	}
+	return int(math.Floor(float64(8*size-1) * math.Log10(2)))
+}
+
+func intProp(schema avro.Schema, key string) (int, bool) {
+	prop, ok := schema.Prop(key)
+	if !ok {
+		return 0, false
+	}
+	number, ok := prop.(float64)
+	if !ok || number != math.Trunc(number) {
+		return 0, false
+	}
+	return int(number), true
+}
+
+func typeIn(schemaType int, types []int) bool {
+	for _, t := range types {
+		if schemaType == t {
+			return true
+		}
+	}
+	return false
+}
+
+func typeName(schema avro.Schema) string {
+	if schema.Type() == avro.Fixed {
+		return "fixed"
+	}
+	return schema.GetName()
+}
+
+// CheckLogicalTypes returns an error for every logical type in the schema that doesn't fit the type it annotates.
+func CheckLogicalTypes(schema avro.Schema) SchemaErrors {
+	checker := &logicalTypeChecker{visited: make(map[*avro.RecordSchema]bool)}
+	checker.check(schema, "")
+	return checker.errors
+}
+
+type logicalTypeChecker struct {
+	visited map[*avro.RecordSchema]bool
+	errors  SchemaErrors
+}
+
+func (c *logicalTypeChecker) check(schema avro.Schema, path string) {
+	schema = actual(schema)
+	if _, err := parseLogicalType(schema); err != nil {
+		location := path
+		if location == "" {
+			location = "/"
+		}
+		c.errors = append(c.errors, &SchemaError{Path: location, Message: err.Error()})
+	}
+
+	switch typed := schema.(type) {
+	case *avro.RecordSchema:
+		if c.visited[typed] {
+			return
+		}
+		c.visited[typed] = true
+		for _, field := range typed.Fields {
+			c.check(field.Type, path+"/fields/"+field.Name+"/type")
+		}
+	case *avro.ArraySchema:
+		c.check(typed.Items, path+"/items")
+	case *avro.MapSchema:
+		c.check(typed.Values, path+"/values")
+	case *avro.UnionSchema:
+		for i, branch := range typed.Types {
+			c.check(branch, fmt.Sprintf("%s/%d", path, i))
+		}
+	}
+}
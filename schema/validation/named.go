@@ -0,0 +1,138 @@
+/* Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// NamedTypes returns the full names of the records, enums and fixed types a schema defines, in the order they are defined.
+func NamedTypes(rawSchema string) ([]string, error) {
+	var raw interface{}
+	err := json.Unmarshal([]byte(rawSchema), &raw)
+	if err != nil {
+		return nil, err
+	}
+	walker := &nodeWalker{}
+	walker.walk(raw, "", "", 0)
+	names := make([]string, 0)
+	for _, node := range walker.nodes {
+		if node.Kind != NodeField {
+			names = append(names, node.Name)
+		}
+	}
+	return names, nil
+}
+
+// NamedTypeName returns the full name of a record, enum or fixed definition.
+func NamedTypeName(rawDefinition string) (string, error) {
+	names, err := NamedTypes(rawDefinition)
+	if err != nil {
+		return "", err
+	}
+	if len(names) == 0 {
+		return "", fmt.Errorf("Not a record, enum or fixed definition")
+	}
+	return names[0], nil
+}
+
+// ReplaceNamedType returns the schema with the definition of the named type replaced, and false if the schema
+// doesn't define it. A new definition without a namespace keeps the namespace of the named type.
+func ReplaceNamedType(rawSchema string, name string, rawDefinition string) (string, bool, error) {
+	var raw, definition interface{}
+	err := json.Unmarshal([]byte(rawSchema), &raw)
+	if err != nil {
+		return "", false, err
+	}
+	err = json.Unmarshal([]byte(rawDefinition), &definition)
+	if err != nil {
+		return "", false, err
+	}
+	if named, ok := definition.(map[string]interface{}); ok {
+		if _, ok := named["namespace"]; !ok {
+			named["namespace"] = namespaceOf(name)
+		}
+	}
+	replacer := &namedTypeReplacer{name: name, definition: definition}
+	replaced := replacer.replace(raw, "")
+	if !replacer.replaced {
+		return rawSchema, false, nil
+	}
+	return encode(replaced), true, nil
+}
+
+type namedTypeReplacer struct {
+	name       string
+	definition interface{}
+	replaced   bool
+}
+
+func (r *namedTypeReplacer) replace(raw interface{}, namespace string) interface{} {
+	switch definition := raw.(type) {
+	case []interface{}:
+		branches := make([]interface{}, len(definition))
+		for i, branch := range definition {
+			branches[i] = r.replace(branch, namespace)
+		}
+		return branches
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(definition))
+		for key, value := range definition {
+			copied[key] = value
+		}
+		switch definition["type"] {
+		case "record", "error", "enum", "fixed":
+			name, _ := definition["name"].(string)
+			if ns, ok := definition["namespace"].(string); ok {
+				namespace = ns
+			}
+			full := fullName(name, namespace)
+			if full == r.name {
+				r.replaced = true
+				return r.definition
+			}
+			namespace = namespaceOf(full)
+			fields, _ := definition["fields"].([]interface{})
+			if fields == nil {
+				return copied
+			}
+			replacedFields := make([]interface{}, len(fields))
+			for i, raw := range fields {
+				replacedFields[i] = raw
+				if field, ok := raw.(map[string]interface{}); ok {
+					copiedField := make(map[string]interface{}, len(field))
+					for key, value := range field {
+						copiedField[key] = value
+					}
+					copiedField["type"] = r.replace(field["type"], namespace)
+					replacedFields[i] = copiedField
+				}
+			}
+			copied["fields"] = replacedFields
+		case "array":
+			copied["items"] = r.replace(definition["items"], namespace)
+		case "map":
+			copied["values"] = r.replace(definition["values"], namespace)
+		default:
+			if _, ok := definition["type"].(string); !ok {
+				copied["type"] = r.replace(definition["type"], namespace)
+			}
+		}
+		return copied
+	}
+	return raw
+}
@@ -0,0 +1,70 @@
+/* Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package validation
+
+import (
+	"testing"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+	"gopkg.in/stretchr/testify.v1/require"
+)
+
+const addressSchema = `{"type": "record", "name": "Person", "namespace": "com.acme", "fields": [
+     {"name": "home", "type": {"type": "record", "name": "Address", "fields": [{"name": "street", "type": "string"}]}},
+     {"name": "work", "type": ["null", "Address"]},
+     {"name": "tags", "type": {"type": "array", "items": {"type": "enum", "name": "Tag", "namespace": "com.other", "symbols": ["A"]}}}
+ ]}`
+
+func TestNamedTypes(t *testing.T) {
+	names, err := NamedTypes(addressSchema)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"com.acme.Person", "com.acme.Address", "com.other.Tag"}, names)
+
+	names, err = NamedTypes(`"string"`)
+	require.NoError(t, err)
+	assert.Empty(t, names)
+
+	name, err := NamedTypeName(`{"type": "fixed", "name": "Hash", "namespace": "com.acme", "size": 16}`)
+	require.NoError(t, err)
+	assert.Equal(t, "com.acme.Hash", name)
+	_, err = NamedTypeName(`{"type": "array", "items": "string"}`)
+	assert.Error(t, err)
+}
+
+func TestReplaceNamedType(t *testing.T) {
+	replaced, found, err := ReplaceNamedType(addressSchema, "com.acme.Address",
+		`{"type": "record", "name": "Address", "fields": [{"name": "street", "type": "string"}, {"name": "zip", "type": "string"}]}`)
+	require.NoError(t, err)
+	require.True(t, found)
+
+	old, err := ParseSchema(addressSchema)
+	require.NoError(t, err)
+	schema, err := ParseSchema(replaced)
+	require.NoError(t, err)
+	names, err := NamedTypes(replaced)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"com.acme.Person", "com.acme.Address", "com.other.Tag"}, names)
+
+	incompatibilities := check(old, schema)
+	require.Len(t, incompatibilities, 2)
+	assert.Equal(t, "/fields/home/type/fields/zip", incompatibilities[0].Path)
+	assert.Equal(t, "/fields/work/type/1/fields/zip", incompatibilities[1].Path)
+
+	unchanged, found, err := ReplaceNamedType(addressSchema, "com.other.Address", `{"type": "record", "name": "Address", "fields": []}`)
+	require.NoError(t, err)
+	assert.False(t, found)
+	assert.Equal(t, addressSchema, unchanged)
+}
@@ -0,0 +1,155 @@
+/* Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package validation
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/elodina/go-avro"
+)
+
+// aliasesProperty keeps the aliases of fixed types and record fields in their Properties, go-avro has no field for them.
+const aliasesProperty = "aliases"
+
+// ParseSchema parses a schema for validation. Unlike avro.ParseSchema it keeps the aliases of named types
+// and record fields, sets the namespace every named type inherits, so full names and aliases can be
+// resolved the way the Avro specification requires, and keeps the logical types of primitives.
+func ParseSchema(rawSchema string) (avro.Schema, error) {
+	schema, err := avro.ParseSchema(rawSchema)
+	if err != nil {
+		return nil, err
+	}
+	var raw interface{}
+	if err := json.Unmarshal([]byte(rawSchema), &raw); err != nil {
+		// go-avro accepts bare primitive names that are not valid JSON
+		return schema, nil
+	}
+	return annotate(schema, raw, ""), nil
+}
+
+// annotate walks the parsed schema along with its JSON definition, copies over what go-avro drops
+// and returns the schema to use in place of the parsed one.
+func annotate(schema avro.Schema, raw interface{}, namespace string) avro.Schema {
+	switch definition := raw.(type) {
+	case []interface{}:
+		if union, ok := schema.(*avro.UnionSchema); ok && len(union.Types) == len(definition) {
+			for i, branch := range definition {
+				union.Types[i] = annotate(union.Types[i], branch, namespace)
+			}
+		}
+	case map[string]interface{}:
+		switch typed := schema.(type) {
+		case *avro.RecordSchema:
+			namespace = namedType(&typed.Name, &typed.Namespace, definition, namespace)
+			typed.Aliases = qualifiedAliases(definition, namespace)
+			fields, _ := definition["fields"].([]interface{})
+			if len(fields) != len(typed.Fields) {
+				return schema
+			}
+			for i, field := range typed.Fields {
+				fieldDefinition, ok := fields[i].(map[string]interface{})
+				if !ok {
+					continue
+				}
+				if aliases := stringList(fieldDefinition[aliasesProperty]); len(aliases) > 0 {
+					if field.Properties == nil {
+						field.Properties = make(map[string]interface{})
+					}
+					field.Properties[aliasesProperty] = aliases
+				}
+				field.Type = annotate(field.Type, fieldDefinition["type"], namespace)
+			}
+		case *avro.EnumSchema:
+			namespace = namedType(&typed.Name, &typed.Namespace, definition, namespace)
+			typed.Aliases = qualifiedAliases(definition, namespace)
+		case *avro.FixedSchema:
+			namespace = namedType(&typed.Name, &typed.Namespace, definition, namespace)
+			if typed.Properties == nil {
+				typed.Properties = make(map[string]interface{})
+			}
+			typed.Properties[aliasesProperty] = qualifiedAliases(definition, namespace)
+		case *avro.ArraySchema:
+			typed.Items = annotate(typed.Items, definition["items"], namespace)
+		case *avro.MapSchema:
+			typed.Values = annotate(typed.Values, definition["values"], namespace)
+		default:
+			if _, ok := definition[logicalTypeProperty]; ok && primitive(schema) {
+				return newLogicalSchema(schema, definition)
+			}
+			// {"type": {...}} wraps another definition
+			return annotate(schema, definition["type"], namespace)
+		}
+	}
+	return schema
+}
+
+// namedType sets the inherited namespace of a named type unless its name is already a full name,
+// and returns the namespace the types nested in it inherit.
+func namedType(name *string, namespace *string, definition map[string]interface{}, enclosing string) string {
+	if index := strings.LastIndex(*name, "."); index >= 0 {
+		return (*name)[:index]
+	}
+	if _, ok := definition["namespace"]; !ok {
+		*namespace = enclosing
+	}
+	return *namespace
+}
+
+func qualifiedAliases(definition map[string]interface{}, namespace string) []string {
+	aliases := stringList(definition[aliasesProperty])
+	for i, alias := range aliases {
+		aliases[i] = fullName(alias, namespace)
+	}
+	return aliases
+}
+
+func stringList(raw interface{}) []string {
+	list, _ := raw.([]interface{})
+	strs := make([]string, 0, len(list))
+	for _, item := range list {
+		if str, ok := item.(string); ok {
+			strs = append(strs, str)
+		}
+	}
+	return strs
+}
+
+func fullName(name string, namespace string) string {
+	if namespace != "" && !strings.Contains(name, ".") {
+		return namespace + "." + name
+	}
+	return name
+}
+
+// aliases returns the full names a named type can also be referred to by.
+func aliases(schema avro.Schema) []string {
+	switch typed := schema.(type) {
+	case *avro.RecordSchema:
+		return typed.Aliases
+	case *avro.EnumSchema:
+		return typed.Aliases
+	case *avro.FixedSchema:
+		aliases, _ := typed.Properties[aliasesProperty].([]string)
+		return aliases
+	}
+	return nil
+}
+
+func fieldAliases(field *avro.SchemaField) []string {
+	aliases, _ := field.Properties[aliasesProperty].([]string)
+	return aliases
+}
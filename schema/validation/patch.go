@@ -0,0 +1,359 @@
+/* Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Patch applies fixes to a schema and returns the patched schema. Fixes that are the same as one applied before are skipped.
+// The patched schema is compact JSON with its properties in alphabetical order.
+func Patch(rawSchema string, fixes []*Fix) (string, error) {
+	var raw interface{}
+	err := json.Unmarshal([]byte(rawSchema), &raw)
+	if err != nil {
+		return "", err
+	}
+	patcher := &schemaPatcher{named: make(map[string]map[string]interface{})}
+	patcher.collect(raw, "")
+
+	applied := make(map[string]bool)
+	for _, fix := range fixes {
+		key := fix.Action + " " + fix.Path + " " + string(fix.Value)
+		if applied[key] {
+			continue
+		}
+		applied[key] = true
+		err = patcher.apply(&raw, fix)
+		if err != nil {
+			return "", fmt.Errorf("%s at %s: %s", fix.Action, fix.Path, err)
+		}
+	}
+	return encode(sweep(raw)), nil
+}
+
+// removed marks a union branch that is removed once every fix is applied, so that the paths of other branches stay valid.
+type removed struct{}
+
+// slot is a place in the schema JSON that can be read and written, namespace is the one names are resolved in there.
+type slot struct {
+	get       func() interface{}
+	set       func(interface{})
+	namespace string
+}
+
+// schemaPatcher finds the places fixes apply to. References to named types are followed to their definitions.
+type schemaPatcher struct {
+	named map[string]map[string]interface{}
+}
+
+func (p *schemaPatcher) collect(raw interface{}, namespace string) {
+	switch definition := raw.(type) {
+	case []interface{}:
+		for _, branch := range definition {
+			p.collect(branch, namespace)
+		}
+	case map[string]interface{}:
+		switch definition["type"] {
+		case "record", "error", "enum", "fixed":
+			full := p.fullName(definition, namespace)
+			p.named[full] = definition
+			fields, _ := definition["fields"].([]interface{})
+			for _, raw := range fields {
+				if field, ok := raw.(map[string]interface{}); ok {
+					p.collect(field["type"], namespaceOf(full))
+				}
+			}
+		case "array":
+			p.collect(definition["items"], namespace)
+		case "map":
+			p.collect(definition["values"], namespace)
+		default:
+			p.collect(definition["type"], namespace)
+		}
+	}
+}
+
+func (p *schemaPatcher) fullName(definition map[string]interface{}, namespace string) string {
+	name, _ := definition["name"].(string)
+	if ns, ok := definition["namespace"].(string); ok {
+		namespace = ns
+	}
+	return fullName(name, namespace)
+}
+
+// deref returns the slot of the schema a slot holds: the definition of a referenced named type,
+// or the type of a {"type": ...} wrapper. Writing a referenced definition's slot replaces the reference.
+func (p *schemaPatcher) deref(s *slot) *slot {
+	for {
+		switch value := s.get().(type) {
+		case string:
+			definition, ok := p.named[fullName(value, s.namespace)]
+			if !ok {
+				definition, ok = p.named[value]
+			}
+			if !ok {
+				return s
+			}
+			return &slot{get: func() interface{} { return definition }, set: s.set, namespace: s.namespace}
+		case map[string]interface{}:
+			switch value["type"] {
+			case "record", "error", "enum", "fixed":
+				namespace := namespaceOf(p.fullName(value, s.namespace))
+				return &slot{get: s.get, set: s.set, namespace: namespace}
+			case "array", "map":
+				return s
+			}
+			if _, ok := value["type"].(string); ok {
+				if _, named := p.named[fullName(value["type"].(string), s.namespace)]; !named {
+					// an annotated primitive
+					return s
+				}
+			}
+			s = &slot{get: func() interface{} { return value["type"] }, set: func(v interface{}) { value["type"] = v }, namespace: s.namespace}
+		default:
+			return s
+		}
+	}
+}
+
+// locate returns the slot a path points to and whether it is a record field.
+func (p *schemaPatcher) locate(root *interface{}, path string) (*slot, bool, error) {
+	current := &slot{get: func() interface{} { return *root }, set: func(v interface{}) { *root = v }}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if path == "/" || path == "" {
+		segments = nil
+	}
+	field := false
+	for i := 0; i < len(segments); i++ {
+		segment := segments[i]
+		if field {
+			if segment != "type" {
+				return nil, false, fmt.Errorf("field has no %s", segment)
+			}
+			fieldDefinition := current.get().(map[string]interface{})
+			current = &slot{get: func() interface{} { return fieldDefinition["type"] },
+				set: func(v interface{}) { fieldDefinition["type"] = v }, namespace: current.namespace}
+			field = false
+			continue
+		}
+		current = p.deref(current)
+		switch value := current.get().(type) {
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(value) {
+				return nil, false, fmt.Errorf("union has no branch %s", segment)
+			}
+			current = &slot{get: func() interface{} { return value[index] }, set: func(v interface{}) { value[index] = v }, namespace: current.namespace}
+		case map[string]interface{}:
+			switch segment {
+			case "items", "values":
+				current = &slot{get: func() interface{} { return value[segment] }, set: func(v interface{}) { value[segment] = v }, namespace: current.namespace}
+			case "fields":
+				if i+1 >= len(segments) {
+					return current, false, nil
+				}
+				i++
+				fields, _ := value["fields"].([]interface{})
+				index := fieldIndex(fields, segments[i])
+				if index < 0 {
+					return nil, false, fmt.Errorf("record has no field %s", segments[i])
+				}
+				current = &slot{get: func() interface{} { return fields[index] }, set: func(v interface{}) { fields[index] = v }, namespace: current.namespace}
+				field = true
+			default:
+				return nil, false, fmt.Errorf("schema has no %s", segment)
+			}
+		default:
+			return nil, false, fmt.Errorf("schema has no %s", segment)
+		}
+	}
+	return current, field, nil
+}
+
+// renameReferences replaces every reference to a renamed named type with its new full name.
+func (p *schemaPatcher) renameReferences(raw interface{}, namespace string, oldName string, newName string) interface{} {
+	switch definition := raw.(type) {
+	case string:
+		if definition == oldName || fullName(definition, namespace) == oldName {
+			return newName
+		}
+	case []interface{}:
+		for i, branch := range definition {
+			definition[i] = p.renameReferences(branch, namespace, oldName, newName)
+		}
+	case map[string]interface{}:
+		switch definition["type"] {
+		case "record", "error":
+			inner := namespaceOf(p.fullName(definition, namespace))
+			fields, _ := definition["fields"].([]interface{})
+			for _, raw := range fields {
+				if field, ok := raw.(map[string]interface{}); ok {
+					field["type"] = p.renameReferences(field["type"], inner, oldName, newName)
+				}
+			}
+		case "enum", "fixed":
+		case "array":
+			definition["items"] = p.renameReferences(definition["items"], namespace, oldName, newName)
+		case "map":
+			definition["values"] = p.renameReferences(definition["values"], namespace, oldName, newName)
+		default:
+			definition["type"] = p.renameReferences(definition["type"], namespace, oldName, newName)
+		}
+	}
+	return raw
+}
+
+func fieldIndex(fields []interface{}, name string) int {
+	for index, raw := range fields {
+		if field, ok := raw.(map[string]interface{}); ok && field["name"] == name {
+			return index
+		}
+	}
+	return -1
+}
+
+func (p *schemaPatcher) apply(root *interface{}, fix *Fix) error {
+	var value interface{}
+	if len(fix.Value) > 0 {
+		if err := json.Unmarshal(fix.Value, &value); err != nil {
+			return err
+		}
+	}
+	target, field, err := p.locate(root, fix.Path)
+	if err != nil {
+		return err
+	}
+
+	if field {
+		definition := target.get().(map[string]interface{})
+		switch fix.Action {
+		case FixAddDefault:
+			definition["default"] = value
+		case FixMakeNullable:
+			definition["type"] = nullable(definition["type"])
+			definition["default"] = nil
+		case FixAddAlias:
+			definition[aliasesProperty] = appendString(definition[aliasesProperty], value)
+		default:
+			return fmt.Errorf("not a field action")
+		}
+		return nil
+	}
+
+	switch fix.Action {
+	case FixReplaceType:
+		target.set(value)
+		return nil
+	case FixRemoveUnionBranch:
+		target.set(removed{})
+		return nil
+	}
+	target = p.deref(target)
+	if fix.Action == FixAddUnionBranch {
+		branches, ok := target.get().([]interface{})
+		if !ok {
+			return fmt.Errorf("not a union")
+		}
+		target.set(append(branches, value))
+		return nil
+	}
+	definition, ok := target.get().(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("not a named type")
+	}
+	switch fix.Action {
+	case FixAddAlias:
+		definition[aliasesProperty] = appendString(definition[aliasesProperty], value)
+	case FixRename:
+		oldName := p.fullName(definition, target.namespace)
+		newName, _ := value.(string)
+		definition["name"] = newName
+		delete(definition, "namespace")
+		if !strings.Contains(newName, ".") {
+			definition["namespace"] = ""
+		}
+		delete(p.named, oldName)
+		p.named[newName] = definition
+		*root = p.renameReferences(*root, "", oldName, newName)
+	case FixSetSize:
+		definition["size"] = value
+	case FixAddEnumSymbol:
+		definition["symbols"] = appendString(definition["symbols"], value)
+	case FixRemoveEnumSymbol:
+		symbols, _ := definition["symbols"].([]interface{})
+		kept := make([]interface{}, 0, len(symbols))
+		for _, symbol := range symbols {
+			if symbol != value {
+				kept = append(kept, symbol)
+			}
+		}
+		definition["symbols"] = kept
+	case FixAddField:
+		fields, _ := definition["fields"].([]interface{})
+		definition["fields"] = append(fields, value)
+	default:
+		return fmt.Errorf("unknown action")
+	}
+	return nil
+}
+
+// nullable returns a union of null and the type with null first.
+func nullable(fieldType interface{}) interface{} {
+	branches, ok := fieldType.([]interface{})
+	if !ok {
+		return []interface{}{"null", fieldType}
+	}
+	union := []interface{}{"null"}
+	for _, branch := range branches {
+		if branch != "null" {
+			union = append(union, branch)
+		}
+	}
+	return union
+}
+
+func appendString(list interface{}, value interface{}) []interface{} {
+	values, _ := list.([]interface{})
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// sweep drops the union branches marked as removed.
+func sweep(raw interface{}) interface{} {
+	switch value := raw.(type) {
+	case []interface{}:
+		kept := make([]interface{}, 0, len(value))
+		for _, item := range value {
+			if _, ok := item.(removed); !ok {
+				kept = append(kept, sweep(item))
+			}
+		}
+		return kept
+	case map[string]interface{}:
+		for key, item := range value {
+			value[key] = sweep(item)
+		}
+	}
+	return raw
+}
@@ -0,0 +1,407 @@
+/* Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Protocol is an Avro protocol, parsed from IDL or from its JSON document, with the named types it defines.
+// Its messages are not kept, only its types can be registered.
+type Protocol struct {
+	Name      string
+	Namespace string
+	// Types lists the full names of the named types in the order they are defined.
+	Types       []string
+	definitions map[string]Record
+}
+
+func newProtocol(name string, namespace string) *Protocol {
+	return &Protocol{Name: name, Namespace: namespace, Types: make([]string, 0), definitions: make(map[string]Record)}
+}
+
+// ParseProtocol parses an Avro protocol JSON document. Syntax errors are *SchemaError with the line and column
+// they were found at.
+func ParseProtocol(source string) (*Protocol, error) {
+	decoder := json.NewDecoder(strings.NewReader(source))
+	decoder.UseNumber()
+	document, err := decodeOrdered(decoder)
+	if err == nil {
+		if _, err = decoder.Token(); err == io.EOF {
+			err = nil
+		} else if err == nil {
+			err = fmt.Errorf("Unexpected data after the protocol")
+		}
+	}
+	if err != nil {
+		return nil, jsonError(source, err)
+	}
+	definition, _ := document.(Record)
+	name, ok := definition.get("protocol").(string)
+	if !ok {
+		return nil, &SchemaError{Path: "/protocol", Message: "A protocol document needs a protocol name"}
+	}
+	namespace, _ := definition.get("namespace").(string)
+	protocol := newProtocol(name, namespace)
+	types, ok := definition.get("types").([]interface{})
+	if !ok && definition.get("types") != nil {
+		return nil, &SchemaError{Path: "/types", Message: "The types of a protocol must be an array"}
+	}
+	for i, raw := range types {
+		err := protocol.collect(raw, namespace, fmt.Sprintf("/types/%d", i), true)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return protocol, nil
+}
+
+// collect defines the named types of a protocol document, the top level ones and those defined inline.
+func (p *Protocol) collect(raw interface{}, namespace string, path string, named bool) error {
+	switch definition := raw.(type) {
+	case []interface{}:
+		for i, branch := range definition {
+			if err := p.collect(branch, namespace, fmt.Sprintf("%s/%d", path, i), false); err != nil {
+				return err
+			}
+		}
+		return nil
+	case Record:
+		switch definition.get("type") {
+		case "record", "error", "enum", "fixed":
+			name, _ := definition.get("name").(string)
+			if ns, ok := definition.get("namespace").(string); ok {
+				namespace = ns
+			}
+			full := fullName(name, namespace)
+			if name == "" || p.definitions[full] != nil {
+				return &SchemaError{Path: path + "/name", Message: fmt.Sprintf("Type %q is defined more than once or has no name", full)}
+			}
+			p.define(full, definition)
+			fields, _ := definition.get("fields").([]interface{})
+			for i, field := range fields {
+				fieldDefinition, _ := field.(Record)
+				err := p.collect(fieldDefinition.get("type"), namespaceOf(full), fmt.Sprintf("%s/fields/%d/type", path, i), false)
+				if err != nil {
+					return err
+				}
+				if message := integerDefault(fieldDefinition.get("type"), fieldDefinition.get("default")); message != "" {
+					return &SchemaError{Path: fmt.Sprintf("%s/fields/%d/default", path, i), Message: message}
+				}
+			}
+			return nil
+		case "array":
+			return p.collect(definition.get("items"), namespace, path+"/items", false)
+		case "map":
+			return p.collect(definition.get("values"), namespace, path+"/values", false)
+		}
+		if !named {
+			return p.collect(definition.get("type"), namespace, path, false)
+		}
+	}
+	if named {
+		return &SchemaError{Path: path, Message: "A protocol type must be a record, an error, an enum or a fixed type"}
+	}
+	return nil
+}
+
+// integerBits are the sizes of the Avro integer types.
+var integerBits = map[string]int{"int": 32, "long": 64}
+
+// integerDefault tells why a field default doesn't fit the field's int or long type, or returns "" if it does
+// or the field isn't one. The default of a union is a value of its first branch.
+func integerDefault(schema interface{}, value interface{}) string {
+	if branches, ok := schema.([]interface{}); ok && len(branches) > 0 {
+		schema = branches[0]
+	}
+	if definition, ok := schema.(Record); ok {
+		schema = definition.get("type")
+	}
+	name, _ := schema.(string)
+	bits, ok := integerBits[name]
+	number, isNumber := value.(json.Number)
+	if !ok || !isNumber {
+		return ""
+	}
+	if _, err := strconv.ParseInt(string(number), 10, bits); err != nil {
+		return fmt.Sprintf("Default %s is not a valid %s", number, name)
+	}
+	return ""
+}
+
+func (p *Protocol) define(full string, definition Record) {
+	p.Types = append(p.Types, full)
+	p.definitions[full] = definition
+}
+
+// Schema returns the JSON schema of a named type of the protocol, with every type it refers to defined inline
+// where it is first used. An empty name selects the only record of the protocol.
+func (p *Protocol) Schema(name string) (string, error) {
+	full, err := p.lookup(name)
+	if err != nil {
+		return "", err
+	}
+	expander := &protocolExpander{protocol: p, defined: make(map[string]bool)}
+	schema, err := expander.named(p.definitions[full], full, "")
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// lookup returns the full name of a type given by its full name, or by its name if that is unambiguous.
+func (p *Protocol) lookup(name string) (string, error) {
+	if name == "" {
+		records := make([]string, 0)
+		for _, full := range p.Types {
+			if p.definitions[full].get("type") == "record" {
+				records = append(records, full)
+			}
+		}
+		if len(records) != 1 {
+			return "", &SchemaError{Path: "/", Message: fmt.Sprintf("The protocol defines %d records, select one of %s",
+				len(records), strings.Join(records, ", "))}
+		}
+		return records[0], nil
+	}
+	if _, ok := p.definitions[name]; ok {
+		return name, nil
+	}
+	matches := make([]string, 0)
+	for _, full := range p.Types {
+		if full[strings.LastIndex(full, ".")+1:] == name {
+			matches = append(matches, full)
+		}
+	}
+	if len(matches) != 1 {
+		return "", &SchemaError{Path: "/", Message: fmt.Sprintf("The protocol defines %d types named %s", len(matches), name)}
+	}
+	return matches[0], nil
+}
+
+// resolve returns the full name of the type a name refers to in a namespace, trying the protocol namespace
+// and the null namespace as IDL does, or "" if there is none.
+func (p *Protocol) resolve(name string, namespace string) string {
+	for _, full := range []string{fullName(name, namespace), fullName(name, p.Namespace), name} {
+		if _, ok := p.definitions[full]; ok {
+			return full
+		}
+	}
+	return ""
+}
+
+// protocolExpander copies a protocol type with the types it refers to, defining each one the first time it's used.
+type protocolExpander struct {
+	protocol *Protocol
+	defined  map[string]bool
+}
+
+func (e *protocolExpander) expand(schema interface{}, namespace string) (interface{}, error) {
+	switch typed := schema.(type) {
+	case *typeRef:
+		return e.reference(typed.full, namespace)
+	case string:
+		if primitiveTypes[typed] {
+			return typed, nil
+		}
+		full := fullName(typed, namespace)
+		if _, ok := e.protocol.definitions[full]; !ok {
+			full = typed
+		}
+		return e.reference(full, namespace)
+	case []interface{}:
+		branches := make([]interface{}, len(typed))
+		for i, branch := range typed {
+			expanded, err := e.expand(branch, namespace)
+			if err != nil {
+				return nil, err
+			}
+			branches[i] = expanded
+		}
+		return branches, nil
+	case Record:
+		switch typed.get("type") {
+		case "record", "error", "enum", "fixed":
+			name, _ := typed.get("name").(string)
+			ns := namespace
+			if explicit, ok := typed.get("namespace").(string); ok {
+				ns = explicit
+			}
+			full := fullName(name, ns)
+			if e.defined[full] {
+				return full, nil
+			}
+			return e.named(typed, full, namespace)
+		case "array":
+			return e.copy(typed, "items", namespace)
+		case "map":
+			return e.copy(typed, "values", namespace)
+		}
+		return e.copy(typed, "type", namespace)
+	}
+	return schema, nil
+}
+
+func (e *protocolExpander) reference(full string, namespace string) (interface{}, error) {
+	if e.defined[full] {
+		return full, nil
+	}
+	definition, ok := e.protocol.definitions[full]
+	if !ok {
+		return nil, &SchemaError{Path: "/", Message: fmt.Sprintf("Undefined type %s", full)}
+	}
+	return e.named(definition, full, namespace)
+}
+
+// named defines a named type, giving its namespace only where it differs from the enclosing one.
+// Errors are read as records, a schema can't define an error.
+func (e *protocolExpander) named(definition Record, full string, enclosing string) (interface{}, error) {
+	e.defined[full] = true
+	namespace := namespaceOf(full)
+	named := make(Record, 0, len(definition)+1)
+	for _, field := range definition {
+		switch field.Name {
+		case "type":
+			if field.Value == "error" {
+				named = append(named, &RecordField{"type", "record"})
+				continue
+			}
+		case "name":
+			named = append(named, &RecordField{"name", full[strings.LastIndex(full, ".")+1:]})
+			if namespace != enclosing {
+				named = append(named, &RecordField{"namespace", namespace})
+			}
+			continue
+		case "namespace":
+			continue
+		case "fields":
+			rawFields, _ := field.Value.([]interface{})
+			fields := make([]interface{}, len(rawFields))
+			for i, rawField := range rawFields {
+				fieldDefinition, _ := rawField.(Record)
+				expanded, err := e.copy(fieldDefinition, "type", namespace)
+				if err != nil {
+					return nil, err
+				}
+				fields[i] = expanded
+			}
+			named = append(named, &RecordField{"fields", fields})
+			continue
+		}
+		named = append(named, field)
+	}
+	return named, nil
+}
+
+// copy copies a definition, expanding the schema under key.
+func (e *protocolExpander) copy(definition Record, key string, namespace string) (interface{}, error) {
+	copied := make(Record, len(definition))
+	for i, field := range definition {
+		copied[i] = field
+		if field.Name == key {
+			expanded, err := e.expand(field.Value, namespace)
+			if err != nil {
+				return nil, err
+			}
+			copied[i] = &RecordField{key, expanded}
+		}
+	}
+	return copied, nil
+}
+
+func (r Record) get(name string) interface{} {
+	for _, field := range r {
+		if field.Name == name {
+			return field.Value
+		}
+	}
+	return nil
+}
+
+// decodeOrdered decodes a JSON value with objects as Records, keeping the order of their members, and numbers
+// as json.Number if the decoder uses them.
+func decodeOrdered(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch token {
+	case json.Delim('{'):
+		record := make(Record, 0)
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeOrdered(decoder)
+			if err != nil {
+				return nil, err
+			}
+			record = append(record, &RecordField{key.(string), value})
+		}
+		_, err = decoder.Token()
+		return record, err
+	case json.Delim('['):
+		list := make([]interface{}, 0)
+		for decoder.More() {
+			value, err := decodeOrdered(decoder)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		_, err = decoder.Token()
+		return list, err
+	}
+	return token, nil
+}
+
+// jsonError places a JSON decoding error in the source.
+func jsonError(source string, err error) error {
+	offset := len(source)
+	message := err.Error()
+	switch typed := err.(type) {
+	case *json.SyntaxError:
+		offset = int(typed.Offset) - 1
+	default:
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			message = "Unexpected end of input"
+		}
+	}
+	line, column := lineColumn(source, offset)
+	return &SchemaError{Line: line, Column: column, Message: message}
+}
+
+// lineColumn returns the line and the column of a byte offset, both counted from 1.
+func lineColumn(source string, offset int) (int, int) {
+	if offset > len(source) {
+		offset = len(source)
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	start := strings.LastIndex(source[:offset], "\n") + 1
+	return strings.Count(source[:offset], "\n") + 1, utf8.RuneCountInString(source[start:offset]) + 1
+}
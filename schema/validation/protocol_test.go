@@ -0,0 +1,80 @@
+/* Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package validation
+
+import (
+	"testing"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+	"gopkg.in/stretchr/testify.v1/require"
+)
+
+func TestParseProtocol(t *testing.T) {
+	protocol, err := ParseProtocol(`{"protocol": "People", "namespace": "com.acme", "types": [
+     {"type": "fixed", "name": "MD5", "size": 16},
+     {"type": "record", "name": "Person", "namespace": "com.other", "fields": [
+       {"name": "hash", "type": "com.acme.MD5"},
+       {"name": "role", "type": {"type": "enum", "name": "Role", "symbols": ["ADMIN", "USER"]}},
+       {"name": "roles", "type": {"type": "array", "items": "Role"}}
+     ]}],
+   "messages": {}}`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"com.acme.MD5", "com.other.Person", "com.other.Role"}, protocol.Types)
+
+	schema, err := protocol.Schema("")
+	require.NoError(t, err)
+	assert.Empty(t, CheckSchema(schema))
+	assert.JSONEq(t, `{"type": "record", "name": "Person", "namespace": "com.other", "fields": [
+       {"name": "hash", "type": {"type": "fixed", "name": "MD5", "namespace": "com.acme", "size": 16}},
+       {"name": "role", "type": {"type": "enum", "name": "Role", "symbols": ["ADMIN", "USER"]}},
+       {"name": "roles", "type": {"type": "array", "items": "com.other.Role"}}]}`, schema)
+
+	schema, err = protocol.Schema("com.other.Role")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type": "enum", "name": "Role", "namespace": "com.other", "symbols": ["ADMIN", "USER"]}`, schema)
+
+	_, err = protocol.Schema("Address")
+	assert.Error(t, err)
+}
+
+func TestParseProtocolErrors(t *testing.T) {
+	_, err := ParseProtocol("{\"protocol\": \"People\",\n \"types\": [}")
+	require.Error(t, err)
+	schemaError := err.(*SchemaError)
+	assert.Equal(t, 2, schemaError.Line)
+	assert.Equal(t, 12, schemaError.Column)
+
+	_, err = ParseProtocol(`{"type": "record", "name": "Person", "fields": []}`)
+	assert.Error(t, err)
+
+	_, err = ParseProtocol(`{"protocol": "People", "types": ["string"]}`)
+	assert.Error(t, err)
+
+	_, err = ParseProtocol(`{"protocol": "P", "types": "x"}`)
+	require.Error(t, err)
+	assert.Equal(t, "/types", err.(*SchemaError).Path)
+
+	_, err = ParseProtocol(`{"protocol": "P", "types": [{"type": "record", "name": "A", "fields": [
+     {"name": "a", "type": "long", "default": 99999999999},
+     {"name": "b", "type": "int", "default": 99999999999}]}]}`)
+	require.Error(t, err)
+	assert.Equal(t, "/types/0/fields/1/default", err.(*SchemaError).Path)
+
+	protocol, err := ParseProtocol(`{"protocol": "People", "types": [{"type": "record", "name": "A", "fields": [{"name": "b", "type": "B"}]}]}`)
+	require.NoError(t, err)
+	_, err = protocol.Schema("A")
+	assert.Error(t, err)
+}
@@ -0,0 +1,94 @@
+/* Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/elodina/go-avro"
+)
+
+// Steps of a resolution plan.
+const (
+	StepReadField    = "READ_FIELD"
+	StepUseDefault   = "USE_DEFAULT"
+	StepSkipField    = "SKIP_FIELD"
+	StepPromote      = "PROMOTE"
+	StepSelectBranch = "SELECT_BRANCH"
+	StepMatchAlias   = "MATCH_ALIAS"
+)
+
+// ResolutionStep is one decision resolution makes reading data of the writer schema with the reader schema.
+// Path points into the reader schema and WriterPath into the writer schema, the way Incompatibility.Path does.
+type ResolutionStep struct {
+	Action     string          `json:"action"`
+	Path       string          `json:"path"`
+	WriterPath string          `json:"writer_path"`
+	Default    json.RawMessage `json:"default,omitempty"`
+	Message    string          `json:"message"`
+}
+
+// ResolutionPlan is how data written with one schema is read with another, step by step in the order resolution
+// takes them, along with every incompatibility that keeps it from being read.
+type ResolutionPlan struct {
+	Compatible        bool              `json:"compatible"`
+	Steps             []*ResolutionStep `json:"steps"`
+	Incompatibilities Incompatibilities `json:"incompatibilities"`
+}
+
+// Explain returns the plan for reading data written with the writer schema with the reader schema.
+// Fixes to either schema are left out.
+func Explain(writer avro.Schema, reader avro.Schema) *ResolutionPlan {
+	r := newResolver()
+	r.explain = true
+	r.steps = make([]*ResolutionStep, 0)
+	r.resolve(writer, reader)
+	for _, incompatibility := range r.found {
+		incompatibility.Fixes = nil
+	}
+	found := r.found
+	if found == nil {
+		found = make(Incompatibilities, 0)
+	}
+	return &ResolutionPlan{Compatible: len(found) == 0, Steps: r.steps, Incompatibilities: found}
+}
+
+// step records a step of the resolution plan at the given segments below the current locations of both schemas.
+func (r *resolver) step(action string, readerSegments []string, writerSegments []string, value interface{}, format string, args ...interface{}) {
+	if !r.explain {
+		return
+	}
+	step := &ResolutionStep{
+		Action:     action,
+		Path:       joinPath(r.location(), "/"+strings.Join(readerSegments, "/")),
+		WriterPath: joinPath(r.writerLocation(), "/"+strings.Join(writerSegments, "/")),
+		Message:    fmt.Sprintf(format, args...),
+	}
+	if action == StepUseDefault {
+		step.Default = json.RawMessage(fragment(value))
+	}
+	r.steps = append(r.steps, step)
+}
+
+// explainNames records a named type read through an alias of the reader.
+func (r *resolver) explainNames(writer avro.Schema, reader avro.Schema) {
+	writerName, readerName := avro.GetFullName(writer), avro.GetFullName(reader)
+	if writerName != readerName {
+		r.step(StepMatchAlias, nil, nil, nil, "Writer %s is read as %s, an alias", writerName, readerName)
+	}
+}
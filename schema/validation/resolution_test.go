@@ -0,0 +1,85 @@
+/* Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package validation
+
+import (
+	"testing"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+	"gopkg.in/stretchr/testify.v1/require"
+)
+
+func TestExplain(t *testing.T) {
+	writer, err := ParseSchema(`{"type": "record", "name": "User", "namespace": "com.acme", "fields": [
+		{"name": "id", "type": "int"},
+		{"name": "login", "type": "string"},
+		{"name": "status", "type": "string"},
+		{"name": "email", "type": "string"}
+	]}`)
+	require.NoError(t, err)
+	reader, err := ParseSchema(`{"type": "record", "name": "Account", "namespace": "com.acme", "aliases": ["User"], "fields": [
+		{"name": "id", "type": "long"},
+		{"name": "name", "type": "string", "aliases": ["login"]},
+		{"name": "status", "type": ["null", "string"]},
+		{"name": "age", "type": "int", "default": 0}
+	]}`)
+	require.NoError(t, err)
+
+	plan := Explain(writer, reader)
+	assert.True(t, plan.Compatible)
+	assert.Empty(t, plan.Incompatibilities)
+
+	actions := make([]string, 0)
+	for _, step := range plan.Steps {
+		actions = append(actions, step.Action+" "+step.Path+" "+step.WriterPath)
+	}
+	assert.Equal(t, []string{
+		StepMatchAlias + " / /",
+		StepSkipField + " / /fields/email",
+		StepReadField + " /fields/id /fields/id",
+		StepPromote + " /fields/id/type /fields/id/type",
+		StepReadField + " /fields/name /fields/login",
+		StepReadField + " /fields/status /fields/status",
+		StepSelectBranch + " /fields/status/type/1 /fields/status/type",
+		StepUseDefault + " /fields/age /",
+	}, actions)
+	assert.Equal(t, "0", string(plan.Steps[7].Default))
+}
+
+func TestExplainIncompatible(t *testing.T) {
+	writer, err := ParseSchema(`{"type": "record", "name": "User", "fields": [
+		{"name": "tags", "type": {"type": "array", "items": ["int", "string"]}}
+	]}`)
+	require.NoError(t, err)
+	reader, err := ParseSchema(`{"type": "record", "name": "User", "fields": [
+		{"name": "tags", "type": {"type": "array", "items": "string"}},
+		{"name": "age", "type": "int"}
+	]}`)
+	require.NoError(t, err)
+
+	plan := Explain(writer, reader)
+	assert.False(t, plan.Compatible)
+	require.Len(t, plan.Incompatibilities, 2)
+	assert.Empty(t, plan.Incompatibilities[0].Fixes)
+
+	branches := make([]string, 0)
+	for _, step := range plan.Steps {
+		if step.Action == StepSelectBranch {
+			branches = append(branches, step.WriterPath)
+		}
+	}
+	assert.Equal(t, []string{"/fields/tags/type/items/0", "/fields/tags/type/items/1"}, branches)
+}
@@ -0,0 +1,340 @@
+/* Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Rule levels. A schema breaking an ERROR rule is rejected, a WARN rule only reports it.
+const (
+	RuleLevelError = "ERROR"
+	RuleLevelWarn  = "WARN"
+)
+
+// Built-in rules.
+const (
+	RuleDocRequired     = "doc-required"
+	RuleSnakeCaseFields = "snake-case-fields"
+	RuleNamespacePrefix = "namespace-prefix"
+	RuleNoBytesIDs      = "no-bytes-ids"
+	RuleMaxDepth        = "max-depth"
+)
+
+// Kinds of schema nodes rules are checked on.
+const (
+	NodeRecord = "record"
+	NodeEnum   = "enum"
+	NodeFixed  = "fixed"
+	NodeField  = "field"
+)
+
+// Node is a named type or a record field of a schema. Name is the full name of a type and the name of a field,
+// Type is the JSON definition of a field's type. Depth counts the records a node is in, the outermost record included.
+type Node struct {
+	Kind      string
+	Path      string
+	Name      string
+	Namespace string
+	Doc       string
+	Type      interface{}
+	Depth     int
+}
+
+// Rule checks a convention on every node of a schema.
+type Rule interface {
+	// ValidateParam returns an error if the rule can't be used with the given parameter.
+	ValidateParam(param string) error
+	// Check returns a message for every way the node breaks the rule.
+	Check(node *Node, param string) []string
+}
+
+var rules = map[string]Rule{
+	RuleDocRequired:     new(docRequired),
+	RuleSnakeCaseFields: new(snakeCaseFields),
+	RuleNamespacePrefix: new(namespacePrefix),
+	RuleNoBytesIDs:      new(noBytesIDs),
+	RuleMaxDepth:        new(maxDepth),
+}
+
+// RegisterRule makes a rule available to rule sets under the given name, replacing any rule with the same name.
+// It is not safe to call while schemas are linted.
+func RegisterRule(name string, rule Rule) {
+	rules[name] = rule
+}
+
+// RuleConfig enables a rule at a level. Param configures the rules that take one:
+// the namespace prefix of namespace-prefix and the depth of max-depth.
+type RuleConfig struct {
+	Name  string `json:"name"`
+	Level string `json:"level"`
+	Param string `json:"param,omitempty"`
+}
+
+// RuleSet is the rules a tenant or a subject enforces on new schemas.
+type RuleSet struct {
+	Rules []*RuleConfig `json:"rules"`
+}
+
+// ParseRuleSet decodes and validates a rule set.
+func ParseRuleSet(raw string) (*RuleSet, error) {
+	ruleSet := new(RuleSet)
+	err := json.Unmarshal([]byte(raw), ruleSet)
+	if err != nil {
+		return nil, err
+	}
+	return ruleSet, ruleSet.Validate()
+}
+
+// Validate returns an error if the rule set has an unknown rule, an unknown level or a parameter its rule can't use.
+func (rs *RuleSet) Validate() error {
+	for _, config := range rs.Rules {
+		rule, ok := rules[config.Name]
+		if !ok {
+			return fmt.Errorf("Unknown rule %s", config.Name)
+		}
+		if config.Level != RuleLevelError && config.Level != RuleLevelWarn {
+			return fmt.Errorf("Rule %s has invalid level %q", config.Name, config.Level)
+		}
+		if err := rule.ValidateParam(config.Param); err != nil {
+			return fmt.Errorf("Rule %s: %s", config.Name, err)
+		}
+	}
+	return nil
+}
+
+// Violation is a place a schema breaks a rule.
+type Violation struct {
+	Rule    string `json:"rule"`
+	Level   string `json:"level"`
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+type Violations []*Violation
+
+// Errors returns the violations of ERROR rules.
+func (v Violations) Errors() Violations {
+	return v.level(RuleLevelError)
+}
+
+// Warnings returns the violations of WARN rules.
+func (v Violations) Warnings() Violations {
+	return v.level(RuleLevelWarn)
+}
+
+func (v Violations) level(level string) Violations {
+	filtered := make(Violations, 0)
+	for _, violation := range v {
+		if violation.Level == level {
+			filtered = append(filtered, violation)
+		}
+	}
+	return filtered
+}
+
+// Lint checks a schema against every rule of the rule set. Rules the registry doesn't know are skipped.
+func Lint(rawSchema string, ruleSet *RuleSet) (Violations, error) {
+	var raw interface{}
+	err := json.Unmarshal([]byte(rawSchema), &raw)
+	if err != nil {
+		return nil, err
+	}
+	walker := &nodeWalker{}
+	walker.walk(raw, "", "", 0)
+
+	violations := make(Violations, 0)
+	for _, config := range ruleSet.Rules {
+		rule, ok := rules[config.Name]
+		if !ok {
+			continue
+		}
+		for _, node := range walker.nodes {
+			for _, message := range rule.Check(node, config.Param) {
+				violations = append(violations, &Violation{config.Name, config.Level, node.Path, message})
+			}
+		}
+	}
+	return violations, nil
+}
+
+// nodeWalker lists the named types and fields of a schema in the order they are defined.
+type nodeWalker struct {
+	nodes []*Node
+}
+
+func (nw *nodeWalker) walk(raw interface{}, path string, namespace string, depth int) {
+	switch definition := raw.(type) {
+	case []interface{}:
+		for i, branch := range definition {
+			nw.walk(branch, fmt.Sprintf("%s/%d", path, i), namespace, depth)
+		}
+	case map[string]interface{}:
+		switch definition["type"] {
+		case "record", "error":
+			node := nw.named(NodeRecord, definition, path, namespace, depth+1)
+			fields, _ := definition["fields"].([]interface{})
+			for _, raw := range fields {
+				field, ok := raw.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				name, _ := field["name"].(string)
+				doc, _ := field["doc"].(string)
+				fieldPath := path + "/fields/" + name
+				nw.nodes = append(nw.nodes, &Node{Kind: NodeField, Path: fieldPath, Name: name, Doc: doc, Type: field["type"], Depth: node.Depth})
+				nw.walk(field["type"], fieldPath+"/type", node.Namespace, node.Depth)
+			}
+		case "enum":
+			nw.named(NodeEnum, definition, path, namespace, depth)
+		case "fixed":
+			nw.named(NodeFixed, definition, path, namespace, depth)
+		case "array":
+			nw.walk(definition["items"], path+"/items", namespace, depth)
+		case "map":
+			nw.walk(definition["values"], path+"/values", namespace, depth)
+		default:
+			if _, ok := definition["type"].(string); !ok {
+				nw.walk(definition["type"], path, namespace, depth)
+			}
+		}
+	}
+}
+
+func (nw *nodeWalker) named(kind string, definition map[string]interface{}, path string, namespace string, depth int) *Node {
+	name, _ := definition["name"].(string)
+	if ns, ok := definition["namespace"].(string); ok {
+		namespace = ns
+	}
+	full := fullName(name, namespace)
+	doc, _ := definition["doc"].(string)
+	if path == "" {
+		path = "/"
+	}
+	node := &Node{Kind: kind, Path: path, Name: full, Namespace: namespaceOf(full), Doc: doc, Depth: depth}
+	nw.nodes = append(nw.nodes, node)
+	return node
+}
+
+func noParam(param string) error {
+	if param != "" {
+		return fmt.Errorf("takes no parameter")
+	}
+	return nil
+}
+
+// docRequired requires a doc on every named type and field.
+type docRequired struct{}
+
+func (*docRequired) ValidateParam(param string) error {
+	return noParam(param)
+}
+
+func (*docRequired) Check(node *Node, param string) []string {
+	if strings.TrimSpace(node.Doc) != "" {
+		return nil
+	}
+	return []string{fmt.Sprintf("%s %s has no doc", strings.Title(node.Kind), node.Name)}
+}
+
+var snakeCase = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+
+// snakeCaseFields requires field names in snake_case.
+type snakeCaseFields struct{}
+
+func (*snakeCaseFields) ValidateParam(param string) error {
+	return noParam(param)
+}
+
+func (*snakeCaseFields) Check(node *Node, param string) []string {
+	if node.Kind != NodeField || snakeCase.MatchString(node.Name) {
+		return nil
+	}
+	return []string{fmt.Sprintf("Field name %s is not snake_case", node.Name)}
+}
+
+// namespacePrefix requires every named type to be in the namespace given as the parameter or below it.
+type namespacePrefix struct{}
+
+func (*namespacePrefix) ValidateParam(param string) error {
+	if param == "" || !validFullName(param) {
+		return fmt.Errorf("needs a namespace as its parameter")
+	}
+	return nil
+}
+
+func (*namespacePrefix) Check(node *Node, prefix string) []string {
+	if node.Kind == NodeField || node.Namespace == prefix || strings.HasPrefix(node.Namespace, prefix+".") {
+		return nil
+	}
+	return []string{fmt.Sprintf("%s %s is not in namespace %s", strings.Title(node.Kind), node.Name, prefix)}
+}
+
+// noBytesIDs forbids bytes for id fields: id, *_id and *Id.
+type noBytesIDs struct{}
+
+func (*noBytesIDs) ValidateParam(param string) error {
+	return noParam(param)
+}
+
+func (*noBytesIDs) Check(node *Node, param string) []string {
+	if node.Kind != NodeField || !(strings.ToLower(node.Name) == "id" || strings.HasSuffix(node.Name, "_id") || strings.HasSuffix(node.Name, "Id")) {
+		return nil
+	}
+	if !hasBytes(node.Type) {
+		return nil
+	}
+	return []string{fmt.Sprintf("Id field %s is bytes", node.Name)}
+}
+
+func hasBytes(raw interface{}) bool {
+	switch definition := raw.(type) {
+	case string:
+		return definition == "bytes"
+	case []interface{}:
+		for _, branch := range definition {
+			if hasBytes(branch) {
+				return true
+			}
+		}
+	case map[string]interface{}:
+		return hasBytes(definition["type"])
+	}
+	return false
+}
+
+// maxDepth limits how deep records can be nested, the outermost record is at depth 1.
+type maxDepth struct{}
+
+func (*maxDepth) ValidateParam(param string) error {
+	depth, err := strconv.Atoi(param)
+	if err != nil || depth < 1 {
+		return fmt.Errorf("needs a positive depth as its parameter")
+	}
+	return nil
+}
+
+func (*maxDepth) Check(node *Node, param string) []string {
+	depth, _ := strconv.Atoi(param)
+	if node.Kind != NodeRecord || node.Depth <= depth {
+		return nil
+	}
+	return []string{fmt.Sprintf("Record %s is nested %d deep, more than %d", node.Name, node.Depth, depth)}
+}
@@ -0,0 +1,126 @@
+/* Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package validation
+
+import (
+	"testing"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+	"gopkg.in/stretchr/testify.v1/require"
+)
+
+const lintedSchema = `{"type": "record", "name": "Order", "namespace": "com.acme.orders", "doc": "An order", "fields": [
+     {"name": "order_id", "type": "bytes", "doc": "Order id"},
+     {"name": "customerId", "type": ["null", "bytes"], "default": null},
+     {"name": "line", "type": {"type": "record", "name": "Line", "namespace": "org.other", "fields": [
+         {"name": "item", "type": {"type": "record", "name": "Item", "doc": "An item", "fields": []}, "doc": "Item"}
+     ]}, "doc": "Line"}
+ ]}`
+
+func lint(t *testing.T, configs ...*RuleConfig) Violations {
+	ruleSet := &RuleSet{Rules: configs}
+	require.NoError(t, ruleSet.Validate())
+	violations, err := Lint(lintedSchema, ruleSet)
+	require.NoError(t, err)
+	return violations
+}
+
+func violationPaths(violations Violations) []string {
+	paths := make([]string, len(violations))
+	for i, violation := range violations {
+		paths[i] = violation.Path
+	}
+	return paths
+}
+
+func TestLintDocRequired(t *testing.T) {
+	violations := lint(t, &RuleConfig{Name: RuleDocRequired, Level: RuleLevelError})
+	assert.Equal(t, []string{"/fields/customerId", "/fields/line/type"}, violationPaths(violations))
+	assert.Equal(t, "Field customerId has no doc", violations[0].Message)
+	assert.Equal(t, "Record org.other.Line has no doc", violations[1].Message)
+}
+
+func TestLintSnakeCaseFields(t *testing.T) {
+	violations := lint(t, &RuleConfig{Name: RuleSnakeCaseFields, Level: RuleLevelWarn})
+	require.Len(t, violations, 1)
+	assert.Equal(t, &Violation{RuleSnakeCaseFields, RuleLevelWarn, "/fields/customerId", "Field name customerId is not snake_case"}, violations[0])
+}
+
+func TestLintNamespacePrefix(t *testing.T) {
+	violations := lint(t, &RuleConfig{Name: RuleNamespacePrefix, Level: RuleLevelError, Param: "com.acme"})
+	assert.Equal(t, []string{"/fields/line/type", "/fields/line/type/fields/item/type"}, violationPaths(violations))
+
+	violations = lint(t, &RuleConfig{Name: RuleNamespacePrefix, Level: RuleLevelError, Param: "org"})
+	assert.Equal(t, []string{"/"}, violationPaths(violations))
+	assert.Len(t, lint(t, &RuleConfig{Name: RuleNamespacePrefix, Level: RuleLevelError, Param: "com.ac"}), 3)
+}
+
+func TestLintNoBytesIDs(t *testing.T) {
+	violations := lint(t, &RuleConfig{Name: RuleNoBytesIDs, Level: RuleLevelError})
+	assert.Equal(t, []string{"/fields/order_id", "/fields/customerId"}, violationPaths(violations))
+}
+
+func TestLintMaxDepth(t *testing.T) {
+	assert.Empty(t, lint(t, &RuleConfig{Name: RuleMaxDepth, Level: RuleLevelError, Param: "3"}))
+	violations := lint(t, &RuleConfig{Name: RuleMaxDepth, Level: RuleLevelError, Param: "1"})
+	assert.Equal(t, []string{"/fields/line/type", "/fields/line/type/fields/item/type"}, violationPaths(violations))
+	assert.Equal(t, "Record org.other.Item is nested 3 deep, more than 1", violations[1].Message)
+}
+
+func TestLintLevels(t *testing.T) {
+	violations := lint(t, &RuleConfig{Name: RuleSnakeCaseFields, Level: RuleLevelWarn},
+		&RuleConfig{Name: RuleNoBytesIDs, Level: RuleLevelError})
+	assert.Len(t, violations.Warnings(), 1)
+	assert.Len(t, violations.Errors(), 2)
+}
+
+func TestRuleSetValidate(t *testing.T) {
+	invalid := []*RuleConfig{
+		{Name: "no-such-rule", Level: RuleLevelError},
+		{Name: RuleDocRequired, Level: "INFO"},
+		{Name: RuleDocRequired, Level: RuleLevelError, Param: "x"},
+		{Name: RuleNamespacePrefix, Level: RuleLevelError},
+		{Name: RuleNamespacePrefix, Level: RuleLevelError, Param: "com..acme"},
+		{Name: RuleMaxDepth, Level: RuleLevelError, Param: "0"},
+	}
+	for _, config := range invalid {
+		assert.Error(t, (&RuleSet{Rules: []*RuleConfig{config}}).Validate(), config.Name)
+	}
+
+	ruleSet, err := ParseRuleSet(`{"rules": [{"name": "max-depth", "level": "WARN", "param": "2"}]}`)
+	require.NoError(t, err)
+	assert.Equal(t, &RuleConfig{RuleMaxDepth, RuleLevelWarn, "2"}, ruleSet.Rules[0])
+}
+
+type forbiddenName struct{}
+
+func (*forbiddenName) ValidateParam(param string) error {
+	return nil
+}
+
+func (*forbiddenName) Check(node *Node, param string) []string {
+	if node.Name == param {
+		return []string{"Forbidden"}
+	}
+	return nil
+}
+
+func TestRegisterRule(t *testing.T) {
+	RegisterRule("forbidden-name", new(forbiddenName))
+	defer delete(rules, "forbidden-name")
+	violations := lint(t, &RuleConfig{Name: "forbidden-name", Level: RuleLevelError, Param: "item"})
+	assert.Equal(t, []string{"/fields/line/type/fields/item"}, violationPaths(violations))
+}
@@ -24,21 +24,17 @@ type SchemaValidationStrategy interface {
 type validateCanRead struct{}
 
 func (vcr *validateCanRead) Validate(toValidate avro.Schema, existing avro.Schema) error {
-	return validate(existing, toValidate)
+	return check(existing, toValidate).fixing(false).err()
 }
 
 type validateCanBeRead struct{}
 
 func (vcr *validateCanBeRead) Validate(toValidate avro.Schema, existing avro.Schema) error {
-	return validate(toValidate, existing)
+	return check(toValidate, existing).fixing(true).err()
 }
 
 type validateMutualRead struct{}
 
 func (vmr *validateMutualRead) Validate(toValidate avro.Schema, existing avro.Schema) error {
-	err := validate(toValidate, existing)
-	if err != nil {
-		return err
-	}
-	return validate(existing, toValidate)
+	return append(check(toValidate, existing).fixing(true), check(existing, toValidate).fixing(false)...).err()
 }
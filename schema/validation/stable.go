@@ -0,0 +1,150 @@
+/* Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package validation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/elodina/go-avro"
+)
+
+// checkStable returns every way a reader schema, already able to read the writer schema, encodes data
+// of the writer schema to other bytes than the writer schema does. Resolution allows promotions, new fields
+// with defaults or new enum symbols, but a key encoded differently lands in another partition and
+// is not compacted with the keys written before.
+func checkStable(writer avro.Schema, reader avro.Schema) Incompatibilities {
+	c := &stabilityChecker{visited: make(map[recordPair]bool)}
+	c.check(writer, reader)
+	return c.found
+}
+
+// stabilityChecker walks a writer and a reader schema side by side, recursive records are checked once.
+type stabilityChecker struct {
+	visited map[recordPair]bool
+	path    []string
+	found   Incompatibilities
+}
+
+func (c *stabilityChecker) report(segments []string, writer interface{}, reader interface{}, format string, args ...interface{}) {
+	c.found = append(c.found, &Incompatibility{
+		Type:    EncodingChange,
+		Path:    "/" + strings.Join(append(c.path, segments...), "/"),
+		Message: fmt.Sprintf(format, args...),
+		Reader:  fragment(reader),
+		Writer:  fragment(writer),
+	})
+}
+
+func (c *stabilityChecker) checkAt(writer avro.Schema, reader avro.Schema, segments ...string) {
+	c.path = append(c.path, segments...)
+	c.check(writer, reader)
+	c.path = c.path[:len(c.path)-len(segments)]
+}
+
+func (c *stabilityChecker) check(writer avro.Schema, reader avro.Schema) {
+	writer = actual(writer)
+	reader = actual(reader)
+	writerType, readerType := writer.Type(), reader.Type()
+
+	if writerType == avro.Union && readerType == avro.Union {
+		// values keep their encoding as long as every writer branch keeps its index
+		writerBranches, readerBranches := writer.(*avro.UnionSchema).Types, reader.(*avro.UnionSchema).Types
+		for index, branch := range writerBranches {
+			if index >= len(readerBranches) {
+				c.report(nil, writer, reader, "Union branch %s is removed", avro.GetFullName(branch))
+				continue
+			}
+			if !sameType(actual(branch), actual(readerBranches[index])) {
+				c.report([]string{strconv.Itoa(index)}, branch, readerBranches[index],
+					"Union branch %d changes from %s to %s, values are written with another branch index",
+					index, avro.GetFullName(branch), avro.GetFullName(readerBranches[index]))
+				continue
+			}
+			c.checkAt(branch, readerBranches[index], strconv.Itoa(index))
+		}
+		return
+	}
+	if writerType == avro.Union {
+		c.report(nil, writer, reader, "Union becomes %s, values are written without a branch index", avro.GetFullName(reader))
+		return
+	}
+	if readerType == avro.Union {
+		c.report(nil, writer, reader, "%s becomes a union, values are written with a branch index", avro.GetFullName(writer))
+		return
+	}
+	if writerType != readerType {
+		c.report(nil, writer, reader, "%s is promoted to %s, values are encoded differently", avro.GetFullName(writer), avro.GetFullName(reader))
+		last := c.found[len(c.found)-1]
+		last.Fixes = []*Fix{newFix(FixReplaceType, last.Path, writer, false, "Keep it %s", avro.GetFullName(writer))}
+		return
+	}
+
+	switch writerType {
+	case avro.Enum:
+		writerSymbols, readerSymbols := writer.(*avro.EnumSchema).Symbols, reader.(*avro.EnumSchema).Symbols
+		for index, symbol := range writerSymbols {
+			if index >= len(readerSymbols) || readerSymbols[index] != symbol {
+				c.report([]string{"symbols"}, writer, reader, "Enum symbol %s moves from index %d, symbols can only be added at the end", symbol, index)
+			}
+		}
+	case avro.Array:
+		c.checkAt(writer.(*avro.ArraySchema).Items, reader.(*avro.ArraySchema).Items, "items")
+	case avro.Map:
+		c.checkAt(writer.(*avro.MapSchema).Values, reader.(*avro.MapSchema).Values, "values")
+	case avro.Record:
+		c.checkRecord(writer.(*avro.RecordSchema), reader.(*avro.RecordSchema))
+	}
+}
+
+// checkRecord requires the reader fields to be the writer fields in the same order, fields are written one after another.
+func (c *stabilityChecker) checkRecord(writer *avro.RecordSchema, reader *avro.RecordSchema) {
+	pair := recordPair{writer, reader}
+	if c.visited[pair] {
+		return
+	}
+	c.visited[pair] = true
+
+	writerFields := make(map[string]*avro.SchemaField)
+	positions := make(map[*avro.SchemaField]int)
+	for position, field := range writer.Fields {
+		writerFields[field.Name] = field
+		positions[field] = position
+	}
+	read := make(map[*avro.SchemaField]bool)
+	last := -1
+	for _, readerField := range reader.Fields {
+		writerField := lookupWriterField(writerFields, readerField)
+		if writerField == nil {
+			c.report([]string{"fields", readerField.Name}, writer, readerField, "Added field %s is written into every value", readerField.Name)
+			continue
+		}
+		read[writerField] = true
+		if positions[writerField] < last {
+			c.report([]string{"fields", readerField.Name}, writerField, readerField,
+				"Field %s moves before field %s", readerField.Name, writer.Fields[last].Name)
+		} else {
+			last = positions[writerField]
+		}
+		c.checkAt(writerField.Type, readerField.Type, "fields", readerField.Name, "type")
+	}
+	for _, writerField := range writer.Fields {
+		if !read[writerField] {
+			c.report([]string{"fields"}, writerField, reader, "Removed field %s is no longer written", writerField.Name)
+		}
+	}
+}
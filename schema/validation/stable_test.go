@@ -0,0 +1,81 @@
+/* Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package validation
+
+import (
+	"testing"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+	"gopkg.in/stretchr/testify.v1/require"
+)
+
+func keyStable(t *testing.T, existing string, toValidate string) Incompatibilities {
+	existingSchema, err := ParseSchema(existing)
+	require.NoError(t, err)
+	schema, err := ParseSchema(toValidate)
+	require.NoError(t, err)
+	err = new(KeyStableCompatibility).Validate(schema, existingSchema)
+	if err == nil {
+		return nil
+	}
+	incompatibilities, ok := err.(Incompatibilities)
+	require.True(t, ok, err.Error())
+	return incompatibilities
+}
+
+func TestKeyStableAccepts(t *testing.T) {
+	assert.Empty(t, keyStable(t, intSchema, intSchema))
+	assert.Empty(t, keyStable(t, enum1ABSchema, enum1ABCSchema))
+	assert.Empty(t, keyStable(t, intStringUnionSchema, `["int", "string", "null"]`))
+	assert.Empty(t, keyStable(t,
+		`{"type": "record", "name": "Key", "fields": [{"name": "id", "type": "long"}, {"name": "tenant", "type": "string"}]}`,
+		`{"type": "record", "name": "Key", "doc": "Renamed", "fields": [{"name": "key_id", "aliases": ["id"], "type": "long"}, {"name": "tenant", "type": "string", "default": ""}]}`))
+	assert.Empty(t, keyStable(t,
+		`{"type": "record", "name": "Node", "fields": [{"name": "next", "type": ["null", "Node"]}]}`,
+		`{"type": "record", "name": "Node", "fields": [{"name": "next", "type": ["null", "Node"]}]}`))
+}
+
+func TestKeyStableRejects(t *testing.T) {
+	incompatibilities := keyStable(t, intSchema, longSchema)
+	require.Len(t, incompatibilities, 1)
+	assert.Equal(t, EncodingChange, incompatibilities[0].Type)
+	assert.Equal(t, "int is promoted to long, values are encoded differently", incompatibilities[0].Message)
+
+	incompatibilities = keyStable(t, enum1ABSchema, `{"type": "enum", "name": "Enum1", "symbols": ["C", "A", "B"]}`)
+	require.Len(t, incompatibilities, 2)
+	assert.Equal(t, "/symbols", incompatibilities[0].Path)
+
+	assert.Len(t, keyStable(t, intStringUnionSchema, stringIntUnionSchema), 2)
+	assert.Len(t, keyStable(t, intSchema, `["int", "null"]`), 1)
+	assert.Len(t, keyStable(t, intUnionSchema, intSchema), 1)
+	assert.Len(t, keyStable(t, intArraySchema, longArraySchema), 1)
+
+	incompatibilities = keyStable(t,
+		`{"type": "record", "name": "Key", "fields": [{"name": "id", "type": "int"}, {"name": "tenant", "type": "string"}, {"name": "region", "type": "string"}]}`,
+		`{"type": "record", "name": "Key", "fields": [{"name": "tenant", "type": "string"}, {"name": "id", "type": "long"}, {"name": "shard", "type": "int", "default": 0}]}`)
+	require.Len(t, incompatibilities, 4)
+	assert.Equal(t, "/fields/id", incompatibilities[0].Path)
+	assert.Equal(t, "Field id moves before field tenant", incompatibilities[0].Message)
+	assert.Equal(t, "/fields/id/type", incompatibilities[1].Path)
+	assert.Equal(t, "/fields/shard", incompatibilities[2].Path)
+	assert.Equal(t, "Removed field region is no longer written", incompatibilities[3].Message)
+}
+
+func TestKeyStableRequiresResolution(t *testing.T) {
+	incompatibilities := keyStable(t, longSchema, intSchema)
+	require.Len(t, incompatibilities, 1)
+	assert.Equal(t, TypeMismatch, incompatibilities[0].Type)
+}
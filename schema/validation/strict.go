@@ -0,0 +1,381 @@
+/* Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+)
+
+var namePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+var primitiveTypes = map[string]bool{
+	"null": true, "boolean": true, "int": true, "long": true, "float": true, "double": true, "bytes": true, "string": true,
+}
+
+// SchemaError is a problem with a schema itself rather than with its compatibility, found at Path,
+// or at Line and Column of an IDL or protocol source.
+type SchemaError struct {
+	Path    string `json:"path,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+func (se *SchemaError) Error() string {
+	if se.Line > 0 {
+		return fmt.Sprintf("line %d, column %d: %s", se.Line, se.Column, se.Message)
+	}
+	return fmt.Sprintf("%s: %s", se.Path, se.Message)
+}
+
+// SchemaErrors lists every problem found in a schema.
+type SchemaErrors []*SchemaError
+
+func (se SchemaErrors) Error() string {
+	messages := make([]string, len(se))
+	for i, err := range se {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// CheckSchema reports everything in a schema the Avro specification doesn't allow although go-avro parses it:
+// invalid names, duplicate field names, enum symbols and named types, unions with duplicate or nested branches,
+// defaults that don't match their type or the first branch of a union, enum defaults that aren't symbols,
+// and invalid logical types. The schema must already parse.
+func CheckSchema(rawSchema string) SchemaErrors {
+	schema, err := ParseSchema(rawSchema)
+	if err != nil {
+		return SchemaErrors{{Path: "/", Message: err.Error()}}
+	}
+	checker := &schemaChecker{named: make(map[string]map[string]interface{})}
+	var raw interface{}
+	if err := json.Unmarshal([]byte(rawSchema), &raw); err == nil {
+		checker.walk(raw, "", "")
+	}
+	return append(checker.errors, CheckLogicalTypes(schema)...)
+}
+
+type schemaChecker struct {
+	named  map[string]map[string]interface{}
+	errors SchemaErrors
+}
+
+func (c *schemaChecker) report(path string, format string, args ...interface{}) {
+	if path == "" {
+		path = "/"
+	}
+	c.errors = append(c.errors, &SchemaError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *schemaChecker) walk(raw interface{}, path string, namespace string) {
+	switch definition := raw.(type) {
+	case []interface{}:
+		c.walkUnion(definition, path, namespace)
+	case map[string]interface{}:
+		switch definition["type"] {
+		case "record", "error":
+			namespace = c.define(definition, path, namespace)
+			c.walkFields(definition, path, namespace)
+		case "enum":
+			c.define(definition, path, namespace)
+			c.checkEnum(definition, path)
+		case "fixed":
+			c.define(definition, path, namespace)
+		case "array":
+			c.walk(definition["items"], path+"/items", namespace)
+		case "map":
+			c.walk(definition["values"], path+"/values", namespace)
+		default:
+			// {"type": {...}} wraps another definition
+			if _, ok := definition["type"].(string); !ok {
+				c.walk(definition["type"], path, namespace)
+			}
+		}
+	}
+}
+
+// define registers a named type and checks its name, returning the namespace the types nested in it inherit.
+func (c *schemaChecker) define(definition map[string]interface{}, path string, namespace string) string {
+	name, _ := definition["name"].(string)
+	if ns, ok := definition["namespace"].(string); ok {
+		namespace = ns
+	}
+	full := fullName(name, namespace)
+	if !validFullName(full) {
+		c.report(path+"/name", "Invalid name %q", full)
+	}
+	if _, ok := c.named[full]; ok {
+		c.report(path+"/name", "Type %s is defined more than once", full)
+	}
+	c.named[full] = definition
+	for _, alias := range stringList(definition[aliasesProperty]) {
+		if !validFullName(alias) {
+			c.report(path+"/aliases", "Invalid alias %q", alias)
+		}
+	}
+	if index := strings.LastIndex(full, "."); index >= 0 {
+		return full[:index]
+	}
+	return ""
+}
+
+func (c *schemaChecker) walkFields(definition map[string]interface{}, path string, namespace string) {
+	fields, _ := definition["fields"].([]interface{})
+	seen := make(map[string]bool)
+	for _, raw := range fields {
+		field, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := field["name"].(string)
+		fieldPath := path + "/fields/" + name
+		if !namePattern.MatchString(name) {
+			c.report(fieldPath, "Invalid field name %q", name)
+		}
+		if seen[name] {
+			c.report(fieldPath, "Field %s is defined more than once", name)
+		}
+		seen[name] = true
+		for _, alias := range stringList(field[aliasesProperty]) {
+			if !namePattern.MatchString(alias) {
+				c.report(fieldPath+"/aliases", "Invalid field alias %q", alias)
+			}
+		}
+
+		c.walk(field["type"], fieldPath+"/type", namespace)
+		if value, ok := field["default"]; ok {
+			if problem := c.checkDefault(value, field["type"], namespace); problem != "" {
+				c.report(fieldPath+"/default", "Default value %s %s", encode(value), problem)
+			}
+		}
+	}
+}
+
+func (c *schemaChecker) checkEnum(definition map[string]interface{}, path string) {
+	symbols := stringList(definition["symbols"])
+	seen := make(map[string]bool)
+	for _, symbol := range symbols {
+		if !namePattern.MatchString(symbol) {
+			c.report(path+"/symbols", "Invalid enum symbol %q", symbol)
+		}
+		if seen[symbol] {
+			c.report(path+"/symbols", "Enum symbol %s is defined more than once", symbol)
+		}
+		seen[symbol] = true
+	}
+	if value, ok := definition["default"]; ok {
+		if symbol, ok := value.(string); !ok || !seen[symbol] {
+			c.report(path+"/default", "Enum default %s is not one of the symbols", encode(value))
+		}
+	}
+}
+
+// walkUnion checks that a union has no nested unions and no two branches of the same type, named types aside.
+func (c *schemaChecker) walkUnion(branches []interface{}, path string, namespace string) {
+	seen := make(map[string]bool)
+	for i, branch := range branches {
+		branchPath := fmt.Sprintf("%s/%d", path, i)
+		if _, ok := branch.([]interface{}); ok {
+			c.report(branchPath, "Unions can't contain other unions")
+			continue
+		}
+		key := c.branchKey(branch, namespace)
+		if seen[key] {
+			c.report(branchPath, "Union has more than one branch of type %s", key)
+		}
+		seen[key] = true
+		c.walk(branch, branchPath, namespace)
+	}
+}
+
+// branchKey is what must be unique among union branches: the name of a named type, or else the type.
+func (c *schemaChecker) branchKey(branch interface{}, namespace string) string {
+	switch definition := branch.(type) {
+	case string:
+		if primitiveTypes[definition] {
+			return definition
+		}
+		return fullName(definition, namespace)
+	case map[string]interface{}:
+		switch definition["type"] {
+		case "record", "error", "enum", "fixed":
+			name, _ := definition["name"].(string)
+			if ns, ok := definition["namespace"].(string); ok {
+				namespace = ns
+			}
+			return fullName(name, namespace)
+		}
+		if inner, ok := definition["type"].(string); ok {
+			return c.branchKey(inner, namespace)
+		}
+		return c.branchKey(definition["type"], namespace)
+	}
+	return fmt.Sprintf("%v", branch)
+}
+
+// checkDefault returns why a default value doesn't match its type, or an empty string if it does.
+// A union default must match the first branch.
+func (c *schemaChecker) checkDefault(value interface{}, schema interface{}, namespace string) string {
+	switch definition := schema.(type) {
+	case string:
+		if named, ok := c.named[fullName(definition, namespace)]; ok {
+			return c.checkDefault(value, named, namespace)
+		}
+		if named, ok := c.named[definition]; ok {
+			return c.checkDefault(value, named, namespace)
+		}
+		if !primitiveTypes[definition] || matchesPrimitive(value, definition) {
+			return ""
+		}
+		return "is not a valid " + definition
+	case []interface{}:
+		if len(definition) == 0 {
+			return "is a default for an empty union"
+		}
+		if problem := c.checkDefault(value, definition[0], namespace); problem != "" {
+			return problem + ", a union default must match the first branch"
+		}
+		return ""
+	case map[string]interface{}:
+		typeName, _ := definition["type"].(string)
+		if ns, ok := definition["namespace"].(string); ok {
+			namespace = ns
+		}
+		switch typeName {
+		case "record", "error":
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				return "is not a record"
+			}
+			name, _ := definition["name"].(string)
+			namespace = namespaceOf(fullName(name, namespace))
+			fields, _ := definition["fields"].([]interface{})
+			for _, raw := range fields {
+				field, _ := raw.(map[string]interface{})
+				fieldName, _ := field["name"].(string)
+				fieldValue, ok := object[fieldName]
+				if !ok {
+					if _, hasDefault := field["default"]; !hasDefault {
+						return fmt.Sprintf("has no value for field %s", fieldName)
+					}
+					continue
+				}
+				if problem := c.checkDefault(fieldValue, field["type"], namespace); problem != "" {
+					return fmt.Sprintf("has a field %s that %s", fieldName, problem)
+				}
+			}
+			return ""
+		case "enum":
+			symbol, ok := value.(string)
+			if !ok || !contains(stringList(definition["symbols"]), symbol) {
+				return "is not a symbol of the enum"
+			}
+			return ""
+		case "fixed":
+			str, ok := value.(string)
+			size, _ := definition["size"].(float64)
+			if !ok || len([]rune(str)) != int(size) {
+				return fmt.Sprintf("is not a string of %d characters for the fixed", int(size))
+			}
+			return ""
+		case "array":
+			items, ok := value.([]interface{})
+			if !ok {
+				return "is not an array"
+			}
+			for _, item := range items {
+				if problem := c.checkDefault(item, definition["items"], namespace); problem != "" {
+					return "has an item that " + problem
+				}
+			}
+			return ""
+		case "map":
+			values, ok := value.(map[string]interface{})
+			if !ok {
+				return "is not a map"
+			}
+			for _, item := range values {
+				if problem := c.checkDefault(item, definition["values"], namespace); problem != "" {
+					return "has a value that " + problem
+				}
+			}
+			return ""
+		}
+		return c.checkDefault(value, definition["type"], namespace)
+	}
+	return ""
+}
+
+func matchesPrimitive(value interface{}, primitive string) bool {
+	switch primitive {
+	case "null":
+		return value == nil
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "int":
+		number, ok := value.(float64)
+		return ok && number == math.Trunc(number) && number >= math.MinInt32 && number <= math.MaxInt32
+	case "long":
+		number, ok := value.(float64)
+		return ok && number == math.Trunc(number) && number >= math.MinInt64 && number <= math.MaxInt64
+	case "float", "double":
+		_, ok := value.(float64)
+		return ok
+	case "bytes", "string":
+		_, ok := value.(string)
+		return ok
+	}
+	return false
+}
+
+func validFullName(name string) bool {
+	for _, part := range strings.Split(name, ".") {
+		if !namePattern.MatchString(part) {
+			return false
+		}
+	}
+	return true
+}
+
+func namespaceOf(full string) string {
+	if index := strings.LastIndex(full, "."); index >= 0 {
+		return full[:index]
+	}
+	return ""
+}
+
+func contains(list []string, str string) bool {
+	for _, item := range list {
+		if item == str {
+			return true
+		}
+	}
+	return false
+}
+
+func encode(value interface{}) string {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(bytes)
+}
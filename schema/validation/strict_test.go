@@ -0,0 +1,107 @@
+/* Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package validation
+
+import (
+	"testing"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+	"gopkg.in/stretchr/testify.v1/require"
+)
+
+func schemaErrorPaths(errs SchemaErrors) []string {
+	paths := make([]string, len(errs))
+	for i, err := range errs {
+		paths[i] = err.Path
+	}
+	return paths
+}
+
+func TestCheckSchemaValid(t *testing.T) {
+	assert.Empty(t, CheckSchema(`"string"`))
+	assert.Empty(t, CheckSchema(`{"type": "record", "name": "Person", "namespace": "com.example", "fields": [
+     {"name": "name", "type": "string", "default": ""},
+     {"name": "nickname", "type": ["null", "string"], "default": null},
+     {"name": "age", "type": "int", "default": 42},
+     {"name": "score", "type": "double", "default": 1.5},
+     {"name": "suit", "type": {"type": "enum", "name": "Suit", "symbols": ["SPADES", "HEARTS"], "default": "SPADES"}, "default": "HEARTS"},
+     {"name": "hash", "type": {"type": "fixed", "name": "Hash", "size": 2}, "default": "ab"},
+     {"name": "tags", "type": {"type": "array", "items": "string"}, "default": ["a", "b"]},
+     {"name": "friend", "type": ["null", "Person"], "default": null},
+     {"name": "address", "type": {"type": "record", "name": "Address", "fields": [
+         {"name": "street", "type": "string"},
+         {"name": "zip", "type": "string", "default": ""}
+     ]}, "default": {"street": "Main"}},
+     {"name": "other", "type": ["Address", {"type": "record", "name": "com.other.Address", "fields": []}], "default": {"street": "x"}}
+ ]}`))
+}
+
+func TestCheckSchemaDefaults(t *testing.T) {
+	errs := CheckSchema(`{"type": "record", "name": "Defaults", "fields": [
+     {"name": "age", "type": "int", "default": "forty"},
+     {"name": "count", "type": "int", "default": 1.5},
+     {"name": "nickname", "type": ["null", "string"], "default": "none"},
+     {"name": "label", "type": ["string", "null"], "default": null},
+     {"name": "suit", "type": {"type": "enum", "name": "Suit", "symbols": ["SPADES"]}, "default": "CLUBS"},
+     {"name": "hash", "type": {"type": "fixed", "name": "Hash", "size": 4}, "default": "ab"},
+     {"name": "address", "type": {"type": "record", "name": "Address", "fields": [{"name": "street", "type": "string"}]}, "default": {}},
+     {"name": "scores", "type": {"type": "map", "values": "long"}, "default": {"a": "b"}}
+ ]}`)
+	require.Len(t, errs, 8)
+	assert.Equal(t, []string{"/fields/age/default", "/fields/count/default", "/fields/nickname/default", "/fields/label/default",
+		"/fields/suit/default", "/fields/hash/default", "/fields/address/default", "/fields/scores/default"}, schemaErrorPaths(errs))
+	assert.Equal(t, `Default value "forty" is not a valid int`, errs[0].Message)
+	assert.Equal(t, `Default value "none" is not a valid null, a union default must match the first branch`, errs[2].Message)
+	assert.Equal(t, `Default value {} has no value for field street`, errs[6].Message)
+}
+
+func TestCheckSchemaNames(t *testing.T) {
+	errs := CheckSchema(`{"type": "record", "name": "Bad-Name", "namespace": "com.example", "fields": [
+     {"name": "id", "type": "string"},
+     {"name": "id", "type": "long"},
+     {"name": "first name", "type": "string"},
+     {"name": "suit", "type": {"type": "enum", "name": "Suit", "symbols": ["A", "A", "b-c"], "default": "D"}},
+     {"name": "again", "type": {"type": "enum", "name": "Suit", "symbols": ["X"]}}
+ ]}`)
+	assert.Equal(t, []string{"/name", "/fields/id", "/fields/first name", "/fields/suit/type/symbols", "/fields/suit/type/symbols",
+		"/fields/suit/type/default", "/fields/again/type/name"}, schemaErrorPaths(errs))
+	assert.Equal(t, `Invalid name "com.example.Bad-Name"`, errs[0].Message)
+	assert.Equal(t, "Field id is defined more than once", errs[1].Message)
+	assert.Equal(t, "Type com.example.Suit is defined more than once", errs[6].Message)
+}
+
+func TestCheckSchemaUnions(t *testing.T) {
+	errs := CheckSchema(`{"type": "record", "name": "Unions", "fields": [
+     {"name": "twice", "type": ["null", "string", {"type": "string"}]},
+     {"name": "named", "type": [{"type": "fixed", "name": "A", "size": 1}, {"type": "fixed", "name": "B", "size": 1}, "A"]}
+ ]}`)
+	assert.Equal(t, []string{"/fields/twice/type/2", "/fields/named/type/2"}, schemaErrorPaths(errs))
+	assert.Equal(t, "Union has more than one branch of type string", errs[0].Message)
+}
+
+func TestCheckSchemaLogicalTypes(t *testing.T) {
+	errs := CheckSchema(`{"type": "record", "name": "Payment", "fields": [
+     {"name": "amount", "type": {"type": "bytes", "logicalType": "decimal", "precision": 4, "scale": 5}}
+ ]}`)
+	require.Len(t, errs, 1)
+	assert.Equal(t, "/fields/amount/type: Decimal scale 5 is greater than precision 4", errs.Error())
+}
+
+func TestValidatorNullDefault(t *testing.T) {
+	writer := `{"type": "record", "name": "A", "fields": [{"name": "a", "type": "int"}]}`
+	reader := `{"type": "record", "name": "A", "fields": [{"name": "a", "type": "int"}, {"name": "b", "type": ["null", "int"], "default": null}]}`
+	assert.Nil(t, canRead(t, writer, reader))
+}
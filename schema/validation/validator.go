@@ -17,103 +17,227 @@ package validation
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+
 	"github.com/elodina/go-avro"
 )
 
 func validate(writer avro.Schema, reader avro.Schema) error {
+	return check(writer, reader).err()
+}
+
+// check returns every incompatibility between the writer and the reader schema.
+func check(writer avro.Schema, reader avro.Schema) Incompatibilities {
+	r := newResolver()
+	r.resolve(writer, reader)
+	return r.found
+}
+
+// recordPair is a writer record and the reader record resolved against it.
+type recordPair struct {
+	writer *avro.RecordSchema
+	reader *avro.RecordSchema
+}
+
+// recordResult is what resolving a pair of records found, with paths relative to the records.
+type recordResult struct {
+	found Incompatibilities
+	steps []*ResolutionStep
+}
+
+// resolver checks if data written with one schema can be read with another and collects every incompatibility
+// along with where it is in the reader schema and fixes to either schema. Records can refer to themselves or to each other,
+// so the incompatibilities of every pair of records are memoized with paths relative to the records. A pair that is
+// being resolved further up the stack is assumed to be compatible, if it isn't that is reported where it was entered.
+// With explain set, the resolver also records how every part of the writer schema is read.
+type resolver struct {
+	inProgress map[recordPair]bool
+	resolved   map[recordPair]*recordResult
+	path       []string
+	writerPath []string
+	found      Incompatibilities
+	explain    bool
+	steps      []*ResolutionStep
+}
+
+func newResolver() *resolver {
+	return &resolver{
+		inProgress: make(map[recordPair]bool),
+		resolved:   make(map[recordPair]*recordResult),
+	}
+}
+
+func (r *resolver) location() string {
+	return "/" + strings.Join(r.path, "/")
+}
+
+func (r *resolver) writerLocation() string {
+	return "/" + strings.Join(r.writerPath, "/")
+}
+
+// suggest adds fixes to the incompatibility reported last.
+func (r *resolver) suggest(fixes ...*Fix) {
+	last := r.found[len(r.found)-1]
+	last.Fixes = append(last.Fixes, fixes...)
+}
+
+// readerFix returns a fix to the reader schema at the given segments below the current location.
+func (r *resolver) readerFix(action string, segments []string, value interface{}, format string, args ...interface{}) *Fix {
+	return newFix(action, joinPath(r.location(), "/"+strings.Join(segments, "/")), value, false, format, args...)
+}
+
+// writerFix returns a fix to the writer schema at the given segments below the current writer location.
+func (r *resolver) writerFix(action string, segments []string, value interface{}, format string, args ...interface{}) *Fix {
+	return newFix(action, joinPath(r.writerLocation(), "/"+strings.Join(segments, "/")), value, true, format, args...)
+}
+
+func (r *resolver) report(kind string, writer interface{}, reader interface{}, format string, args ...interface{}) {
+	r.reportAt(nil, kind, writer, reader, format, args...)
+}
+
+func (r *resolver) reportAt(segments []string, kind string, writer interface{}, reader interface{}, format string, args ...interface{}) {
+	location := r.location()
+	if len(segments) > 0 {
+		location = joinPath(location, "/"+strings.Join(segments, "/"))
+	}
+	r.found = append(r.found, &Incompatibility{
+		Type:    kind,
+		Path:    location,
+		Message: fmt.Sprintf(format, args...),
+		Reader:  fragment(reader),
+		Writer:  fragment(writer),
+	})
+}
+
+// resolveAt resolves the schemas found at the given path segments below the current location of both schemas.
+func (r *resolver) resolveAt(writer avro.Schema, reader avro.Schema, segments ...string) {
+	r.resolveBetween(writer, reader, segments, segments)
+}
+
+// resolveBetween resolves the schemas found at different path segments of the writer and the reader schema.
+func (r *resolver) resolveBetween(writer avro.Schema, reader avro.Schema, writerSegments []string, readerSegments []string) {
+	r.path = append(r.path, readerSegments...)
+	r.writerPath = append(r.writerPath, writerSegments...)
+	r.resolve(writer, reader)
+	r.path = r.path[:len(r.path)-len(readerSegments)]
+	r.writerPath = r.writerPath[:len(r.writerPath)-len(writerSegments)]
+}
+
+func (r *resolver) resolve(writer avro.Schema, reader avro.Schema) {
+	writer = actual(writer)
+	reader = actual(reader)
 	writerType := writer.Type()
 	readerType := reader.Type()
 
+	// every branch of a writer union has to be readable, a reader union reads with the branch that fits the writer
+	if writerType == avro.Union {
+		r.validateWriterUnion(writer, reader)
+		return
+	}
+	if readerType == avro.Union {
+		r.validateReaderUnion(writer, reader)
+		return
+	}
+
+	// a value means something else once its logical type changes, even if the underlying types match
+	writerLogical, readerLogical := logicalType(writer), logicalType(reader)
+	if !writerLogical.readableAs(readerLogical) {
+		if writerLogical != nil && readerLogical != nil && writerLogical.Name == readerLogical.Name {
+			r.report(LogicalTypeMismatch, writer, reader, "Different decimal scale or smaller precision: writer %s, reader %s", writerLogical, readerLogical)
+		} else {
+			r.report(LogicalTypeMismatch, writer, reader, "Different logical types: writer %s, reader %s", writerLogical, readerLogical)
+		}
+		r.suggestReplaceType(writer, reader)
+		return
+	}
+
 	if writerType == readerType {
 		switch writerType {
 		case avro.Null, avro.Boolean, avro.Int, avro.Long, avro.Float, avro.Double, avro.String, avro.Bytes:
-			return nil
 		case avro.Fixed:
-			return validateFixed(writer, reader)
+			r.validateFixed(writer, reader)
 		case avro.Enum:
-			return validateEnum(writer, reader)
+			r.validateEnum(writer, reader)
 		case avro.Array:
-			return validateArray(writer, reader)
+			r.validateArray(writer, reader)
 		case avro.Map:
-			return validateMap(writer, reader)
+			r.validateMap(writer, reader)
 		case avro.Record:
-			return validateRecord(writer, reader)
-		case avro.Union:
-			return validateUnion(writer, reader)
+			r.validateRecord(writer, reader)
 		default:
-			return fmt.Errorf("Unknown schema type: %d", writerType)
-		}
-	} else {
-		if writerType == avro.Union {
-			return validateUnion(writer, reader)
+			r.report(TypeMismatch, writer, reader, "Unknown schema type: %d", writerType)
 		}
+		return
+	}
 
-		switch readerType {
-		case avro.Long:
-			{
-				if writerType == avro.Int {
-					return nil
-				}
-			}
-		case avro.Float:
-			{
-				switch writerType {
-				case avro.Int, avro.Long:
-					return nil
-				}
-			}
-		case avro.Double:
-			{
-				switch writerType {
-				case avro.Int, avro.Long, avro.Float:
-					return nil
-				}
-			}
-		case avro.Bytes:
-			{
-				if writerType == avro.String {
-					return nil
-				}
-			}
-		case avro.String:
-			{
-				if writerType == avro.Bytes {
-					return nil
-				}
-			}
-		case avro.Union:
-			{
-				return validateUnion(reader, writer)
-			}
-		case avro.Null, avro.Boolean, avro.Int, avro.Enum, avro.Array, avro.Map, avro.Record, avro.Fixed:
-		default:
-			return fmt.Errorf("Unknown schema type: %d", readerType)
+	switch readerType {
+	case avro.Null, avro.Boolean, avro.Int, avro.Long, avro.Float, avro.Double, avro.String, avro.Bytes,
+		avro.Enum, avro.Array, avro.Map, avro.Record, avro.Fixed:
+		if promotable(writerType, readerType) {
+			r.step(StepPromote, nil, nil, nil, "Writer %s is promoted to %s", avro.GetFullName(writer), avro.GetFullName(reader))
+			return
 		}
+	default:
+		r.report(TypeMismatch, writer, reader, "Unknown schema type: %d", readerType)
+		return
 	}
 
-	return fmt.Errorf("Found %s, expecting %s", avro.GetFullName(writer), avro.GetFullName(reader))
+	r.report(TypeMismatch, writer, reader, "Found %s, expecting %s", avro.GetFullName(writer), avro.GetFullName(reader))
+	r.suggestReplaceType(writer, reader)
+}
+
+func (r *resolver) suggestReplaceType(writer avro.Schema, reader avro.Schema) {
+	r.suggest(r.readerFix(FixReplaceType, nil, writer, "Read it as %s, like the writer", avro.GetFullName(writer)),
+		r.writerFix(FixReplaceType, nil, reader, "Write it as %s, like the reader", avro.GetFullName(reader)))
+}
+
+// promotable tells if a value written as one primitive type can be read as another.
+func promotable(writerType int, readerType int) bool {
+	switch readerType {
+	case avro.Long:
+		return writerType == avro.Int
+	case avro.Float:
+		return writerType == avro.Int || writerType == avro.Long
+	case avro.Double:
+		return writerType == avro.Int || writerType == avro.Long || writerType == avro.Float
+	case avro.Bytes:
+		return writerType == avro.String
+	case avro.String:
+		return writerType == avro.Bytes
+	}
+	return false
 }
 
-func validateFixed(writer avro.Schema, reader avro.Schema) error {
+func (r *resolver) validateFixed(writer avro.Schema, reader avro.Schema) {
 	fixedWriter := writer.(*avro.FixedSchema)
 	fixedReader := reader.(*avro.FixedSchema)
-	if avro.GetFullName(writer) != avro.GetFullName(reader) {
-		return fmt.Errorf("Different Fixed type names: writer %s, reader %s", writer.GetName(), reader.GetName())
+	if !namesMatch(writer, reader) {
+		r.reportAt([]string{"name"}, NameMismatch, writer, reader,
+			"Different Fixed type names: writer %s, reader %s", writer.GetName(), reader.GetName())
+		r.suggestAlias(writer, reader)
+	} else {
+		r.explainNames(writer, reader)
 	}
 
 	if fixedWriter.Size != fixedReader.Size {
-		return fmt.Errorf("Different Fixed type sizes: writer %d, reader %d", fixedWriter.Size, fixedReader.Size)
+		r.reportAt([]string{"size"}, FixedSizeMismatch, writer, reader,
+			"Different Fixed type sizes: writer %d, reader %d", fixedWriter.Size, fixedReader.Size)
+		r.suggest(r.readerFix(FixSetSize, nil, fixedWriter.Size, "Set the size to %d, like the writer", fixedWriter.Size),
+			r.writerFix(FixSetSize, nil, fixedReader.Size, "Set the size to %d, like the reader", fixedReader.Size))
 	}
-
-	return nil
 }
 
-func validateEnum(writer avro.Schema, reader avro.Schema) error {
+func (r *resolver) validateEnum(writer avro.Schema, reader avro.Schema) {
 	enumWriter := writer.(*avro.EnumSchema)
 	enumReader := reader.(*avro.EnumSchema)
 
-	if avro.GetFullName(writer) != avro.GetFullName(reader) {
-		return fmt.Errorf("Different Enum type names: writer %s, reader %s", writer.GetName(), reader.GetName())
+	if !namesMatch(writer, reader) {
+		r.reportAt([]string{"name"}, NameMismatch, writer, reader,
+			"Different Enum type names: writer %s, reader %s", writer.GetName(), reader.GetName())
+		r.suggestAlias(writer, reader)
+	} else {
+		r.explainNames(writer, reader)
 	}
 
 	readerSymbolsMap := make(map[string]struct{})
@@ -123,33 +247,74 @@ func validateEnum(writer avro.Schema, reader avro.Schema) error {
 
 	for _, symbol := range enumWriter.Symbols {
 		if _, ok := readerSymbolsMap[symbol]; !ok {
-			return fmt.Errorf("Enum symbol %s does not exist for reader schema", symbol)
+			r.reportAt([]string{"symbols"}, MissingEnumSymbols, writer, reader, "Enum symbol %s does not exist for reader schema", symbol)
+			r.suggest(r.readerFix(FixAddEnumSymbol, nil, symbol, "Add symbol %s back", symbol),
+				r.writerFix(FixRemoveEnumSymbol, nil, symbol, "Remove symbol %s the reader doesn't know", symbol))
 		}
 	}
-
-	return nil
 }
 
-func validateArray(writer avro.Schema, reader avro.Schema) error {
+func (r *resolver) validateArray(writer avro.Schema, reader avro.Schema) {
 	arrayWriter := writer.(*avro.ArraySchema)
 	arrayReader := reader.(*avro.ArraySchema)
 
-	return validate(arrayWriter.Items, arrayReader.Items)
+	r.resolveAt(arrayWriter.Items, arrayReader.Items, "items")
 }
 
-func validateMap(writer avro.Schema, reader avro.Schema) error {
+func (r *resolver) validateMap(writer avro.Schema, reader avro.Schema) {
 	mapWriter := writer.(*avro.MapSchema)
 	mapReader := reader.(*avro.MapSchema)
 
-	return validate(mapWriter.Values, mapReader.Values)
+	r.resolveAt(mapWriter.Values, mapReader.Values, "values")
 }
 
-func validateRecord(writer avro.Schema, reader avro.Schema) error {
-	recordWriter := writer.(*avro.RecordSchema)
-	recordReader := reader.(*avro.RecordSchema)
+func (r *resolver) validateRecord(writer avro.Schema, reader avro.Schema) {
+	pair := recordPair{writer.(*avro.RecordSchema), reader.(*avro.RecordSchema)}
+	result, ok := r.resolved[pair]
+	if !ok {
+		if r.inProgress[pair] {
+			return
+		}
+		r.inProgress[pair] = true
+		path, writerPath, outer, outerSteps := r.path, r.writerPath, r.found, r.steps
+		r.path, r.writerPath, r.found, r.steps = nil, nil, nil, nil
+		r.validateFields(pair.writer, pair.reader)
+		result = &recordResult{found: r.found, steps: r.steps}
+		r.path, r.writerPath, r.found, r.steps = path, writerPath, outer, outerSteps
+		delete(r.inProgress, pair)
+		r.resolved[pair] = result
+	}
+
+	location, writerLocation := r.location(), r.writerLocation()
+	for _, step := range result.steps {
+		relocated := *step
+		relocated.Path = joinPath(location, step.Path)
+		relocated.WriterPath = joinPath(writerLocation, step.WriterPath)
+		r.steps = append(r.steps, &relocated)
+	}
+	for _, incompatibility := range result.found {
+		relocated := *incompatibility
+		relocated.Path = joinPath(location, incompatibility.Path)
+		relocated.Fixes = make([]*Fix, len(incompatibility.Fixes))
+		for i, fix := range incompatibility.Fixes {
+			relocatedFix := *fix
+			if fix.writer {
+				relocatedFix.Path = joinPath(writerLocation, fix.Path)
+			} else {
+				relocatedFix.Path = joinPath(location, fix.Path)
+			}
+			relocated.Fixes[i] = &relocatedFix
+		}
+		r.found = append(r.found, &relocated)
+	}
+}
 
-	if avro.GetFullName(writer) != avro.GetFullName(reader) {
-		return fmt.Errorf("Different Record type names: writer %s, reader %s", writer.GetName(), reader.GetName())
+func (r *resolver) validateFields(recordWriter *avro.RecordSchema, recordReader *avro.RecordSchema) {
+	if !namesMatch(recordWriter, recordReader) {
+		r.reportAt([]string{"name"}, NameMismatch, recordWriter, recordReader,
+			"Different Record type names: writer %s, reader %s", recordWriter.GetName(), recordReader.GetName())
+		r.suggestAlias(recordWriter, recordReader)
+		return
 	}
 
 	writerFields := recordWriter.Fields
@@ -160,37 +325,209 @@ func validateRecord(writer avro.Schema, reader avro.Schema) error {
 		writerFieldsMap[field.Name] = field
 	}
 
-	readerFieldsMap := make(map[string]*avro.SchemaField)
-	for _, field := range readerFields {
-		readerFieldsMap[field.Name] = field
+	r.explainNames(recordWriter, recordReader)
+	read := make(map[*avro.SchemaField]bool)
+	for _, readerField := range readerFields {
+		if writerField := lookupWriterField(writerFieldsMap, readerField); writerField != nil {
+			read[writerField] = true
+		}
+	}
+	for _, writerField := range writerFields {
+		if !read[writerField] {
+			r.step(StepSkipField, nil, []string{"fields", writerField.Name}, nil, "Writer field %s is skipped", writerField.Name)
+		}
 	}
-
 	for _, readerField := range readerFields {
-		if _, ok := writerFieldsMap[readerField.Name]; !ok && readerField.Default == nil {
-			return fmt.Errorf("Introduced field %s does not have default value which is required.", readerField.Name)
+		writerField := lookupWriterField(writerFieldsMap, readerField)
+		if writerField == nil {
+			if !hasDefault(readerField) {
+				r.reportAt([]string{"fields", readerField.Name}, ReaderFieldMissingDefaultValue, recordWriter, readerField,
+					"Introduced field %s does not have default value which is required.", readerField.Name)
+				r.suggestDefault(writerFields, read, readerField)
+			} else {
+				value := fieldDefault(readerField)
+				r.step(StepUseDefault, []string{"fields", readerField.Name}, nil, value,
+					"Field %s is not written, it takes its default %s", readerField.Name, fragment(value))
+			}
+			continue
 		}
+		if writerField.Name == readerField.Name {
+			r.step(StepReadField, []string{"fields", readerField.Name}, []string{"fields", writerField.Name}, nil, "Field %s is read", readerField.Name)
+		} else {
+			r.step(StepReadField, []string{"fields", readerField.Name}, []string{"fields", writerField.Name}, nil,
+				"Field %s is read from writer field %s, an alias", readerField.Name, writerField.Name)
+		}
+		r.resolveBetween(writerField.Type, readerField.Type, []string{"fields", writerField.Name, "type"}, []string{"fields", readerField.Name, "type"})
 	}
+}
 
+// suggestDefault suggests fixes for a reader field the writer doesn't have: an alias if a writer field
+// of the same type isn't read and may be its old name, a default, or writing the field.
+// A writer field is suggested as the old name of the first such reader field only.
+func (r *resolver) suggestDefault(writerFields []*avro.SchemaField, read map[*avro.SchemaField]bool, readerField *avro.SchemaField) {
+	segments := []string{"fields", readerField.Name}
 	for _, writerField := range writerFields {
-		if readerField, ok := readerFieldsMap[writerField.Name]; ok {
-			err := validate(writerField.Type, readerField.Type)
-			if err != nil {
-				return err
-			}
+		if !read[writerField] && sameType(actual(writerField.Type), actual(readerField.Type)) {
+			r.suggest(r.readerFix(FixAddAlias, segments, writerField.Name, "Add alias %s if field %s was renamed", writerField.Name, readerField.Name))
+			read[writerField] = true
+			break
 		}
 	}
+	fieldType := actual(readerField.Type)
+	if union, ok := fieldType.(*avro.UnionSchema); !ok || len(union.Types) == 0 || union.Types[0].Type() != avro.Null {
+		r.suggest(r.readerFix(FixMakeNullable, segments, nil, "Make field %s a union of null and its type with default null", readerField.Name))
+	}
+	if value, ok := zeroValue(fieldType); ok {
+		r.suggest(r.readerFix(FixAddDefault, segments, value, "Add default %s to field %s", fragment(value), readerField.Name))
+	}
+	r.suggest(r.writerFix(FixAddField, []string{"fields"}, readerField, "Write field %s the reader needs", readerField.Name))
+}
+
+// suggestAlias suggests naming the reader type after the writer type, or the writer type after the reader one.
+func (r *resolver) suggestAlias(writer avro.Schema, reader avro.Schema) {
+	writerName, readerName := avro.GetFullName(writer), avro.GetFullName(reader)
+	r.suggest(r.readerFix(FixAddAlias, nil, writerName, "Add alias %s if %s was renamed", writerName, readerName),
+		r.writerFix(FixRename, nil, readerName, "Rename %s to %s, like the reader", writerName, readerName))
+}
+
+// hasDefault tells if a field has a default value. go-avro leaves a null default nil, but keeps it in the field properties.
+func hasDefault(field *avro.SchemaField) bool {
+	_, ok := field.Prop("default")
+	return ok || field.Default != nil
+}
 
+// fieldDefault returns the default value of a field as parsed from JSON.
+func fieldDefault(field *avro.SchemaField) interface{} {
+	if field.Default != nil {
+		return field.Default
+	}
+	value, _ := field.Prop("default")
+	return value
+}
+
+// actual returns the record a reference to an already defined record stands for.
+func actual(schema avro.Schema) avro.Schema {
+	if recursive, ok := schema.(*avro.RecursiveSchema); ok {
+		return recursive.Actual
+	}
+	return schema
+}
+
+func lookupWriterField(writerFields map[string]*avro.SchemaField, readerField *avro.SchemaField) *avro.SchemaField {
+	if field, ok := writerFields[readerField.Name]; ok {
+		return field
+	}
+	for _, alias := range fieldAliases(readerField) {
+		if field, ok := writerFields[alias]; ok {
+			return field
+		}
+	}
 	return nil
 }
 
-func validateUnion(writer avro.Schema, reader avro.Schema) error {
+// namesMatch tells if a reader named type can read the writer one: the full names are the same,
+// or the writer full name is one of the reader aliases.
+func namesMatch(writer avro.Schema, reader avro.Schema) bool {
+	writerName := avro.GetFullName(writer)
+	if writerName == avro.GetFullName(reader) {
+		return true
+	}
+	for _, alias := range aliases(reader) {
+		if alias == writerName {
+			return true
+		}
+	}
+	return false
+}
+
+// validateWriterUnion resolves every writer branch at the current location, the writer fragment tells the branches apart.
+func (r *resolver) validateWriterUnion(writer avro.Schema, reader avro.Schema) {
 	unionWriter := writer.(*avro.UnionSchema)
 
-	for _, writerSchema := range unionWriter.Types {
-		if validate(writerSchema, reader) == nil {
-			return nil
+	for index, writerSchema := range unionWriter.Types {
+		if reader.Type() != avro.Union {
+			r.step(StepSelectBranch, nil, []string{strconv.Itoa(index)}, nil,
+				"Writer union branch %d %s is read as %s", index, avro.GetFullName(writerSchema), avro.GetFullName(reader))
+		}
+		r.resolveBetween(writerSchema, reader, []string{strconv.Itoa(index)}, nil)
+	}
+}
+
+func (r *resolver) validateReaderUnion(writer avro.Schema, reader avro.Schema) {
+	unionReader := reader.(*avro.UnionSchema)
+	index := readerBranch(writer, unionReader)
+	if index < 0 {
+		r.report(MissingUnionBranch, writer, reader, "Writer schema %s cannot be read by any branch of reader union", avro.GetFullName(writer))
+		r.suggestBranch(writer, unionReader)
+		return
+	}
+
+	r.step(StepSelectBranch, []string{strconv.Itoa(index)}, nil, nil,
+		"Writer %s is read as reader union branch %d %s", avro.GetFullName(writer), index, avro.GetFullName(unionReader.Types[index]))
+	r.resolveBetween(writer, unionReader.Types[index], nil, []string{strconv.Itoa(index)})
+}
+
+// suggestBranch suggests fixes for a writer schema no branch of a reader union reads: if a branch is a named type
+// of the same kind, naming one after the other, else adding the branch to the reader or removing it from the writer.
+func (r *resolver) suggestBranch(writer avro.Schema, reader *avro.UnionSchema) {
+	for index, branch := range reader.Types {
+		branch = actual(branch)
+		if branch.Type() != writer.Type() {
+			continue
+		}
+		if named := writer.Type() == avro.Record || writer.Type() == avro.Enum || writer.Type() == avro.Fixed; !named {
+			continue
+		}
+		writerName, branchName := avro.GetFullName(writer), avro.GetFullName(branch)
+		r.suggest(r.readerFix(FixAddAlias, []string{strconv.Itoa(index)}, writerName, "Add alias %s if %s was renamed", writerName, branchName),
+			r.writerFix(FixRename, nil, branchName, "Rename %s to %s, like the reader", writerName, branchName))
+		break
+	}
+	r.suggest(r.readerFix(FixAddUnionBranch, nil, writer, "Add branch %s to the union", avro.GetFullName(writer)))
+	if len(r.writerPath) > 0 {
+		if _, err := strconv.Atoi(r.writerPath[len(r.writerPath)-1]); err == nil {
+			r.suggest(r.writerFix(FixRemoveUnionBranch, nil, nil, "Remove branch %s the reader can't read", avro.GetFullName(writer)))
 		}
 	}
+}
 
-	return fmt.Errorf("Writer schema %s cannot be read by %s", writer.String(), reader.String())
+// readerBranch returns the index of the reader union branch a non-union writer schema is read with:
+// the first branch of the same type, with the same name for named types and preferably the same logical type,
+// or else the first one it can be promoted to. It returns -1 if there is no such branch.
+func readerBranch(writer avro.Schema, reader *avro.UnionSchema) int {
+	first := -1
+	for index, branch := range reader.Types {
+		if !sameType(writer, actual(branch)) {
+			continue
+		}
+		if logicalType(writer).readableAs(logicalType(actual(branch))) {
+			return index
+		}
+		if first < 0 {
+			first = index
+		}
+	}
+	if first >= 0 {
+		return first
+	}
+	for index, branch := range reader.Types {
+		if promotable(writer.Type(), actual(branch).Type()) {
+			return index
+		}
+	}
+	return -1
+}
+
+// sameType tells if a reader union branch has the type of the writer schema, and for named types its name and size.
+func sameType(writer avro.Schema, branch avro.Schema) bool {
+	if branch.Type() != writer.Type() {
+		return false
+	}
+	switch branch.Type() {
+	case avro.Record, avro.Enum:
+		return namesMatch(writer, branch)
+	case avro.Fixed:
+		return namesMatch(writer, branch) && writer.(*avro.FixedSchema).Size == branch.(*avro.FixedSchema).Size
+	}
+	return true
 }
@@ -0,0 +1,144 @@
+/* Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package validation
+
+import (
+	"regexp"
+	"testing"
+
+	avro "github.com/elodina/go-avro"
+	"gopkg.in/stretchr/testify.v1/assert"
+	"gopkg.in/stretchr/testify.v1/require"
+)
+
+func canRead(t *testing.T, writer string, reader string) error {
+	writerSchema, err := ParseSchema(writer)
+	require.Equal(t, nil, err)
+	readerSchema, err := ParseSchema(reader)
+	require.Equal(t, nil, err)
+
+	validator, err := NewBuilder().CanReadStrategy().ValidateLatest()
+	require.Equal(t, nil, err)
+	return validator.Validate(readerSchema, []avro.Schema{writerSchema})
+}
+
+func TestParseSchemaAliases(t *testing.T) {
+	schema, err := ParseSchema(`{"type": "record", "name": "Outer", "namespace": "a", "aliases": ["Old", "b.Older"],
+ "fields": [
+     {"name": "inner", "aliases": ["nested"], "type": {"type": "record", "name": "Inner", "aliases": ["OldInner"], "fields": []}},
+     {"name": "hash", "type": {"type": "fixed", "name": "Hash", "namespace": "c", "size": 4, "aliases": ["Digest"]}},
+     {"name": "suit", "type": ["null", {"type": "enum", "name": "Suit", "aliases": ["Card"], "symbols": ["A"]}]}
+ ]}`)
+	require.Equal(t, nil, err)
+
+	outer := schema.(*avro.RecordSchema)
+	assert.Equal(t, []string{"a.Old", "b.Older"}, aliases(outer))
+	assert.Equal(t, []string{"nested"}, fieldAliases(outer.Fields[0]))
+
+	inner := outer.Fields[0].Type
+	assert.Equal(t, "a.Inner", avro.GetFullName(inner))
+	assert.Equal(t, []string{"a.OldInner"}, aliases(inner))
+
+	hash := outer.Fields[1].Type
+	assert.Equal(t, "c.Hash", avro.GetFullName(hash))
+	assert.Equal(t, []string{"c.Digest"}, aliases(hash))
+
+	suit := outer.Fields[2].Type.(*avro.UnionSchema).Types[1]
+	assert.Equal(t, "a.Suit", avro.GetFullName(suit))
+	assert.Equal(t, []string{"a.Card"}, aliases(suit))
+}
+
+func TestValidatorRenamedField(t *testing.T) {
+	writer := `{"type": "record", "name": "User", "fields": [{"name": "name", "type": "string"}]}`
+
+	err := canRead(t, writer, `{"type": "record", "name": "User", "fields": [{"name": "fullName", "aliases": ["name"], "type": "string"}]}`)
+	assert.Equal(t, nil, err)
+
+	// without the alias the renamed field is a new field without a default
+	err = canRead(t, writer, `{"type": "record", "name": "User", "fields": [{"name": "fullName", "type": "string"}]}`)
+	assert.Regexp(t, regexp.MustCompile(".*does not have default value.*"), err)
+
+	// the aliased field still has to be able to read the writer type
+	err = canRead(t, writer, `{"type": "record", "name": "User", "fields": [{"name": "fullName", "aliases": ["name"], "type": "int"}]}`)
+	assert.Regexp(t, regexp.MustCompile(".*Found string, expecting int.*"), err)
+
+	// a field with the writer name wins over an alias
+	err = canRead(t, `{"type": "record", "name": "User", "fields": [{"name": "name", "type": "string"}, {"name": "id", "type": "int"}]}`,
+		`{"type": "record", "name": "User", "fields": [{"name": "id", "aliases": ["name"], "type": "int"}]}`)
+	assert.Equal(t, nil, err)
+}
+
+func TestValidatorRenamedNestedRecord(t *testing.T) {
+	writer := `{"type": "record", "name": "Order", "namespace": "shop", "fields": [
+     {"name": "customer", "type": {"type": "record", "name": "Client", "fields": [{"name": "id", "type": "long"}]}}
+ ]}`
+
+	err := canRead(t, writer, `{"type": "record", "name": "Order", "namespace": "shop", "fields": [
+     {"name": "buyer", "aliases": ["customer"], "type": {"type": "record", "name": "Customer", "aliases": ["Client"],
+         "fields": [{"name": "customerId", "aliases": ["id"], "type": "long"}]}}
+ ]}`)
+	assert.Equal(t, nil, err)
+
+	err = canRead(t, writer, `{"type": "record", "name": "Order", "namespace": "shop", "fields": [
+     {"name": "customer", "type": {"type": "record", "name": "Customer", "fields": [{"name": "id", "type": "long"}]}}
+ ]}`)
+	assert.Regexp(t, regexp.MustCompile(".*Different Record type names.*"), err)
+
+	// aliases are for reading old data, so the writer aliases don't matter
+	err = canRead(t, `{"type": "record", "name": "Customer", "aliases": ["Client"], "fields": []}`,
+		`{"type": "record", "name": "Client", "fields": []}`)
+	assert.Regexp(t, regexp.MustCompile(".*Different Record type names.*"), err)
+}
+
+func TestValidatorRenamedUnionBranch(t *testing.T) {
+	writer := `["null", {"type": "enum", "name": "Color", "symbols": ["RED", "GREEN"]}, {"type": "fixed", "name": "Id", "size": 8}]`
+
+	err := canRead(t, writer, `["null", {"type": "enum", "name": "Colour", "aliases": ["Color"], "symbols": ["RED", "GREEN", "BLUE"]},
+ {"type": "fixed", "name": "Key", "aliases": ["Id"], "size": 8}]`)
+	assert.Equal(t, nil, err)
+
+	err = canRead(t, `{"type": "fixed", "name": "Id", "size": 8}`, `{"type": "fixed", "name": "Key", "size": 8}`)
+	assert.Regexp(t, regexp.MustCompile(".*Different Fixed type names.*"), err)
+}
+
+func TestValidatorAliasNamespaces(t *testing.T) {
+	writer := `{"type": "record", "name": "Event", "namespace": "old.ns", "fields": [
+     {"name": "kind", "type": {"type": "enum", "name": "Kind", "symbols": ["A"]}}
+ ]}`
+
+	// aliases with a namespace are full names
+	err := canRead(t, writer, `{"type": "record", "name": "Event", "namespace": "new.ns", "aliases": ["old.ns.Event"], "fields": [
+     {"name": "kind", "type": {"type": "enum", "name": "Kind", "aliases": ["old.ns.Kind"], "symbols": ["A"]}}
+ ]}`)
+	assert.Equal(t, nil, err)
+
+	// aliases without one are in the namespace of the type
+	err = canRead(t, writer, `{"type": "record", "name": "Event", "namespace": "new.ns", "aliases": ["Event"], "fields": [
+     {"name": "kind", "type": {"type": "enum", "name": "Kind", "symbols": ["A"]}}
+ ]}`)
+	assert.Regexp(t, regexp.MustCompile(".*Different Record type names.*"), err)
+
+	// nested types inherit the namespace of the enclosing record
+	err = canRead(t, writer, `{"type": "record", "name": "old.ns.Event", "fields": [
+     {"name": "kind", "type": {"type": "enum", "name": "Kind", "symbols": ["A"]}}
+ ]}`)
+	assert.Equal(t, nil, err)
+
+	err = canRead(t, writer, `{"type": "record", "name": "Event", "namespace": "old.ns", "fields": [
+     {"name": "kind", "type": {"type": "enum", "name": "Kind", "namespace": "other", "symbols": ["A"]}}
+ ]}`)
+	assert.Regexp(t, regexp.MustCompile(".*Different Enum type names.*"), err)
+}
@@ -0,0 +1,259 @@
+/* Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package validation
+
+import "testing"
+
+// The schemas and reader/writer pairs below are ported from TestSchemaCompatibility in Apache Avro Java.
+
+const (
+	nullSchema    = `"null"`
+	booleanSchema = `"boolean"`
+	intSchema     = `"int"`
+	longSchema    = `"long"`
+	floatSchema   = `"float"`
+	doubleSchema  = `"double"`
+	stringSchema  = `"string"`
+	bytesSchema   = `"bytes"`
+
+	intArraySchema  = `{"type": "array", "items": "int"}`
+	longArraySchema = `{"type": "array", "items": "long"}`
+	intMapSchema    = `{"type": "map", "values": "int"}`
+	longMapSchema   = `{"type": "map", "values": "long"}`
+
+	enum1ABSchema  = `{"type": "enum", "name": "Enum1", "symbols": ["A", "B"]}`
+	enum1ABCSchema = `{"type": "enum", "name": "Enum1", "symbols": ["A", "B", "C"]}`
+	enum1BCSchema  = `{"type": "enum", "name": "Enum1", "symbols": ["B", "C"]}`
+	enum2ABSchema  = `{"type": "enum", "name": "Enum2", "symbols": ["A", "B"]}`
+
+	emptyUnionSchema              = `[]`
+	nullUnionSchema               = `["null"]`
+	intUnionSchema                = `["int"]`
+	longUnionSchema               = `["long"]`
+	floatUnionSchema              = `["float"]`
+	doubleUnionSchema             = `["double"]`
+	stringUnionSchema             = `["string"]`
+	bytesUnionSchema              = `["bytes"]`
+	intStringUnionSchema          = `["int", "string"]`
+	stringIntUnionSchema          = `["string", "int"]`
+	intFloatUnionSchema           = `["int", "float"]`
+	intLongUnionSchema            = `["int", "long"]`
+	intLongFloatDoubleUnionSchema = `["int", "long", "float", "double"]`
+	nullIntArrayUnionSchema       = `["null", {"type": "array", "items": "int"}]`
+	nullIntMapUnionSchema         = `["null", {"type": "map", "values": "int"}]`
+
+	fixed4BytesSchema = `{"type": "fixed", "name": "Fixed", "size": 4}`
+
+	emptyRecord1Schema      = `{"type": "record", "name": "Record1", "fields": []}`
+	emptyRecord2Schema      = `{"type": "record", "name": "Record2", "fields": []}`
+	aIntRecord1Schema       = `{"type": "record", "name": "Record1", "fields": [{"name": "a", "type": "int"}]}`
+	aLongRecord1Schema      = `{"type": "record", "name": "Record1", "fields": [{"name": "a", "type": "long"}]}`
+	aDIntRecord1Schema      = `{"type": "record", "name": "Record1", "fields": [{"name": "a", "type": "int", "default": 0}]}`
+	aIntBIntRecord1Schema   = `{"type": "record", "name": "Record1", "fields": [{"name": "a", "type": "int"}, {"name": "b", "type": "int"}]}`
+	aIntBDIntRecord1Schema  = `{"type": "record", "name": "Record1", "fields": [{"name": "a", "type": "int"}, {"name": "b", "type": "int", "default": 0}]}`
+	aDIntBDIntRecord1Schema = `{"type": "record", "name": "Record1", "fields": [{"name": "a", "type": "int", "default": 0}, {"name": "b", "type": "int", "default": 0}]}`
+	intListRecordSchema     = `{"type": "record", "name": "List", "fields": [
+     {"name": "head", "type": "int", "default": 0},
+     {"name": "tail", "type": ["null", "List"], "default": null}]}`
+	longListRecordSchema = `{"type": "record", "name": "List", "fields": [
+     {"name": "head", "type": "long", "default": 0},
+     {"name": "tail", "type": ["null", "List"], "default": null}]}`
+)
+
+type readerWriter struct {
+	reader string
+	writer string
+}
+
+var compatibleReaderWriters = []readerWriter{
+	{booleanSchema, booleanSchema},
+
+	{intSchema, intSchema},
+
+	{longSchema, intSchema},
+	{longSchema, longSchema},
+
+	{floatSchema, intSchema},
+	{floatSchema, longSchema},
+	{floatSchema, floatSchema},
+
+	{doubleSchema, intSchema},
+	{doubleSchema, longSchema},
+	{doubleSchema, floatSchema},
+	{doubleSchema, doubleSchema},
+
+	{stringSchema, stringSchema},
+	{stringSchema, bytesSchema},
+	{bytesSchema, bytesSchema},
+	{bytesSchema, stringSchema},
+
+	{intArraySchema, intArraySchema},
+	{longArraySchema, intArraySchema},
+	{intMapSchema, intMapSchema},
+	{longMapSchema, intMapSchema},
+
+	{enum1ABSchema, enum1ABSchema},
+	{enum1ABCSchema, enum1ABSchema},
+
+	// unions
+	{emptyUnionSchema, emptyUnionSchema},
+	{floatUnionSchema, emptyUnionSchema},
+	{floatUnionSchema, intUnionSchema},
+	{floatUnionSchema, longUnionSchema},
+	{floatUnionSchema, intLongUnionSchema},
+	{intUnionSchema, intUnionSchema},
+	{intStringUnionSchema, stringIntUnionSchema},
+	{intUnionSchema, emptyUnionSchema},
+	{longUnionSchema, emptyUnionSchema},
+	{longUnionSchema, intUnionSchema},
+	{doubleUnionSchema, intUnionSchema},
+	{doubleUnionSchema, longUnionSchema},
+	{doubleUnionSchema, intLongUnionSchema},
+	{doubleUnionSchema, floatUnionSchema},
+	{stringUnionSchema, emptyUnionSchema},
+	{stringUnionSchema, bytesUnionSchema},
+	{bytesUnionSchema, emptyUnionSchema},
+	{bytesUnionSchema, stringUnionSchema},
+	{doubleUnionSchema, intFloatUnionSchema},
+	{nullUnionSchema, nullUnionSchema},
+	{nullIntArrayUnionSchema, nullIntArrayUnionSchema},
+	{nullIntMapUnionSchema, nullIntMapUnionSchema},
+
+	// a reader union reads a non-union writer with the fitting branch, promotions included
+	{floatUnionSchema, intSchema},
+	{intStringUnionSchema, stringSchema},
+	{intLongFloatDoubleUnionSchema, floatSchema},
+	{nullIntArrayUnionSchema, intArraySchema},
+
+	// singleton unions
+	{floatUnionSchema, floatSchema},
+	{intUnionSchema, intSchema},
+	{intSchema, intUnionSchema},
+
+	// a writer union can be read by a non-union reader if every branch can
+	{longSchema, intLongUnionSchema},
+	{doubleSchema, intLongFloatDoubleUnionSchema},
+	{intSchema, emptyUnionSchema},
+
+	{fixed4BytesSchema, fixed4BytesSchema},
+
+	// records
+	{emptyRecord1Schema, emptyRecord1Schema},
+	{emptyRecord1Schema, aIntRecord1Schema},
+
+	{aIntRecord1Schema, aIntRecord1Schema},
+	{aDIntRecord1Schema, aIntRecord1Schema},
+	{aDIntRecord1Schema, aDIntRecord1Schema},
+	{aIntRecord1Schema, aDIntRecord1Schema},
+
+	{aLongRecord1Schema, aIntRecord1Schema},
+
+	{aIntRecord1Schema, aIntBIntRecord1Schema},
+	{aDIntRecord1Schema, aIntBIntRecord1Schema},
+
+	{aIntBDIntRecord1Schema, aIntRecord1Schema},
+	{aDIntBDIntRecord1Schema, emptyRecord1Schema},
+	{aDIntBDIntRecord1Schema, aIntRecord1Schema},
+	{aIntBIntRecord1Schema, aDIntBDIntRecord1Schema},
+
+	// recursive records
+	{intListRecordSchema, intListRecordSchema},
+	{longListRecordSchema, longListRecordSchema},
+	{longListRecordSchema, intListRecordSchema},
+
+	{nullSchema, nullSchema},
+}
+
+var incompatibleReaderWriters = []readerWriter{
+	{nullSchema, intSchema},
+	{nullSchema, longSchema},
+
+	{booleanSchema, intSchema},
+
+	{intSchema, nullSchema},
+	{intSchema, booleanSchema},
+	{intSchema, longSchema},
+	{intSchema, floatSchema},
+	{intSchema, doubleSchema},
+
+	{longSchema, floatSchema},
+	{longSchema, doubleSchema},
+
+	{floatSchema, doubleSchema},
+
+	{stringSchema, booleanSchema},
+	{stringSchema, intSchema},
+
+	{bytesSchema, nullSchema},
+	{bytesSchema, intSchema},
+
+	{intArraySchema, longArraySchema},
+	{intMapSchema, intArraySchema},
+	{intArraySchema, intMapSchema},
+	{intMapSchema, longMapSchema},
+
+	{enum1ABSchema, enum1ABCSchema},
+	{enum1BCSchema, enum1ABCSchema},
+
+	{enum1ABSchema, enum2ABSchema},
+	{intSchema, enum2ABSchema},
+	{enum2ABSchema, intSchema},
+
+	// unions
+	{intUnionSchema, intStringUnionSchema},
+	{stringUnionSchema, intStringUnionSchema},
+	{floatSchema, intLongFloatDoubleUnionSchema},
+	{longSchema, intFloatUnionSchema},
+	{intSchema, intFloatUnionSchema},
+	{intUnionSchema, stringSchema},
+	{emptyUnionSchema, intSchema},
+	{nullIntArrayUnionSchema, intMapSchema},
+
+	{emptyRecord2Schema, emptyRecord1Schema},
+	{aIntRecord1Schema, emptyRecord1Schema},
+	{aIntBDIntRecord1Schema, emptyRecord1Schema},
+
+	// recursive records
+	{intListRecordSchema, longListRecordSchema},
+}
+
+func TestCompatibleReaderWriters(t *testing.T) {
+	for i, pair := range compatibleReaderWriters {
+		if err := resolvePair(t, pair); err != nil {
+			t.Errorf("%d: expected reader %s to read writer %s, got %s", i, pair.reader, pair.writer, err)
+		}
+	}
+}
+
+func TestIncompatibleReaderWriters(t *testing.T) {
+	for i, pair := range incompatibleReaderWriters {
+		if err := resolvePair(t, pair); err == nil {
+			t.Errorf("%d: expected reader %s not to read writer %s", i, pair.reader, pair.writer)
+		}
+	}
+}
+
+func resolvePair(t *testing.T, pair readerWriter) error {
+	writer, err := ParseSchema(pair.writer)
+	if err != nil {
+		t.Fatalf("Can't parse %s: %s", pair.writer, err)
+	}
+	reader, err := ParseSchema(pair.reader)
+	if err != nil {
+		t.Fatalf("Can't parse %s: %s", pair.reader, err)
+	}
+	return validate(writer, reader)
+}
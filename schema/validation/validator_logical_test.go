@@ -0,0 +1,115 @@
+/* Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package validation
+
+import (
+	"regexp"
+	"testing"
+
+	avro "github.com/elodina/go-avro"
+	"gopkg.in/stretchr/testify.v1/assert"
+	"gopkg.in/stretchr/testify.v1/require"
+)
+
+func checkLogicalTypes(t *testing.T, schema string) SchemaErrors {
+	parsed, err := ParseSchema(schema)
+	require.Equal(t, nil, err)
+	return CheckLogicalTypes(parsed)
+}
+
+func TestParseSchemaLogicalTypes(t *testing.T) {
+	schema, err := ParseSchema(`{"type": "record", "name": "Payment", "fields": [
+     {"name": "id", "type": {"type": "string", "logicalType": "uuid"}},
+     {"name": "at", "type": ["null", {"type": "long", "logicalType": "timestamp-millis"}]},
+     {"name": "amount", "type": {"type": "bytes", "logicalType": "decimal", "precision": 10, "scale": 2}},
+     {"name": "count", "type": "int"}
+ ]}`)
+	require.Equal(t, nil, err)
+	fields := schema.(*avro.RecordSchema).Fields
+
+	assert.Equal(t, &LogicalType{Name: UUID}, logicalType(fields[0].Type))
+	assert.Equal(t, avro.String, fields[0].Type.Type())
+	assert.Equal(t, &LogicalType{Name: TimestampMillis}, logicalType(fields[1].Type.(*avro.UnionSchema).Types[1]))
+	assert.Equal(t, &LogicalType{Name: Decimal, Precision: 10, Scale: 2}, logicalType(fields[2].Type))
+	assert.Equal(t, `{"logicalType":"decimal","precision":10,"scale":2,"type":"bytes"}`, fragment(fields[2].Type))
+	assert.Nil(t, logicalType(fields[3].Type))
+}
+
+func TestCheckLogicalTypes(t *testing.T) {
+	assert.Empty(t, checkLogicalTypes(t, `{"type": "record", "name": "Valid", "fields": [
+     {"name": "day", "type": {"type": "int", "logicalType": "date"}},
+     {"name": "amount", "type": {"type": "fixed", "name": "Amount", "size": 5, "logicalType": "decimal", "precision": 11}},
+     {"name": "period", "type": {"type": "fixed", "name": "Period", "size": 12, "logicalType": "duration"}},
+     {"name": "custom", "type": {"type": "string", "logicalType": "not-in-the-spec"}}
+ ]}`))
+
+	errs := checkLogicalTypes(t, `{"type": "record", "name": "Invalid", "fields": [
+     {"name": "day", "type": {"type": "string", "logicalType": "date"}},
+     {"name": "amount", "type": {"type": "bytes", "logicalType": "decimal", "precision": 4, "scale": 5}},
+     {"name": "price", "type": {"type": "fixed", "name": "Price", "size": 5, "logicalType": "decimal", "precision": 12}},
+     {"name": "rate", "type": {"type": "bytes", "logicalType": "decimal"}},
+     {"name": "period", "type": {"type": "array", "items": {"type": "fixed", "name": "Period", "size": 8, "logicalType": "duration"}}}
+ ]}`)
+	require.Len(t, errs, 5)
+	assert.Equal(t, "/fields/day/type: Logical type date can't annotate string", errs[0].Error())
+	assert.Equal(t, "/fields/amount/type: Decimal scale 5 is greater than precision 4", errs[1].Error())
+	assert.Regexp(t, regexp.MustCompile("^/fields/price/type: Decimal precision 12 needs more than 5 bytes"), errs[2].Error())
+	assert.Equal(t, "/fields/rate/type: Decimal precision must be a positive integer", errs[3].Error())
+	assert.Equal(t, "/fields/period/type/items: Duration must be a fixed of size 12, got 8", errs[4].Error())
+}
+
+func TestMaxPrecision(t *testing.T) {
+	assert.Equal(t, 2, maxPrecision(1))
+	assert.Equal(t, 9, maxPrecision(4))
+	assert.Equal(t, 18, maxPrecision(8))
+	assert.Equal(t, 38, maxPrecision(16))
+}
+
+func TestValidatorLogicalTypes(t *testing.T) {
+	timestamp := `{"type": "long", "logicalType": "timestamp-millis"}`
+	decimal := `{"type": "bytes", "logicalType": "decimal", "precision": 10, "scale": 2}`
+
+	assert.Nil(t, canRead(t, timestamp, timestamp))
+	assert.Nil(t, canRead(t, decimal, decimal))
+
+	err := canRead(t, timestamp, `"long"`)
+	assert.Regexp(t, regexp.MustCompile(".*Different logical types: writer timestamp-millis, reader no logical type.*"), err)
+	err = canRead(t, `"long"`, timestamp)
+	assert.Regexp(t, regexp.MustCompile(".*Different logical types.*"), err)
+	err = canRead(t, timestamp, `{"type": "long", "logicalType": "timestamp-micros"}`)
+	assert.Regexp(t, regexp.MustCompile(".*Different logical types.*"), err)
+	assert.Equal(t, LogicalTypeMismatch, err.(Incompatibilities)[0].Type)
+
+	err = canRead(t, decimal, `{"type": "bytes", "logicalType": "decimal", "precision": 10, "scale": 3}`)
+	assert.Regexp(t, regexp.MustCompile(".*writer decimal\\(10,2\\), reader decimal\\(10,3\\).*"), err)
+	err = canRead(t, decimal, `{"type": "bytes", "logicalType": "decimal", "precision": 8, "scale": 2}`)
+	assert.Regexp(t, regexp.MustCompile(".*smaller precision.*"), err)
+	assert.Nil(t, canRead(t, decimal, `{"type": "bytes", "logicalType": "decimal", "precision": 12, "scale": 2}`))
+
+	// invalid logical types are ignored, the schema is read as its underlying type
+	assert.Nil(t, canRead(t, `{"type": "bytes", "logicalType": "decimal", "precision": 2, "scale": 3}`, `"bytes"`))
+}
+
+func TestValidatorLogicalTypesInUnions(t *testing.T) {
+	timestamp := `{"type": "long", "logicalType": "timestamp-millis"}`
+
+	assert.Nil(t, canRead(t, timestamp, `["null", "long", `+timestamp+`]`))
+	assert.Nil(t, canRead(t, `["null", `+timestamp+`]`, `["null", "long", `+timestamp+`]`))
+
+	err := canRead(t, timestamp, `["null", "long"]`)
+	assert.Regexp(t, regexp.MustCompile(".*Different logical types.*"), err)
+	assert.Equal(t, "/1", err.(Incompatibilities)[0].Path)
+}
@@ -0,0 +1,112 @@
+/* Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package validation
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+// withTimeout fails the test instead of hanging if the validator recurses forever.
+func withTimeout(t *testing.T, check func() error) error {
+	result := make(chan error, 1)
+	go func() {
+		result <- check()
+	}()
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("Validation did not finish, the validator recurses forever")
+		return nil
+	}
+}
+
+func TestValidatorLinkedList(t *testing.T) {
+	writer := `{"type": "record", "name": "Node", "fields": [
+     {"name": "value", "type": "int"},
+     {"name": "next", "type": ["null", "Node"]}
+ ]}`
+
+	err := withTimeout(t, func() error { return canRead(t, writer, writer) })
+	assert.Equal(t, nil, err)
+
+	// promotion inside the recursive type
+	err = withTimeout(t, func() error {
+		return canRead(t, writer, `{"type": "record", "name": "Node", "fields": [
+     {"name": "value", "type": "long"},
+     {"name": "next", "type": ["null", "Node"]}
+ ]}`)
+	})
+	assert.Equal(t, nil, err)
+
+	err = withTimeout(t, func() error {
+		return canRead(t, writer, `{"type": "record", "name": "Node", "fields": [
+     {"name": "value", "type": "string"},
+     {"name": "next", "type": ["null", "Node"]}
+ ]}`)
+	})
+	assert.Regexp(t, regexp.MustCompile(".*Found int, expecting string.*"), err)
+}
+
+func TestValidatorTree(t *testing.T) {
+	writer := `{"type": "record", "name": "Tree", "namespace": "trees", "fields": [
+     {"name": "label", "type": "string"},
+     {"name": "children", "type": {"type": "array", "items": "Tree"}},
+     {"name": "index", "type": {"type": "map", "values": "trees.Tree"}}
+ ]}`
+	reader := `{"type": "record", "name": "Tree", "namespace": "trees", "fields": [
+     {"name": "label", "type": "string"},
+     {"name": "children", "type": {"type": "array", "items": "Tree"}},
+     {"name": "index", "type": {"type": "map", "values": "trees.Tree"}},
+     {"name": "weight", "type": "double", "default": 1.0}
+ ]}`
+
+	err := withTimeout(t, func() error { return canRead(t, writer, reader) })
+	assert.Equal(t, nil, err)
+	err = withTimeout(t, func() error { return canRead(t, reader, writer) })
+	assert.Equal(t, nil, err)
+}
+
+func TestValidatorMutualRecursion(t *testing.T) {
+	writer := `{"type": "record", "name": "Employee", "namespace": "people", "fields": [
+     {"name": "name", "type": "string"},
+     {"name": "team", "type": {"type": "record", "name": "Team", "namespace": "org", "fields": [
+         {"name": "lead", "type": "people.Employee"},
+         {"name": "members", "type": {"type": "array", "items": "people.Employee"}},
+         {"name": "parent", "type": ["null", "Team"]}
+     ]}}
+ ]}`
+
+	err := withTimeout(t, func() error { return canRead(t, writer, writer) })
+	assert.Equal(t, nil, err)
+
+	// a field without a default deep inside the cycle is still found
+	reader := `{"type": "record", "name": "Employee", "namespace": "people", "fields": [
+     {"name": "name", "type": "string"},
+     {"name": "team", "type": {"type": "record", "name": "Team", "namespace": "org", "fields": [
+         {"name": "lead", "type": "people.Employee"},
+         {"name": "members", "type": {"type": "array", "items": "people.Employee"}},
+         {"name": "parent", "type": ["null", "Team"]},
+         {"name": "budget", "type": "long"}
+     ]}}
+ ]}`
+	err = withTimeout(t, func() error { return canRead(t, writer, reader) })
+	assert.Regexp(t, regexp.MustCompile(".*budget does not have default value.*"), err)
+}
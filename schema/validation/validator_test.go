@@ -268,11 +268,16 @@ func TestValidatorUnion(t *testing.T) {
 	err = validator.Validate(writer, []avro.Schema{reader})
 	assert.Regexp(t, regexp.MustCompile(".*cannot be read.*"), err)
 
-	// string -> [null, string]
+	// [null, string] can read string, but string can't read null
 	reader = avro.MustParseSchema(`{"type": "string"}`)
 
 	validator, err = NewBuilder().MutualReadStrategy().ValidateLatest()
 	require.Equal(t, nil, err)
 	err = validator.Validate(writer, []avro.Schema{reader})
+	assert.Regexp(t, regexp.MustCompile(".*Found null, expecting string.*"), err)
+
+	validator, err = NewBuilder().CanReadStrategy().ValidateLatest()
+	require.Equal(t, nil, err)
+	err = validator.Validate(writer, []avro.Schema{reader})
 	assert.Equal(t, nil, err)
 }
@@ -0,0 +1,44 @@
+/* Licensed to the Apache Software Foundation (ASF) under one or more
+contributor license agreements.  See the NOTICE file distributed with
+this work for additional information regarding copyright ownership.
+The ASF licenses this file to You under the Apache License, Version 2.0
+(the "License"); you may not use this file except in compliance with
+the License.  You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License. */
+
+package validation
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// wireMagic is the first byte of the Confluent wire format, followed by the schema id as a 4 byte big endian
+// integer and the Avro binary data.
+const wireMagic = 0
+
+// ErrNotWireFormat is returned for data that doesn't start with the wire format header.
+var ErrNotWireFormat = errors.New("Data is not in the Confluent wire format")
+
+// SplitWireFormat returns the schema id and the Avro binary data of a message in the Confluent wire format.
+func SplitWireFormat(data []byte) (int64, []byte, error) {
+	if len(data) < 5 || data[0] != wireMagic {
+		return 0, nil, ErrNotWireFormat
+	}
+	return int64(binary.BigEndian.Uint32(data[1:5])), data[5:], nil
+}
+
+// WireFormat prefixes Avro binary data with the wire format header for the schema id.
+func WireFormat(id int64, data []byte) []byte {
+	message := make([]byte, 5, 5+len(data))
+	message[0] = wireMagic
+	binary.BigEndian.PutUint32(message[1:5], uint32(id))
+	return append(message, data...)
+}